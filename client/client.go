@@ -10,7 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"sort"
+	"net/url"
 )
 
 // ===== Config =====
@@ -27,15 +27,23 @@ var httpc = &http.Client{Timeout: 10 * time.Second}
 // ===== DTOs =====
 
 type Book struct {
-	ID              int64  `json:"id"`
-	BookName        string `json:"book_name"`
-	BookCategory    string `json:"book_category"`
-	TransactionType string `json:"transaction_type"`
-	Price           int64  `json:"price"`
-	Status          any    `json:"status"`
-	PopularityScore int64  `json:"popularity_score"`
+	ID              int64   `json:"id"`
+	BookName        string  `json:"book_name"`
+	BookCategory    string  `json:"book_category"`
+	TransactionType string  `json:"transaction_type"`
+	Price           int64   `json:"price"`
+	EffectivePrice  int64   `json:"effective_price"` // con promociones vigentes
+	SalePrice       *int64  `json:"sale_price"`      // nil si el libro no se vende
+	RentalPrice     *int64  `json:"rental_price"`    // por período; nil si no se arrienda
+	RentalPeriod    int64   `json:"rental_period_days"`
+	Status          any     `json:"status"`
+	PopularityScore int64   `json:"popularity_score"`
+	RatingAverage   float64 `json:"rating_average"`
+	ReviewCount     int64   `json:"review_count"`
 	Inventory       struct {
 		AvailableQuantity int64 `json:"available_quantity"`
+		SaleQuantity      int64 `json:"sale_quantity"`
+		RentalQuantity    int64 `json:"rental_quantity"`
 	} `json:"inventory"`
 }
 
@@ -46,20 +54,23 @@ type BooksList struct {
 type CreateBookReq struct {
 	BookName        string `json:"book_name"`
 	BookCategory    string `json:"book_category"`
-	TransactionType string `json:"transaction_type"` // "venta" | "arriendo"
-	Price           int64  `json:"price"`
+	TransactionType string `json:"transaction_type"` // "venta" | "arriendo" | "ambos"
+	Price           int64  `json:"price"`            // en "ambos", el de venta
+	RentalPrice     *int64 `json:"rental_price,omitempty"`
 	Status          bool   `json:"status"`
 	Stock           int64  `json:"stock"`
+	RentalStock     int64  `json:"rental_stock,omitempty"` // en "ambos", parte del stock para arriendo
 }
 
 type UpdateBookReq struct {
 	BookName        *string `json:"book_name,omitempty"`
 	BookCategory    *string `json:"book_category,omitempty"`
-	TransactionType *string `json:"transaction_type,omitempty"`
+	TransactionType *string `json:"transaction_type,omitempty"` // "venta" | "arriendo" | "ambos"
 	Price           *int64  `json:"price,omitempty"`
+	RentalPrice     *int64  `json:"rental_price,omitempty"`
 	Status          *bool   `json:"status,omitempty"`
-	PopularityScore *int64  `json:"popularity_score,omitempty"`
 	Stock           *int64  `json:"stock,omitempty"`
+	RentalStock     *int64  `json:"rental_stock,omitempty"`
 }
 
 type User struct {
@@ -188,7 +199,7 @@ func menuInicio() (user *User, ok bool) {
 			}
 
 			var me User
-			if err := getJSON(fmt.Sprintf("/api/users/email/%s", Correo), &me); err != nil || me.ID == 0 {
+			if err := getJSON(fmt.Sprintf("/api/v1/users/email/%s", Correo), &me); err != nil || me.ID == 0 {
 				fmt.Println("Usuario no encontrado:", err)
 				continue
 			}
@@ -220,10 +231,10 @@ func crearUsuario() *User {
 
 	req := CreateUserReq{FirstName: fn, LastName: ln, Email: em, Password: pw, USMPesos: usm}
 	var created User
-	if err := postJSON("/api/users", req, &created); err != nil {
+	if err := postJSON("/api/v1/users", req, &created); err != nil {
 		// si tu handler devuelve { "user_id": n } en vez del user completo,
 		// hacemos un segundo POST sin decodificar respuesta estricta (solo para crear):
-		if e2 := postJSON("/api/users", req, nil); e2 != nil {
+		if e2 := postJSON("/api/v1/users", req, nil); e2 != nil {
 			fmt.Println("Error creando usuario:", err)
 			pause()
 			return nil
@@ -269,7 +280,7 @@ func menuPrincipal(user *User) {
 
 
 func listarLibros(includeAll bool) {
-	url := "/api/books"
+	url := "/api/v1/books"
 	if includeAll { url += "?status=false" }
 	var out BooksList
 	if err := getJSON(url, &out); err != nil {
@@ -282,14 +293,27 @@ func listarLibros(includeAll bool) {
 		pause()
 		return
 	}
-	fmt.Println("-----------------------------------------------------------------")
-	fmt.Printf("| %-7s | %-20s | %-10s | %-9s | %-5s | %-5s |\n", "ID", "Nombre", "Categoría", "Tipo", "Valor", "Stock")
-	fmt.Println("-----------------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------------------------------------------")
+	fmt.Printf("| %-7s | %-20s | %-10s | %-9s | %-6s | %-8s | %-8s | %-8s |\n",
+		"ID", "Nombre", "Categoría", "Tipo", "Venta", "Stock V", "Arriendo", "Stock A")
+	fmt.Println("-------------------------------------------------------------------------------------------")
 	for _, b := range out.Books {
-		fmt.Printf("| %-7d | %-20s | %-10s | %-9s | %-5d | %-5d |\n",
-			b.ID, b.BookName, b.BookCategory, b.TransactionType, b.Price, b.Inventory.AvailableQuantity)
+		// EffectivePrice ya trae la promoción sobre el precio principal del libro
+		sale, rental := "-", "-"
+		if b.SalePrice != nil {
+			sale = fmt.Sprint(b.EffectivePrice)
+		}
+		if b.RentalPrice != nil {
+			rental = fmt.Sprintf("%d/%dd", *b.RentalPrice, b.RentalPeriod)
+			if b.SalePrice == nil {
+				rental = fmt.Sprintf("%d/%dd", b.EffectivePrice, b.RentalPeriod)
+			}
+		}
+		fmt.Printf("| %-7d | %-20s | %-10s | %-9s | %-6s | %-8d | %-8s | %-8d |\n",
+			b.ID, b.BookName, b.BookCategory, b.TransactionType,
+			sale, b.Inventory.SaleQuantity, rental, b.Inventory.RentalQuantity)
 	}
-	fmt.Println("-----------------------------------------------------------------")
+	fmt.Println("-------------------------------------------------------------------------------------------")
 	pause()
 }
 
@@ -304,24 +328,28 @@ func misPrestamos(user *User) {
 }
 
 func verPopulares() {
+    window := prompt("Ventana (7d/30d/all) [all]: ")
+    if window == "" { window = "all" }
+    category := prompt("Categoría (Enter para todas): ")
+
+    q := url.Values{}
+    q.Set("window", window)
+    if category != "" { q.Set("category", category) }
+
+    // El servidor entrega los libros ya ordenados por popularidad
     var out BooksList
-    if err := getJSON("/api/books", &out); err != nil {
+    if err := getJSON("/api/v1/books/popular?"+q.Encode(), &out); err != nil {
         fmt.Println("Error:", err)
         pause()
         return
     }
 
-    // Ordenar por PopularityScore descendente
-    sort.Slice(out.Books, func(i, j int) bool {
-        return out.Books[i].PopularityScore > out.Books[j].PopularityScore
-    })
-
     fmt.Println("-----------------------------------------------------------------")
     fmt.Printf("| %-7s | %-20s | %-10s | %-5s | %-5s |\n", "ID", "Nombre", "Categoría", "Valor", "Popularidad")
     fmt.Println("-----------------------------------------------------------------")
     for _, b := range out.Books {
         fmt.Printf("| %-7d | %-20s | %-10s | %-5d | %-5d |\n",
-            b.ID, b.BookName, b.BookCategory, b.Price, b.PopularityScore)
+            b.ID, b.BookName, b.BookCategory, b.EffectivePrice, b.PopularityScore)
     }
     fmt.Println("-----------------------------------------------------------------")
     pause()
@@ -331,7 +359,7 @@ func verPopulares() {
 
 func verMiCuenta(user *User) {
 	var me User
-	if err := getJSON(fmt.Sprintf("/api/users/%d", user.ID), &me); err != nil {
+	if err := getJSON(fmt.Sprintf("/api/v1/users/%d", user.ID), &me); err != nil {
 		fmt.Println("Error:", err)
 		pause()
 		return
@@ -361,7 +389,7 @@ func verMiCuenta(user *User) {
 
 func abonarMiCuenta(user *User) {
 	amt := mustAtoi64(prompt("Ingrese la cantidad de usm pesos a abonar (+/-): "))
-	if err := patchJSON(fmt.Sprintf("/api/users/%d/usm_pesos", user.ID), UpdatePesosReq{Amount: amt}, nil); err != nil {
+	if err := patchJSON(fmt.Sprintf("/api/v1/users/%d/usm_pesos", user.ID), UpdatePesosReq{Amount: amt}, nil); err != nil {
 		fmt.Println("Error:", err)
 	} else {
 		user.USMPesos += amt
@@ -1,50 +1,245 @@
 package main // Define el paquete principal del programa
 
 import (
-	"database/sql" // Importa el paquete para trabajar con bases de datos SQL
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 	"uzm-server/internal/books" // Importa el paquete local 'books' que contiene la lógica relacionada con libros
-	"uzm-server/internal/db"    // Importa el paquete local 'db' que contiene la lógica para migrar la base de datos
+	"uzm-server/internal/config"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/db" // Abre SQLite o PostgreSQL según el DSN
+	"uzm-server/internal/health"
+	httpx "uzm-server/internal/http"
+	"uzm-server/internal/logging"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/metrics"
+	"uzm-server/internal/openapi"
+	"uzm-server/internal/reviews"
+	"uzm-server/internal/storage"
 	"uzm-server/internal/users" // Importa el paquete local 'users' que contiene la lógica relacionada con usuarios
+	"uzm-server/internal/wishlist"
 
 	"github.com/gin-gonic/gin" // Importa el framework web Gin para crear servidores HTTP
-	_ "modernc.org/sqlite"     // Importa el driver SQLite3 (el guion bajo indica importación solo para efectos secundarios)
 
 	"log" // Importa el paquete para registro de logs
 )
 
+// Fechas del alias /api sin versión: obsoleto desde que existe /api/v1 y se
+// retira en legacyAPISunset
+var (
+	legacyAPIDeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	legacyAPISunset       = time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+)
+
 func main() { // Función principal, punto de entrada del programa
-	// Conexión a la base de datos SQLite
-	dbconn, err := sql.Open("sqlite", "./uzm.db") // Abre una conexión
-	if err != nil {                           // Verifica si hubo un error al abrir la conexión
-		log.Fatal(err) // Registra el error y termina el programa
+	// "api migrate up|down|status" administra el esquema y termina
+	args := os.Args[1:]
+	var migrateCmd string
+	if len(args) > 0 && args[0] == "migrate" {
+		if len(args) < 2 {
+			log.Fatal("uso: api migrate up|down|status [banderas]")
+		}
+		migrateCmd, args = args[1], args[2:]
 	}
-	defer dbconn.Close() // Asegura que la conexión se cierre al finalizar la función
 
-	// Crear tablas
-	db.MakeMigrate(dbconn, "schema.sql") // Ejecuta una sentencia SQL para crear la tabla 'users' si no existe
+	// Configuración: valores por defecto < archivo < variables UZM_* < banderas
+	cfg, printConfig, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return // -h ya mostró las opciones
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	gin.SetMode(cfg.Server.Mode)
+	if err := logging.Setup(os.Stderr, cfg.Log.Level, cfg.Log.Format); err != nil {
+		log.Fatal(err)
+	}
+
+	var repos repositories
+	var dbconn *db.DB // nil con el almacenamiento en memoria
+	if cfg.Database.Storage == config.StorageMemory {
+		if migrateCmd != "" {
+			log.Fatal("migrate necesita una base de datos; quita --storage=memory")
+		}
+		repos = memoryRepositories(memstore.New()) // Datos de demostración: se pierden al apagar
+		log.Println("almacenamiento en memoria: los datos se pierden al apagar el servidor")
+	} else {
+		// Conexión a la base de datos: PostgreSQL si el DSN es postgres://, si no SQLite
+		dbconn, err = db.Open(cfg.Database.DSN) // Abre una conexión
+		if err != nil {                          // Verifica si hubo un error al abrir la conexión
+			log.Fatal(err) // Registra el error y termina el programa
+		}
+		defer dbconn.Close() // Cierra la conexión si el programa termina antes de levantar el servidor
+
+		if migrateCmd != "" {
+			if err := runMigrate(context.Background(), dbconn, migrateCmd); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		// Aplica las migraciones pendientes del esquema
+		if cfg.Database.AutoMigrate {
+			if err := runMigrate(context.Background(), dbconn, "up"); err != nil {
+				log.Fatal(err)
+			}
+		}
+		repos = sqlRepositories(dbconn) // Repositorios del motor de la base de datos
+	}
 
 	// DI
-	userRepo := users.NewSQLiteRepository(dbconn) // Crea un repositorio de usuarios basado en SQLite
-	userService := users.NewService(userRepo)  // Crea un servicio de usuarios utilizando el repositorio
+	userService := users.NewService(repos.users)  // Crea un servicio de usuarios utilizando el repositorio
 	userHandler := users.NewHandler(userService) // Crea un manejador de usuarios utilizando el servicio
 
 	// Books
-	bookRepo := books.NewSQLiteRepository(dbconn)
-	bookService := books.NewService(bookRepo)
-	bookHandler := books.NewHandler(bookService)
+	bookService := books.NewService(repos.books, cfg.Business.LoanDays)
+	bookHandler := books.NewHandler(bookService, time.Duration(cfg.Server.ExportTimeout))
+
+	// Reviews
+	reviewService := reviews.NewService(repos.reviews)
+	reviewHandler := reviews.NewHandler(reviewService)
+
+	// Covers (imágenes en disco local)
+	coverStore, err := storage.NewLocalStorage(cfg.Storage.CoversDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	coverService := covers.NewService(repos.covers, coverStore)
+	coverHandler := covers.NewHandler(coverService)
+
+	// Copies (ejemplares físicos)
+	copyService := copies.NewService(repos.copies, bookService, cfg.Business.LateFeePerDay)
+	copyHandler := copies.NewHandler(copyService)
+
+	// Wishlist y notificaciones
+	wishlistService := wishlist.NewService(repos.wishlist)
+	wishlistHandler := wishlist.NewHandler(wishlistService)
+
+	// SIGINT/SIGTERM inician el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Jobs en segundo plano; se detienen al cancelar jobsCtx
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	var jobs sync.WaitGroup
+	popularityWorker := health.NewWorker("popularity", time.Duration(cfg.Business.PopularityInterval))
+	lowStockWorker := health.NewWorker("low_stock", time.Duration(cfg.Business.LowStockInterval))
+	jobs.Go(func() { books.RunPopularityJob(jobsCtx, bookService, time.Duration(cfg.Business.PopularityInterval), popularityWorker.Report) }) // Recalcula popularity_score
+	jobs.Go(func() { books.RunLowStockJob(jobsCtx, bookService, time.Duration(cfg.Business.LowStockInterval), lowStockWorker.Report) })       // Levanta alertas de stock bajo
+
+	// Chequeos de /readyz; en memoria no hay base de datos que revisar
+	var checks []health.Check
+	if dbconn != nil {
+		checks = append(checks, health.Database(dbconn.DB), health.Schema(dbconn))
+	}
+	readiness := health.NewChecker(append(checks, popularityWorker.Check(), lowStockWorker.Check())...)
+
+	// Métricas Prometheus
+	appMetrics := metrics.New(dbconn)
+
+	// Especificación OpenAPI; valida todas las solicitudes de la API
+	apiSpec, err := openapi.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// Inicializa el router Gin
-	router := gin.Default() // Crea un router con las configuraciones por defecto
-	api := router.Group("/api/")
-	userHandler.RegisterRoutes(api) // Registra las rutas del manejador de usuarios bajo el grupo /api/v1
-	bookHandler.RegisterRoutes(api) // Registra las rutas del manejador de libros bajo el grupo /api/v1
+	router := gin.New()                  // Crea un router sin el logger de texto de Gin
+	router.Use(httpx.RequestLogger())   // Asigna X-Request-ID y escribe el log de acceso en JSON
+	router.Use(appMetrics.Middleware()) // Cuenta solicitudes y latencia por ruta
+	router.Use(gin.Recovery())          // Responde 500 si un handler entra en pánico
+	if len(cfg.CORS.Origins) > 0 {
+		router.Use(httpx.CORS(cfg.CORS.Origins)) // Permite llamadas desde los navegadores de esos orígenes
+	}
+	router.Use(httpx.Problems()) // Responde los errores de los handlers como application/problem+json
+	router.NoRoute(httpx.NoRoute)
+	// Registra las rutas del contrato v1 en un grupo. Una versión nueva tendría
+	// su propia función con los handlers de esa versión.
+	mountV1 := func(api *gin.RouterGroup) {
+		api.Use(apiSpec.Validate(api.BasePath())) // Rechaza las solicitudes que no cumplen la especificación
+		apiSpec.RegisterRoutes(api)               // Publica openapi.json y docs
+		userHandler.RegisterRoutes(api)           // Registra las rutas del manejador de usuarios
+		bookHandler.RegisterRoutes(api)           // Registra las rutas del manejador de libros
+		reviewHandler.RegisterRoutes(api)
+		coverHandler.RegisterRoutes(api)
+		copyHandler.RegisterRoutes(api)
+		wishlistHandler.RegisterRoutes(api)
+	}
+	mountV1(router.Group("/api/v1")) // Contrato vigente
+	// /api es el alias sin versión que usan los clientes ya publicados: responde
+	// como v1 y avisa con Deprecation y Sunset que deben pasar a /api/v1
+	mountV1(router.Group("/api", httpx.Deprecated("/api", "/api/v1", legacyAPIDeprecatedAt, legacyAPISunset)))
+	// Validate rechaza las rutas sin documentar; mejor no arrancar que
+	// descubrirlas en producción. /api monta los mismos handlers que /api/v1.
+	if err := apiSpec.Check(router.Routes(), "/api/v1"); err != nil {
+		log.Fatal(err)
+	}
 
-	okmessage := fmt.Sprintf("El server está corriendo en el puerto %v", 8080)
+	okmessage := fmt.Sprintf("El server está corriendo en %v", cfg.Server.Addr)
 	log.Println(okmessage)
 
-	// Ruta para verificar que el servidor está corriendo
-	router.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status":"ok"}) })
+	// Sondas: /livez indica que el proceso responde, /readyz que puede atender tráfico
+	router.GET("/livez", health.Livez)
+	router.GET("/readyz", readiness.Readyz)
+	router.GET("/health", health.Livez) // Alias de /livez para los clientes antiguos
+	router.GET("/metrics", appMetrics.Handler()) // Métricas en formato Prometheus
 
-	router.Run(":8080") // Inicia el servidor en el puerto 8080
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router,
+		ReadTimeout:       time.Duration(cfg.Server.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.Server.ReadHeaderTimeout),
+		WriteTimeout:      time.Duration(cfg.Server.WriteTimeout), // la exportación del catálogo usa server.export_timeout
+		IdleTimeout:       time.Duration(cfg.Server.IdleTimeout),
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }() // Inicia el servidor en la dirección configurada
+
+	failed := false
+	select {
+	case err := <-serveErr:
+		log.Println("el servidor se detuvo:", err)
+		failed = true
+	case <-ctx.Done():
+		log.Println("señal recibida, apagando el servidor...")
+	}
+	stop() // una segunda señal termina el proceso de inmediato
+
+	// Deja de aceptar conexiones y espera a las solicitudes en curso (por
+	// ejemplo una compra a medio confirmar) hasta shutdown_timeout; después
+	// corta las que queden, y sus transacciones se revierten.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("quedaron solicitudes sin terminar, cerrando conexiones:", err)
+		_ = srv.Close()
+	}
+
+	stopJobs()
+	jobs.Wait()
+
+	if dbconn != nil {
+		if err := dbconn.Close(); err != nil {
+			log.Println("error cerrando la base de datos:", err)
+			failed = true
+		}
+	}
+	log.Println("servidor detenido")
+	if failed {
+		os.Exit(1)
+	}
 }
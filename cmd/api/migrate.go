@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"uzm-server/internal/db"
+)
+
+// runMigrate ejecuta un subcomando de "api migrate"
+func runMigrate(ctx context.Context, dbconn *db.DB, cmd string) error {
+	switch cmd {
+	case "up":
+		done, err := db.MigrateUp(ctx, dbconn)
+		for _, m := range done {
+			log.Printf("migración %04d_%s aplicada", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			log.Println("el esquema está al día")
+		}
+		return err
+	case "down":
+		m, err := db.MigrateDown(ctx, dbconn)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			log.Println("no hay migraciones que revertir")
+			return nil
+		}
+		log.Printf("migración %04d_%s revertida", m.Version, m.Name)
+		return nil
+	case "status":
+		status, err := db.Status(ctx, dbconn)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSIÓN\tNOMBRE\tAPLICADA")
+		for _, s := range status {
+			at := "pendiente"
+			if s.AppliedAt != nil {
+				at = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, at)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("subcomando desconocido %q: usa migrate up, down o status", cmd)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/db"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/reviews"
+	"uzm-server/internal/users"
+	"uzm-server/internal/wishlist"
+)
+
+// repositories reúne los repositorios del servidor, todos sobre el mismo motor
+type repositories struct {
+	users    users.Repository
+	books    books.Repository
+	reviews  reviews.Repository
+	covers   covers.Repository
+	copies   copies.Repository
+	wishlist wishlist.Repository
+}
+
+// sqlRepositories arma los repositorios del motor de la conexión, que se
+// eligió por el DSN
+func sqlRepositories(conn *db.DB) repositories {
+	if conn.Dialect == db.Postgres {
+		return repositories{
+			users:    users.NewPostgresRepository(conn.DB),
+			books:    books.NewPostgresRepository(conn.DB),
+			reviews:  reviews.NewPostgresRepository(conn.DB),
+			covers:   covers.NewPostgresRepository(conn.DB),
+			copies:   copies.NewPostgresRepository(conn.DB),
+			wishlist: wishlist.NewPostgresRepository(conn.DB),
+		}
+	}
+	return repositories{
+		users:    users.NewSQLiteRepository(conn.DB),
+		books:    books.NewSQLiteRepository(conn.DB),
+		reviews:  reviews.NewSQLiteRepository(conn.DB),
+		covers:   covers.NewSQLiteRepository(conn.DB),
+		copies:   copies.NewSQLiteRepository(conn.DB),
+		wishlist: wishlist.NewSQLiteRepository(conn.DB),
+	}
+}
+
+// memoryRepositories arma los repositorios sobre un único almacén en memoria,
+// para que lo que escribe uno (una venta en copies) lo vean los demás
+func memoryRepositories(store *memstore.Store) repositories {
+	return repositories{
+		users:    users.NewMemoryRepository(store),
+		books:    books.NewMemoryRepository(store),
+		reviews:  reviews.NewMemoryRepository(store),
+		covers:   covers.NewMemoryRepository(store),
+		copies:   copies.NewMemoryRepository(store),
+		wishlist: wishlist.NewMemoryRepository(store),
+	}
+}
@@ -0,0 +1,86 @@
+// Package apperr define los errores de dominio que comparten los servicios.
+// Cada error tiene una clase (no encontrado, conflicto, validación...) que la
+// capa HTTP traduce a un status, y un código estable que los clientes pueden
+// comparar sin depender del mensaje.
+package apperr
+
+import "errors"
+
+// Kind clasifica un error de dominio
+type Kind int
+
+const (
+	KindInternal             Kind = iota // fallo inesperado; el detalle no se muestra al cliente
+	KindNotFound                         // el recurso no existe
+	KindConflict                         // el estado actual impide la operación
+	KindValidation                       // la solicitud trae datos inválidos
+	KindInsufficientFunds                // el saldo del usuario no alcanza
+	KindForbidden                        // el usuario no puede hacer la operación
+	KindUnauthorized                     // falta identificar al usuario
+	KindPreconditionFailed               // el recurso cambió desde que el cliente lo leyó
+	KindPreconditionRequired             // falta la precondición (If-Match) que la operación exige
+	KindTooLarge                         // el cuerpo supera el tamaño permitido
+	KindUnsupportedMedia                 // el tipo de contenido no se acepta
+)
+
+// FieldError describe un campo inválido de la solicitud
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"` // regla que no se cumplió: required, email, type...
+	Message string `json:"message"`
+}
+
+// Error es un error de dominio con código estable
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Is compara por código, así un mismo error declarado en dos paquetes
+// (por ejemplo "book_not_found") se reconoce como el mismo
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func NotFound(code, message string) *Error { return New(KindNotFound, code, message) }
+
+func Conflict(code, message string) *Error { return New(KindConflict, code, message) }
+
+func Validation(code, message string) *Error { return New(KindValidation, code, message) }
+
+func InsufficientFunds(code, message string) *Error { return New(KindInsufficientFunds, code, message) }
+
+func Forbidden(code, message string) *Error { return New(KindForbidden, code, message) }
+
+func Unauthorized(code, message string) *Error { return New(KindUnauthorized, code, message) }
+
+// InvalidParam es el error de un parámetro de ruta o de consulta mal formado
+func InvalidParam(name, message string) *Error {
+	return Validation("invalid_parameter", "el parámetro "+name+" no es válido").
+		WithFields(FieldError{Field: name, Code: "invalid", Message: message})
+}
+
+// WithFields devuelve una copia del error con el detalle por campo
+func (e *Error) WithFields(fields ...FieldError) *Error {
+	out := *e
+	out.Fields = append(append([]FieldError{}, e.Fields...), fields...)
+	return &out
+}
+
+// As busca un *Error en la cadena de err; los errores sin clasificar son internos
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
@@ -0,0 +1,118 @@
+package books
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"uzm-server/internal/logging"
+)
+
+// StockAlert avisa que un libro quedó bajo su umbral de reposición
+type StockAlert struct {
+	ID                int64
+	BookID            int64
+	BookName          string
+	AvailableQuantity int64
+	ReorderThreshold  int64
+	DailyVelocity     float64 // ventas + préstamos por día en restockVelocityWindow
+	SuggestedQuantity int64
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	ResolvedAt        *time.Time // nil mientras el stock siga bajo el umbral
+}
+
+// Ventana usada para medir la velocidad de salida de un libro
+const restockVelocityWindow = 30 * 24 * time.Hour
+
+// Días de demanda que debe cubrir la reposición sugerida, sobre el umbral
+const restockCoverDays = 30
+
+// suggestRestock propone cuántos ejemplares pedir para volver al umbral y
+// cubrir restockCoverDays días de demanda a la velocidad observada.
+func suggestRestock(qty, threshold int64, velocity float64) int64 {
+	target := threshold + int64(math.Ceil(velocity*restockCoverDays))
+	if n := target - qty; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// dailyVelocity cuenta eventos por libro desde since y los divide por los días de la ventana
+func dailyVelocity(activity []Activity, since time.Time) map[int64]float64 {
+	days := restockVelocityWindow.Hours() / 24
+	out := make(map[int64]float64)
+	for _, a := range activity {
+		if a.At.Before(since) {
+			continue
+		}
+		out[a.BookID] += 1 / days
+	}
+	return out
+}
+
+// CheckLowStock revisa el catálogo y deja abierta una alerta por cada libro
+// cuyo stock esté bajo su umbral; las alertas de libros repuestos se resuelven.
+func (s *service) CheckLowStock(ctx context.Context) error {
+	books, err := s.repo.ListBook(ctx, CatalogFilter{})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	since := now.Add(-restockVelocityWindow)
+	activity, err := s.repo.ListActivity(ctx, since)
+	if err != nil {
+		return err
+	}
+	velocity := dailyVelocity(activity, since)
+
+	var low []StockAlert
+	for _, bwi := range books {
+		b := bwi.Book
+		if b.ReorderThreshold <= 0 || bwi.AvailableQuantity >= b.ReorderThreshold {
+			continue
+		}
+		v := velocity[b.ID]
+		low = append(low, StockAlert{
+			BookID:            b.ID,
+			AvailableQuantity: bwi.AvailableQuantity,
+			ReorderThreshold:  b.ReorderThreshold,
+			DailyVelocity:     math.Round(v*100) / 100,
+			SuggestedQuantity: suggestRestock(bwi.AvailableQuantity, b.ReorderThreshold, v),
+		})
+	}
+	return s.repo.SyncStockAlerts(ctx, low, now)
+}
+
+func (s *service) ListStockAlerts(ctx context.Context, onlyOpen bool) ([]*StockAlert, error) {
+	alerts, err := s.repo.ListStockAlerts(ctx, onlyOpen)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*StockAlert, len(alerts))
+	for i := range alerts {
+		out[i] = &alerts[i]
+	}
+	return out, nil
+}
+
+// RunLowStockJob revisa el stock al iniciar y luego cada interval, hasta que
+// ctx se cancele. report, si no es nil, recibe el resultado de cada ejecución.
+func RunLowStockJob(ctx context.Context, svc Service, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := svc.CheckLowStock(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("error revisando stock bajo", "err", err)
+		}
+		if report != nil && ctx.Err() == nil {
+			report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,622 @@
+// Package bookstest tiene la prueba de contrato de books.Repository: todas las
+// implementaciones deben pasarla igual, sea cual sea el motor que usen.
+//
+// La prueba solo usa la interfaz, así que no cubre lo que depende de ventas y
+// préstamos (ListActivity, ListRelated con historial): esas filas las crean
+// otros repositorios.
+package bookstest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+)
+
+// RunRepositoryContract prueba una implementación de books.Repository.
+// newRepo debe entregar un repositorio vacío en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepo func(t *testing.T) books.Repository) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+		rental := int64(500)
+		id := createBook(t, repo, &books.Book{
+			BookName:         "  Rayuela ",
+			BookCategory:     "Novela",
+			TransactionType:  "Ambos",
+			Price:            12000,
+			RentalPrice:      &rental,
+			RentalPeriodDays: 7,
+			Status:           true,
+			ReorderThreshold: 2,
+		}, 5, 2)
+		if id <= 0 {
+			t.Fatalf("CreateBook devolvió el id %d", id)
+		}
+
+		got := getBook(t, repo, id)
+		b := got.Book
+		if b.BookName != "Rayuela" || b.TransactionType != books.ModeBoth || b.Price != 12000 ||
+			b.RentalPrice == nil || *b.RentalPrice != 500 || b.RentalPeriodDays != 7 || !b.Status ||
+			b.ReorderThreshold != 2 || b.Version != 1 || b.ArchivedAt != nil || b.ReviewCount != 0 {
+			t.Fatalf("GetBookByID = %+v", b)
+		}
+		if got.AvailableQuantity != 5 || got.RentalQuantity != 2 {
+			t.Fatalf("stock = %d (arriendo %d), quiero 5 (arriendo 2)", got.AvailableQuantity, got.RentalQuantity)
+		}
+
+		history, err := repo.ListPriceHistory(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 1 || history[0].OldPrice != nil || history[0].NewPrice != 12000 {
+			t.Fatalf("ListPriceHistory = %+v, quiero solo el precio inicial", history)
+		}
+	})
+
+	t.Run("GetMissing", func(t *testing.T) {
+		repo := newRepo(t)
+		got, err := repo.GetBookByID(ctx, 999, nil)
+		if err != nil || got.Book != nil {
+			t.Fatalf("GetBookByID(999) = %+v, %v; quiero un resultado vacío", got, err)
+		}
+
+		id := createBook(t, repo, saleBook("Sin stock"), 0, 0)
+		onlyAvailable := true
+		got, err = repo.GetBookByID(ctx, id, &onlyAvailable)
+		if err != nil || got.Book != nil {
+			t.Fatalf("GetBookByID sin stock y onlyAvailable = %+v, %v; quiero un resultado vacío", got, err)
+		}
+	})
+
+	t.Run("ListFilters", func(t *testing.T) {
+		repo := newRepo(t)
+		sale := createBook(t, repo, saleBook("Venta"), 3, 0)
+		rentalBook := saleBook("Arriendo")
+		rentalBook.TransactionType = books.ModeRental
+		rent := createBook(t, repo, rentalBook, 0, 0)
+		bothBook := saleBook("Ambos")
+		bothBook.TransactionType = books.ModeBoth
+		both := createBook(t, repo, bothBook, 2, 2) // todo el stock es de arriendo
+		archived := createBook(t, repo, saleBook("Archivado"), 1, 0)
+		if err := repo.ArchiveBook(ctx, archived); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			name   string
+			filter books.CatalogFilter
+			want   []int64
+		}{
+			{"todos", books.CatalogFilter{}, []int64{sale, rent, both}},
+			{"con stock", books.CatalogFilter{OnlyAvailable: true}, []int64{sale, both}},
+			{"venta", books.CatalogFilter{Mode: books.ModeSale}, []int64{sale, both}},
+			{"venta con stock", books.CatalogFilter{Mode: books.ModeSale, OnlyAvailable: true}, []int64{sale}},
+			{"arriendo", books.CatalogFilter{Mode: books.ModeRental}, []int64{rent, both}},
+			{"arriendo con stock", books.CatalogFilter{Mode: books.ModeRental, OnlyAvailable: true}, []int64{both}},
+		} {
+			list, err := repo.ListBook(ctx, tc.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(list); !equal(got, tc.want) {
+				t.Errorf("ListBook(%s) = %v, quiero %v", tc.name, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("StreamBooksStops", func(t *testing.T) {
+		repo := newRepo(t)
+		createBook(t, repo, saleBook("Uno"), 1, 0)
+		createBook(t, repo, saleBook("Dos"), 1, 0)
+		stop := errors.New("basta")
+		calls := 0
+		err := repo.StreamBooks(ctx, books.CatalogFilter{}, func(books.BookWithInventory) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Fatalf("StreamBooks = %v tras %d llamadas; quiero el error de fn tras 1", err, calls)
+		}
+	})
+
+	t.Run("UpdateBook", func(t *testing.T) {
+		repo := newRepo(t)
+		id := createBook(t, repo, saleBook("Original"), 4, 0)
+		b := getBook(t, repo, id).Book
+
+		b.BookName = "Cambiado"
+		b.Price = 9000
+		stock := int64(1)
+		if err := repo.UpdateBook(ctx, b, &stock, nil); err != nil {
+			t.Fatal(err)
+		}
+		if b.Version != 2 {
+			t.Fatalf("UpdateBook dejó la versión %d, quiero 2", b.Version)
+		}
+		got := getBook(t, repo, id)
+		if got.Book.BookName != "Cambiado" || got.Book.Price != 9000 || got.Book.Version != 2 || got.AvailableQuantity != 1 {
+			t.Fatalf("tras UpdateBook = %+v (stock %d)", got.Book, got.AvailableQuantity)
+		}
+
+		history, err := repo.ListPriceHistory(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[0].NewPrice != 9000 || history[0].OldPrice == nil || *history[0].OldPrice != 1000 {
+			t.Fatalf("ListPriceHistory = %+v, quiero el cambio 1000 → 9000 primero", history)
+		}
+		adjustments, err := repo.ListInventoryAdjustments(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(adjustments) != 1 || adjustments[0].Delta != -3 || adjustments[0].Reason != books.ReasonCorrection ||
+			adjustments[0].UserID != nil || adjustments[0].QuantityAfter != 1 {
+			t.Fatalf("ListInventoryAdjustments = %+v, quiero una corrección de -3", adjustments)
+		}
+
+		// Con la versión vieja el cambio se rechaza y no se aplica
+		stale := *b
+		stale.Version = 1
+		stale.BookName = "Pisado"
+		if err := repo.UpdateBook(ctx, &stale, nil, nil); !errors.Is(err, books.ErrVersionMismatch) {
+			t.Fatalf("UpdateBook con versión vieja = %v, quiero ErrVersionMismatch", err)
+		}
+		if name := getBook(t, repo, id).Book.BookName; name != "Cambiado" {
+			t.Fatalf("el cambio rechazado quedó aplicado: %q", name)
+		}
+
+		missing := *b
+		missing.ID = 999
+		if err := repo.UpdateBook(ctx, &missing, nil, nil); !errors.Is(err, books.ErrBookNotFound) {
+			t.Fatalf("UpdateBook de un libro inexistente = %v, quiero ErrBookNotFound", err)
+		}
+	})
+
+	t.Run("UpdateStockSyncsStatusAndRental", func(t *testing.T) {
+		repo := newRepo(t)
+		both := saleBook("Ambos")
+		both.TransactionType = books.ModeBoth
+		id := createBook(t, repo, both, 5, 4)
+		b := getBook(t, repo, id).Book
+
+		// El stock de arriendo no puede superar el total
+		tooMuch := int64(6)
+		if err := repo.UpdateBook(ctx, b, nil, &tooMuch); !errors.Is(err, books.ErrInvalidBook) {
+			t.Fatalf("rental_stock sobre el total = %v, quiero ErrInvalidBook", err)
+		}
+
+		// Si el stock baja, el de arriendo baja con él
+		b = getBook(t, repo, id).Book
+		stock := int64(2)
+		if err := repo.UpdateBook(ctx, b, &stock, nil); err != nil {
+			t.Fatal(err)
+		}
+		got := getBook(t, repo, id)
+		if got.AvailableQuantity != 2 || got.RentalQuantity != 2 || !got.Book.Status {
+			t.Fatalf("stock %d (arriendo %d, status %v), quiero 2 (arriendo 2, true)", got.AvailableQuantity, got.RentalQuantity, got.Book.Status)
+		}
+
+		stock = 0
+		if err := repo.UpdateBook(ctx, got.Book, &stock, nil); err != nil {
+			t.Fatal(err)
+		}
+		got = getBook(t, repo, id)
+		if got.AvailableQuantity != 0 || got.RentalQuantity != 0 || got.Book.Status {
+			t.Fatalf("stock %d (arriendo %d, status %v), quiero 0 (arriendo 0, false)", got.AvailableQuantity, got.RentalQuantity, got.Book.Status)
+		}
+	})
+
+	t.Run("ArchiveRestoreDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		id := createBook(t, repo, saleBook("Archivable"), 1, 0)
+
+		if err := repo.ArchiveBook(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		got := getBook(t, repo, id)
+		if got.Book.ArchivedAt == nil || got.Book.Version != 2 {
+			t.Fatalf("tras ArchiveBook = %+v, quiero archived_at y versión 2", got.Book)
+		}
+		first := *got.Book.ArchivedAt
+		if err := repo.ArchiveBook(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if at := getBook(t, repo, id).Book.ArchivedAt; at == nil || !at.Equal(first) {
+			t.Fatalf("archivar de nuevo cambió la fecha: %v, quiero %v", at, first)
+		}
+		if list, _ := repo.ListBook(ctx, books.CatalogFilter{}); len(list) != 0 {
+			t.Fatalf("el libro archivado sigue en el catálogo: %v", ids(list))
+		}
+
+		if err := repo.RestoreBook(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if getBook(t, repo, id).Book.ArchivedAt != nil {
+			t.Fatal("RestoreBook no quitó archived_at")
+		}
+
+		if err := repo.DeleteBook(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := repo.GetBookByID(ctx, id, nil); err != nil || got.Book != nil {
+			t.Fatalf("el libro borrado sigue: %+v, %v", got.Book, err)
+		}
+
+		for name, fn := range map[string]func(context.Context, int64) error{
+			"ArchiveBook": repo.ArchiveBook,
+			"RestoreBook": repo.RestoreBook,
+			"DeleteBook":  repo.DeleteBook,
+		} {
+			if err := fn(ctx, 999); !errors.Is(err, books.ErrBookNotFound) {
+				t.Errorf("%s(999) = %v, quiero ErrBookNotFound", name, err)
+			}
+		}
+	})
+
+	t.Run("PopularityAndRecommendations", func(t *testing.T) {
+		repo := newRepo(t)
+		a := createBook(t, repo, saleBook("A"), 1, 0)
+		b := createBook(t, repo, saleBook("B"), 1, 0)
+		story := saleBook("C")
+		story.BookCategory = "Cuento"
+		c := createBook(t, repo, story, 1, 0)
+
+		activity, err := repo.ListActivity(ctx, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(activity) != 0 {
+			t.Fatalf("ListActivity sin ventas ni préstamos = %+v", activity)
+		}
+
+		if err := repo.UpdatePopularityScores(ctx, map[int64]int64{a: 5, b: 9}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdatePopularityScores(ctx, map[int64]int64{b: 3, c: 7}); err != nil {
+			t.Fatal(err)
+		}
+		for id, want := range map[int64]int64{a: 0, b: 3, c: 7} {
+			if got := getBook(t, repo, id).Book.PopularityScore; got != want {
+				t.Errorf("popularity_score de %d = %d, quiero %d", id, got, want)
+			}
+		}
+
+		popular := []struct {
+			name     string
+			scores   map[int64]int64
+			category string
+			limit    int
+			want     []int64
+			score    []int64
+		}{
+			{"Stored", nil, "", 0, []int64{c, b, a}, []int64{7, 3, 0}},
+			{"Category", nil, "NOVELA", 1, []int64{b}, []int64{3}},
+			{"Window", map[int64]int64{a: 4}, "", 2, []int64{a, b}, []int64{4, 0}},
+			{"EmptyWindow", map[int64]int64{}, "novela", 0, []int64{a, b}, []int64{0, 0}},
+		}
+		for _, tt := range popular {
+			got, err := repo.ListPopular(ctx, tt.scores, tt.category, tt.limit)
+			if err != nil {
+				t.Fatalf("ListPopular %s: %v", tt.name, err)
+			}
+			scores := make([]int64, len(got))
+			for i, bwi := range got {
+				scores[i] = bwi.Book.PopularityScore
+			}
+			if !equal(ids(got), tt.want) || !equal(scores, tt.score) {
+				t.Errorf("ListPopular %s = %v con puntajes %v, quiero %v con %v", tt.name, ids(got), scores, tt.want, tt.score)
+			}
+		}
+
+		// Sin historial se recomiendan los más populares
+		rec, err := repo.ListRecommendations(ctx, 1, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(rec); !equal(got, []int64{c, b}) {
+			t.Fatalf("ListRecommendations = %v, quiero %v", got, []int64{c, b})
+		}
+		related, err := repo.ListRelated(ctx, a, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(related) != 0 {
+			t.Fatalf("ListRelated sin historial = %v", ids(related))
+		}
+	})
+
+	t.Run("Promotions", func(t *testing.T) {
+		repo := newRepo(t)
+		id := createBook(t, repo, saleBook("Promo"), 1, 0)
+		now := time.Now().UTC().Truncate(time.Second)
+		category := "Novela"
+
+		current, err := repo.CreatePromotion(ctx, &books.Promotion{
+			BookID: &id, DiscountType: books.DiscountPercentage, DiscountValue: 10,
+			StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		future, err := repo.CreatePromotion(ctx, &books.Promotion{
+			BookCategory: &category, DiscountType: books.DiscountFixed, DiscountValue: 500,
+			StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(48 * time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current <= 0 || future <= 0 || current == future {
+			t.Fatalf("CreatePromotion devolvió los ids %d y %d", current, future)
+		}
+
+		all, err := repo.ListPromotions(ctx, now, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 || all[0].ID != current || all[1].ID != future {
+			t.Fatalf("ListPromotions = %+v, quiero %d y %d por fecha de inicio", all, current, future)
+		}
+		p := all[1]
+		if p.BookID != nil || p.BookCategory == nil || *p.BookCategory != category || p.DiscountValue != 500 ||
+			!p.StartsAt.Equal(now.Add(24*time.Hour)) || !p.EndsAt.Equal(now.Add(48*time.Hour)) {
+			t.Fatalf("promoción leída = %+v", p)
+		}
+		active, err := repo.ListPromotions(ctx, now, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(active) != 1 || active[0].ID != current {
+			t.Fatalf("ListPromotions vigentes = %+v, quiero solo %d", active, current)
+		}
+
+		if err := repo.DeletePromotion(ctx, current); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.DeletePromotion(ctx, current); !errors.Is(err, books.ErrPromotionNotFound) {
+			t.Fatalf("DeletePromotion de nuevo = %v, quiero ErrPromotionNotFound", err)
+		}
+	})
+
+	t.Run("AdjustInventory", func(t *testing.T) {
+		repo := newRepo(t)
+		id := createBook(t, repo, saleBook("Ajustable"), 0, 0)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		add := &books.InventoryAdjustment{BookID: id, Delta: 3, Reason: books.ReasonRestock, Note: "caja", CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, add); err != nil {
+			t.Fatal(err)
+		}
+		if add.ID <= 0 || add.QuantityAfter != 3 {
+			t.Fatalf("AdjustInventory dejó id %d y quantity_after %d, quiero un id y 3", add.ID, add.QuantityAfter)
+		}
+		got := getBook(t, repo, id)
+		if got.AvailableQuantity != 3 || !got.Book.Status || got.Book.Version != 2 {
+			t.Fatalf("tras el ajuste: stock %d, status %v, versión %d", got.AvailableQuantity, got.Book.Status, got.Book.Version)
+		}
+
+		// Un ajuste que deja el stock bajo cero no cambia nada
+		loss := &books.InventoryAdjustment{BookID: id, Delta: -4, Reason: books.ReasonLoss, CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, loss); !errors.Is(err, books.ErrInsufficientStock) {
+			t.Fatalf("ajuste bajo cero = %v, quiero ErrInsufficientStock", err)
+		}
+		if got := getBook(t, repo, id); got.AvailableQuantity != 3 || got.Book.Version != 2 {
+			t.Fatalf("el ajuste rechazado cambió el libro: stock %d, versión %d", got.AvailableQuantity, got.Book.Version)
+		}
+
+		rental := &books.InventoryAdjustment{BookID: id, Delta: 1, Reason: books.ReasonRestock, Mode: books.ModeRental, CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, rental); !errors.Is(err, books.ErrInvalidAdjustment) {
+			t.Fatalf("ajuste de arriendo en un libro de venta = %v, quiero ErrInvalidAdjustment", err)
+		}
+
+		sub := &books.InventoryAdjustment{BookID: id, Delta: -1, Reason: books.ReasonDamage, CreatedAt: now.Add(time.Second)}
+		if err := repo.AdjustInventory(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+		list, err := repo.ListInventoryAdjustments(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].ID != sub.ID || list[1].ID != add.ID || list[1].Note != "caja" ||
+			list[1].QuantityAfter != 3 || !list[1].CreatedAt.Equal(now) {
+			t.Fatalf("ListInventoryAdjustments = %+v, quiero los dos ajustes, el último primero", list)
+		}
+
+		missing := &books.InventoryAdjustment{BookID: 999, Delta: 1, Reason: books.ReasonRestock, CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, missing); !errors.Is(err, books.ErrBookNotFound) {
+			t.Fatalf("ajuste de un libro inexistente = %v, quiero ErrBookNotFound", err)
+		}
+	})
+
+	// Ajustes simultáneos sobre el mismo libro: ninguno se pierde, ninguno
+	// falla por bloqueos del motor y el stock nunca queda bajo cero. Tiene
+	// sentido con go test -race.
+	t.Run("ConcurrentAdjustments", func(t *testing.T) {
+		repo := newRepo(t)
+		id := createBook(t, repo, saleBook("Concurrente"), 10, 0)
+
+		const workers = 40
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var ok, short int
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				adj := &books.InventoryAdjustment{BookID: id, Delta: -1, Reason: books.ReasonLoss, CreatedAt: time.Now()}
+				err := repo.AdjustInventory(ctx, adj)
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					ok++
+				case errors.Is(err, books.ErrInsufficientStock):
+					short++
+				default:
+					t.Error(err)
+				}
+			}()
+			// Lecturas intercaladas con las escrituras
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := repo.ListBook(ctx, books.CatalogFilter{}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if ok != 10 || short != workers-10 {
+			t.Fatalf("%d ajustes aplicados y %d rechazados, quiero 10 y %d", ok, short, workers-10)
+		}
+		got := getBook(t, repo, id)
+		if got.AvailableQuantity != 0 || got.Book.Status || got.Book.Version != 11 {
+			t.Fatalf("stock %d, status %v, versión %d; quiero 0, false y 11", got.AvailableQuantity, got.Book.Status, got.Book.Version)
+		}
+		list, err := repo.ListInventoryAdjustments(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 10 {
+			t.Fatalf("quedaron %d ajustes registrados, quiero 10", len(list))
+		}
+	})
+
+	t.Run("AdjustRentalStock", func(t *testing.T) {
+		repo := newRepo(t)
+		both := saleBook("Ambos")
+		both.TransactionType = books.ModeBoth
+		id := createBook(t, repo, both, 2, 1)
+		now := time.Now().UTC().Truncate(time.Second)
+
+		adj := &books.InventoryAdjustment{BookID: id, Delta: 2, Reason: books.ReasonRestock, Mode: books.ModeRental, CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, adj); err != nil {
+			t.Fatal(err)
+		}
+		got := getBook(t, repo, id)
+		if got.AvailableQuantity != 4 || got.RentalQuantity != 3 {
+			t.Fatalf("stock %d (arriendo %d), quiero 4 (arriendo 3)", got.AvailableQuantity, got.RentalQuantity)
+		}
+
+		// Lo que se resta en modo venta sale primero del stock de venta
+		adj = &books.InventoryAdjustment{BookID: id, Delta: -2, Reason: books.ReasonLoss, CreatedAt: now}
+		if err := repo.AdjustInventory(ctx, adj); err != nil {
+			t.Fatal(err)
+		}
+		got = getBook(t, repo, id)
+		if got.AvailableQuantity != 2 || got.RentalQuantity != 2 {
+			t.Fatalf("stock %d (arriendo %d), quiero 2 (arriendo 2)", got.AvailableQuantity, got.RentalQuantity)
+		}
+	})
+
+	t.Run("StockAlerts", func(t *testing.T) {
+		repo := newRepo(t)
+		a := createBook(t, repo, saleBook("A"), 1, 0)
+		b := createBook(t, repo, saleBook("B"), 0, 0)
+		t0 := time.Now().UTC().Truncate(time.Second)
+
+		low := []books.StockAlert{
+			{BookID: a, AvailableQuantity: 1, ReorderThreshold: 3, DailyVelocity: 0.5, SuggestedQuantity: 17},
+			{BookID: b, AvailableQuantity: 0, ReorderThreshold: 2, SuggestedQuantity: 2},
+		}
+		if err := repo.SyncStockAlerts(ctx, low, t0); err != nil {
+			t.Fatal(err)
+		}
+		open, err := repo.ListStockAlerts(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(open) != 2 || open[0].BookID != a || open[0].BookName != "A" || open[0].DailyVelocity != 0.5 ||
+			open[0].SuggestedQuantity != 17 || !open[0].CreatedAt.Equal(t0) || open[0].ResolvedAt != nil {
+			t.Fatalf("ListStockAlerts = %+v, quiero las dos alertas, la de mayor sugerencia primero", open)
+		}
+		first := open[0].ID
+
+		// La alerta de a se actualiza y la de b se resuelve
+		t1 := t0.Add(time.Minute)
+		low = []books.StockAlert{{BookID: a, AvailableQuantity: 0, ReorderThreshold: 3, SuggestedQuantity: 20}}
+		if err := repo.SyncStockAlerts(ctx, low, t1); err != nil {
+			t.Fatal(err)
+		}
+		open, err = repo.ListStockAlerts(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(open) != 1 || open[0].ID != first || open[0].SuggestedQuantity != 20 ||
+			!open[0].CreatedAt.Equal(t0) || !open[0].UpdatedAt.Equal(t1) {
+			t.Fatalf("ListStockAlerts abiertas = %+v, quiero la alerta %d actualizada", open, first)
+		}
+		all, err := repo.ListStockAlerts(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(all) != 2 || all[1].BookID != b || all[1].ResolvedAt == nil || !all[1].ResolvedAt.Equal(t1) {
+			t.Fatalf("ListStockAlerts = %+v, quiero la alerta de %d resuelta", all, b)
+		}
+
+		if err := repo.SyncStockAlerts(ctx, nil, t1); err != nil {
+			t.Fatal(err)
+		}
+		if open, _ = repo.ListStockAlerts(ctx, true); len(open) != 0 {
+			t.Fatalf("quedaron alertas abiertas: %+v", open)
+		}
+	})
+}
+
+// saleBook es un libro de venta mínimo para las pruebas
+func saleBook(name string) *books.Book {
+	return &books.Book{
+		BookName:         name,
+		BookCategory:     "Novela",
+		TransactionType:  books.ModeSale,
+		Price:            1000,
+		RentalPeriodDays: 14,
+		Status:           true,
+	}
+}
+
+func createBook(t *testing.T, repo books.Repository, b *books.Book, stock, rentalStock int64) int64 {
+	t.Helper()
+	id, err := repo.CreateBook(context.Background(), b, stock, rentalStock)
+	if err != nil {
+		t.Fatalf("CreateBook(%s): %v", b.BookName, err)
+	}
+	return id
+}
+
+func getBook(t *testing.T, repo books.Repository, id int64) books.BookWithInventory {
+	t.Helper()
+	got, err := repo.GetBookByID(context.Background(), id, nil)
+	if err != nil {
+		t.Fatalf("GetBookByID(%d): %v", id, err)
+	}
+	if got.Book == nil {
+		t.Fatalf("GetBookByID(%d): no existe", id)
+	}
+	return got
+}
+
+func ids(list []books.BookWithInventory) []int64 {
+	out := make([]int64, len(list))
+	for i, b := range list {
+		out[i] = b.Book.ID
+	}
+	return out
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
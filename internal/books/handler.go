@@ -1,24 +1,53 @@
 package books
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"strconv"
-	"log"
 	"io"
-	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+	"math"
+	"strings"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/apperr"
+	httpx "uzm-server/internal/http"
+	"uzm-server/internal/logging"
+
 	"github.com/gin-gonic/gin"
 )
 
+// bookResponse es la forma de un libro en el contrato v1 de la API, la que
+// lee el CLI publicado. No se le quitan ni cambian campos: una versión nueva
+// define su propia forma y la entrega con su propio Handler (ver Handler.book).
 type bookResponse struct {
 	Id         int64   `json:"id"`
 	BookName   string  `json:"book_name"`
 	BookCategory string `json:"book_category"`
 	TransactionType string `json:"transaction_type"`
 	Price      int64 `json:"price"`
+	SalePrice        *int64 `json:"sale_price,omitempty"`         // solo si el libro se vende
+	RentalPrice      *int64 `json:"rental_price,omitempty"`       // por período, solo si se arrienda
+	RentalPeriodDays int64  `json:"rental_period_days,omitempty"`
+	EffectivePrice int64  `json:"effective_price"`
+	PromotionID    *int64 `json:"promotion_id,omitempty"`
 	Status     bool    `json:"status"`
 	PopularityScore int64 `json:"popularity_score"`
+	Archived   bool       `json:"archived"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	ReorderThreshold int64 `json:"reorder_threshold"`
+	CoverURL          string `json:"cover_url,omitempty"`
+	CoverThumbnailURL string `json:"cover_thumbnail_url,omitempty"`
+	RatingAverage float64 `json:"rating_average"`
+	ReviewCount   int64   `json:"review_count"`
 	Inventory       struct {
         AvailableQuantity int64 `json:"available_quantity"`
+        SaleQuantity      int64 `json:"sale_quantity"`
+        RentalQuantity    int64 `json:"rental_quantity"`
     } `json:"inventory"`
 }
 
@@ -32,110 +61,692 @@ func toBookResponse(bwi *BookWithInventory) *bookResponse {
         BookCategory:    b.BookCategory,
         TransactionType: b.TransactionType,
         Price:           b.Price,
+        SalePrice:       b.SalePrice(),
+        RentalPrice:     b.RentalPricePerPeriod(),
+        EffectivePrice:  b.EffectivePrice,
+        PromotionID:     b.PromotionID,
         Status:          b.Status,
         PopularityScore: b.PopularityScore,
+        Archived:        b.ArchivedAt != nil,
+        ArchivedAt:      b.ArchivedAt,
+        ReorderThreshold: b.ReorderThreshold,
+        RatingAverage:   math.Round(b.RatingAverage*10) / 10,
+        ReviewCount:     b.ReviewCount,
+    }
+    if b.CoverUpdatedAt != nil {
+        resp.CoverURL = covers.URL(b.ID, *b.CoverUpdatedAt, false)
+        resp.CoverThumbnailURL = covers.URL(b.ID, *b.CoverUpdatedAt, true)
+    }
+    if b.ForRent() {
+        resp.RentalPeriodDays = b.RentalPeriodDays
     }
     resp.Inventory.AvailableQuantity = bwi.AvailableQuantity
+    resp.Inventory.SaleQuantity = bwi.SaleQuantity()
+    resp.Inventory.RentalQuantity = bwi.RentalQuantity
     return resp
 }
 
+// bookETag arma el ETag fuerte de un libro: la versión, que es lo que compara
+// If-Match, y un hash del cuerpo de la respuesta. La versión sola no basta
+// para If-None-Match porque la representación cambia sin escribir el libro
+// (reseñas, popularity_score, promociones que empiezan o terminan).
+func bookETag(version int64, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(version, 10) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// parseETag lee la versión de un If-Match: lo que va antes del guion en los
+// ETag de bookETag, o el número entero en los ETag sin hash. "*" se traduce a
+// 0 (cualquier versión); las etiquetas débiles o ajenas no calzan con ninguna
+// versión.
+func parseETag(v string) (int64, bool) {
+	v = strings.TrimSpace(v)
+	if v == "*" {
+		return 0, true
+	}
+	if len(v) < 3 || v[0] != '"' || v[len(v)-1] != '"' {
+		return 0, false
+	}
+	num, _, _ := strings.Cut(v[1:len(v)-1], "-")
+	version, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// writeBook responde con la representación del libro y su ETag. En los GET
+// responde 304 si If-None-Match incluye ese ETag.
+func (h *Handler) writeBook(c *gin.Context, status int, bwi *BookWithInventory) {
+	body, err := json.Marshal(h.book(bwi))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	etag := bookETag(bwi.Book.Version, body)
+	c.Header("ETag", etag)
+	if c.Request.Method == http.MethodGet && noneMatch(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(status, "application/json; charset=utf-8", body)
+}
+
+// noneMatch indica si If-None-Match incluye el ETag actual (comparación débil)
+func noneMatch(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
 type Handler struct {
 	service Service
+	// book arma la representación de un libro en las respuestas. Es lo único
+	// que cambia entre versiones de la API: un /api/v2 registraría las mismas
+	// rutas con otro Handler cuyo book entregue la forma nueva.
+	book func(*BookWithInventory) any
+	// exportTimeout es el plazo de escritura de /books/export, que reemplaza
+	// al WriteTimeout del servidor; 0 = sin límite
+	exportTimeout time.Duration
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+// NewHandler crea el Handler del contrato v1
+func NewHandler(service Service, exportTimeout time.Duration) *Handler {
+	return &Handler{
+		service:       service,
+		book:          func(bwi *BookWithInventory) any { return toBookResponse(bwi) },
+		exportTimeout: exportTimeout,
+	}
 }
 
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/books", h.ListBooks)
+	r.GET("/books/export", h.exportBooks)
+	r.GET("/books/popular", h.listPopular)
 	r.GET("/books/:id", h.getBookByID)
 	r.POST("/books", h.createBook)
 	r.PATCH("/books/:id", h.updateBook)
+	r.DELETE("/books/:id", h.deleteBook)
+	r.POST("/books/:id/archive", h.archiveBook)
+	r.POST("/books/:id/restore", h.restoreBook)
+	r.GET("/books/:id/related", h.listRelated)
+	r.GET("/users/:id/recommendations", h.listRecommendations)
+	r.GET("/books/:id/price-history", h.listPriceHistory)
+	r.GET("/promotions", h.listPromotions)
+	r.POST("/promotions", h.createPromotion)
+	r.DELETE("/promotions/:id", h.deletePromotion)
+	r.POST("/books/:id/inventory/adjustments", h.adjustInventory)
+	r.GET("/books/:id/inventory/adjustments", h.listInventoryAdjustments)
+	r.GET("/inventory/alerts", h.listStockAlerts)
 }
 
 func (h *Handler) createBook(c *gin.Context) {
-    // Diagnóstico: asegúrate del content-type
-    ct := c.GetHeader("Content-Type")
-    log.Println("Content-Type:", ct)
-
-    // Si quieres loguear el body sin romper el bind, hay que reponerlo:
-    b, _ := io.ReadAll(c.Request.Body)
-    log.Println("RAW BODY:", string(b))
-    c.Request.Body = io.NopCloser(bytes.NewBuffer(b))
-
     var req CreateBookInput
     if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "JSON inválido: " + err.Error()})
+        httpx.BindError(c, err)
         return
     }
-    log.Printf("REQ (after bind): %#v", req)
 
 	id, err := h.service.CreateBook(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		_ = c.Error(err)
+		return
+	}
+	bwi, err := h.service.GetBookByID(c.Request.Context(), id, nil)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	bwi, _ := h.service.GetBookByID(c.Request.Context(), id, nil)
-	c.JSON(http.StatusCreated, toBookResponse(bwi))
+	h.writeBook(c, http.StatusCreated, bwi)
 }
 
 
-func (h *Handler) ListBooks(c *gin.Context) {
-	statusParam := c.Query("status")
-	var onlyAvailable *bool
-	if statusParam != "" {
+// parseCatalogFilter lee los filtros ?status= y ?mode= compartidos por el
+// listado y la exportación
+func parseCatalogFilter(c *gin.Context) (CatalogFilter, bool) {
+	var filter CatalogFilter
+	if statusParam := c.Query("status"); statusParam != "" {
 		parsed, err := strconv.ParseBool(statusParam)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter"})
-			return
+			_ = c.Error(apperr.InvalidParam("status", "debe ser true o false"))
+			return filter, false
 		}
-		onlyAvailable = &parsed
+		filter.OnlyAvailable = parsed
+	}
+	switch mode := normMode(c.Query("mode")); mode {
+	case "", ModeSale, ModeRental:
+		filter.Mode = mode
+	default:
+		_ = c.Error(apperr.InvalidParam("mode", "debe ser venta o arriendo"))
+		return filter, false
 	}
+	return filter, true
+}
 
-	books, err := h.service.ListBook(c.Request.Context(), onlyAvailable)
+func (h *Handler) ListBooks(c *gin.Context) {
+	filter, ok := parseCatalogFilter(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.service.ListBook(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 
-	out := make([]*bookResponse, 0, len(books))
+	c.JSON(http.StatusOK, gin.H{"books": h.books(books)})
+}
+
+// Límite por defecto y máximo de resultados en populares, relacionados y recomendaciones
+const (
+	defaultRankingLimit = 10
+	maxRankingLimit     = 100
+)
+
+// parseLimit lee ?limit= con el valor por defecto y máximo de los rankings
+func parseLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return defaultRankingLimit, true
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 || limit > maxRankingLimit {
+		_ = c.Error(apperr.InvalidParam("limit", fmt.Sprintf("debe ser un entero entre 1 y %d", maxRankingLimit)))
+		return 0, false
+	}
+	return limit, true
+}
+
+func (h *Handler) books(books []*BookWithInventory) []any {
+	out := make([]any, 0, len(books))
 	for _, bwi := range books {
-		out = append(out, toBookResponse(bwi))
+		out = append(out, h.book(bwi))
 	}
-	c.JSON(http.StatusOK, gin.H{"books": out})
+	return out
 }
 
-func (h *Handler) getBookByID(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.ParseInt(idParam, 10, 64)
+func (h *Handler) listPopular(c *gin.Context) {
+	window, err := ParsePopularityWindow(c.Query("window"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+		_ = c.Error(apperr.InvalidParam("window", err.Error()))
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	books, err := h.service.ListPopular(c.Request.Context(), window, c.Query("category"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"window": window, "books": h.books(books)})
+}
+
+func (h *Handler) listRelated(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+	books, err := h.service.ListRelated(c.Request.Context(), id, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"books": h.books(books)})
+}
+
+// listRecommendations vive en el paquete books porque responde libros, aunque
+// cuelga de /users/:id
+func (h *Handler) listRecommendations(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+	books, err := h.service.ListRecommendations(c.Request.Context(), id, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"books": h.books(books)})
+}
+
+func (h *Handler) getBookByID(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
 		return
 	}
 	book, err := h.service.GetBookByID(c.Request.Context(), id, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, toBookResponse(book))
+	h.writeBook(c, http.StatusOK, book)
 }
+
+// updateBook exige If-Match con el ETag de la última lectura para no pisar
+// cambios concurrentes; "*" actualiza sin importar la versión
 func (h *Handler) updateBook(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.ParseInt(idParam, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book ID"})
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		_ = c.Error(ErrIfMatchRequired)
+		return
+	}
+	version, ok := parseETag(ifMatch)
+	if !ok {
+		_ = c.Error(ErrVersionMismatch)
 		return
 	}
 	var input UpdateBookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		httpx.BindError(c, err)
+		return
+	}
+	book, err := h.service.UpdateBook(c.Request.Context(), id, version, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	h.writeBook(c, http.StatusOK, book)
+}
+
+func (h *Handler) archiveBook(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	book, err := h.service.ArchiveBook(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	h.writeBook(c, http.StatusOK, book)
+}
+
+func (h *Handler) restoreBook(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	book, err := h.service.RestoreBook(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	h.writeBook(c, http.StatusOK, book)
+}
+
+// deleteBook borra definitivamente un libro; si tiene historial se debe archivar
+func (h *Handler) deleteBook(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteBook(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type priceChangeResponse struct {
+	ID        int64     `json:"id"`
+	OldPrice  *int64    `json:"old_price"`
+	NewPrice  int64     `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func (h *Handler) listPriceHistory(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	history, err := h.service.ListPriceHistory(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]priceChangeResponse, 0, len(history))
+	for _, pc := range history {
+		out = append(out, priceChangeResponse{ID: pc.ID, OldPrice: pc.OldPrice, NewPrice: pc.NewPrice, ChangedAt: pc.ChangedAt})
+	}
+	c.JSON(http.StatusOK, gin.H{"book_id": id, "history": out})
+}
+
+type promotionResponse struct {
+	ID            int64     `json:"id"`
+	BookID        *int64    `json:"book_id,omitempty"`
+	BookCategory  *string   `json:"book_category,omitempty"`
+	DiscountType  string    `json:"discount_type"`
+	DiscountValue int64     `json:"discount_value"`
+	StartsAt      time.Time `json:"starts_at"`
+	EndsAt        time.Time `json:"ends_at"`
+}
+
+func toPromotionResponse(p *Promotion) promotionResponse {
+	return promotionResponse{
+		ID:            p.ID,
+		BookID:        p.BookID,
+		BookCategory:  p.BookCategory,
+		DiscountType:  p.DiscountType,
+		DiscountValue: p.DiscountValue,
+		StartsAt:      p.StartsAt,
+		EndsAt:        p.EndsAt,
+	}
+}
+
+// listPromotions lista todas las promociones, o solo las vigentes con ?active=true
+func (h *Handler) listPromotions(c *gin.Context) {
+	onlyActive := false
+	if v := c.Query("active"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			_ = c.Error(apperr.InvalidParam("active", "debe ser true o false"))
+			return
+		}
+		onlyActive = parsed
+	}
+	promos, err := h.service.ListPromotions(c.Request.Context(), onlyActive)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]promotionResponse, 0, len(promos))
+	for _, p := range promos {
+		out = append(out, toPromotionResponse(p))
+	}
+	c.JSON(http.StatusOK, gin.H{"promotions": out})
+}
+
+func (h *Handler) createPromotion(c *gin.Context) {
+	var input CreatePromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+	p, err := h.service.CreatePromotion(c.Request.Context(), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, toPromotionResponse(p))
+}
+
+func (h *Handler) deletePromotion(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeletePromotion(c.Request.Context(), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type adjustmentResponse struct {
+	ID            int64     `json:"id"`
+	BookID        int64     `json:"book_id"`
+	UserID        *int64    `json:"user_id"`
+	Delta         int64     `json:"delta"`
+	Reason        string    `json:"reason"`
+	Note          string    `json:"note,omitempty"`
+	QuantityAfter int64     `json:"quantity_after"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func toAdjustmentResponse(a *InventoryAdjustment) adjustmentResponse {
+	return adjustmentResponse{
+		ID:            a.ID,
+		BookID:        a.BookID,
+		UserID:        a.UserID,
+		Delta:         a.Delta,
+		Reason:        a.Reason,
+		Note:          a.Note,
+		QuantityAfter: a.QuantityAfter,
+		CreatedAt:     a.CreatedAt,
+	}
+}
+
+// adjustInventory suma o resta stock; el responsable se identifica con X-User-ID
+func (h *Handler) adjustInventory(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := httpx.RequireUserID(c)
+	if !ok {
+		return
+	}
+	var input AdjustInventoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
 		return
 	}
-	book, err := h.service.UpdateBook(c.Request.Context(), id, input)
+	adj, err := h.service.AdjustInventory(c.Request.Context(), id, userID, input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, toBookResponse(book))
+	c.JSON(http.StatusCreated, toAdjustmentResponse(adj))
+}
+
+func (h *Handler) listInventoryAdjustments(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	adjs, err := h.service.ListInventoryAdjustments(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]adjustmentResponse, 0, len(adjs))
+	for _, a := range adjs {
+		out = append(out, toAdjustmentResponse(a))
+	}
+	c.JSON(http.StatusOK, gin.H{"book_id": id, "adjustments": out})
+}
+
+type stockAlertResponse struct {
+	ID                int64      `json:"id"`
+	BookID            int64      `json:"book_id"`
+	BookName          string     `json:"book_name"`
+	AvailableQuantity int64      `json:"available_quantity"`
+	ReorderThreshold  int64      `json:"reorder_threshold"`
+	DailyVelocity     float64    `json:"daily_velocity"`
+	SuggestedQuantity int64      `json:"suggested_quantity"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+}
+
+// listStockAlerts lista las alertas abiertas, o todas con ?status=all
+func (h *Handler) listStockAlerts(c *gin.Context) {
+	var onlyOpen bool
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		onlyOpen = true
+	case "all":
+	default:
+		_ = c.Error(apperr.InvalidParam("status", "debe ser open o all"))
+		return
+	}
+	alerts, err := h.service.ListStockAlerts(c.Request.Context(), onlyOpen)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]stockAlertResponse, 0, len(alerts))
+	for _, a := range alerts {
+		out = append(out, stockAlertResponse{
+			ID:                a.ID,
+			BookID:            a.BookID,
+			BookName:          a.BookName,
+			AvailableQuantity: a.AvailableQuantity,
+			ReorderThreshold:  a.ReorderThreshold,
+			DailyVelocity:     a.DailyVelocity,
+			SuggestedQuantity: a.SuggestedQuantity,
+			CreatedAt:         a.CreatedAt,
+			UpdatedAt:         a.UpdatedAt,
+			ResolvedAt:        a.ResolvedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": out})
+}
+
+// Cantidad de filas escritas entre cada flush al cliente durante la exportación
+const exportFlushEvery = 100
+
+// exportRentalPrice deja vacía la celda de los libros que no se arriendan
+func exportRentalPrice(b *Book) string {
+	if p := b.RentalPricePerPeriod(); p != nil {
+		return strconv.FormatInt(*p, 10)
+	}
+	return ""
+}
+
+var exportCSVHeader = []string{
+	"id", "book_name", "book_category", "transaction_type",
+	"price", "effective_price", "status", "popularity_score", "available_quantity",
+	"rental_price", "rental_period_days", "sale_quantity", "rental_quantity",
+}
+
+// exportBooks entrega el catálogo completo en csv, json o ndjson escribiendo
+// cada fila a medida que se lee de la base de datos.
+func (h *Handler) exportBooks(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "json":
+		contentType = "application/json; charset=utf-8"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	default:
+		_ = c.Error(apperr.InvalidParam("format", "debe ser csv, json o ndjson"))
+		return
+	}
+	filter, ok := parseCatalogFilter(c)
+	if !ok {
+		return
+	}
+
+	// Un catálogo grande tarda más que el WriteTimeout del resto de las rutas
+	var deadline time.Time
+	if h.exportTimeout > 0 {
+		deadline = time.Now().Add(h.exportTimeout)
+	}
+	err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline)
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		_ = c.Error(err)
+		return
+	}
+
+	// Los encabezados del archivo se ponen recién con la primera fila: si el
+	// servicio falla antes, la respuesta es un problem+json común
+	filename := fmt.Sprintf("catalogo_%s.%s", time.Now().Format("20060102-150405"), format)
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Status(http.StatusOK)
+	}
+
+	w := c.Writer
+	csvw := csv.NewWriter(w)
+	enc := json.NewEncoder(w)
+	rows := 0
+
+	err = h.service.ExportBooks(c.Request.Context(), filter, func(bwi *BookWithInventory) error {
+		start()
+		switch format {
+		case "csv":
+			if rows == 0 {
+				if err := csvw.Write(exportCSVHeader); err != nil {
+					return err
+				}
+			}
+			b := bwi.Book
+			if err := csvw.Write([]string{
+				strconv.FormatInt(b.ID, 10), b.BookName, b.BookCategory, b.TransactionType,
+				strconv.FormatInt(b.Price, 10), strconv.FormatInt(b.EffectivePrice, 10), strconv.FormatBool(b.Status),
+				strconv.FormatInt(b.PopularityScore, 10), strconv.FormatInt(bwi.AvailableQuantity, 10),
+				exportRentalPrice(b), strconv.FormatInt(b.RentalPeriodDays, 10),
+				strconv.FormatInt(bwi.SaleQuantity(), 10), strconv.FormatInt(bwi.RentalQuantity, 10),
+			}); err != nil {
+				return err
+			}
+		case "json":
+			sep := ","
+			if rows == 0 {
+				sep = "["
+			}
+			if _, err := io.WriteString(w, sep); err != nil {
+				return err
+			}
+			if err := enc.Encode(h.book(bwi)); err != nil {
+				return err
+			}
+		case "ndjson":
+			if err := enc.Encode(h.book(bwi)); err != nil {
+				return err
+			}
+		}
+		rows++
+		if rows%exportFlushEvery == 0 {
+			csvw.Flush()
+			w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		// Si aún no se escribió nada todavía se puede responder con un error normal
+		if !w.Written() {
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Disposition")
+			httpx.WriteProblem(c, err)
+			return
+		}
+		logging.FromContext(c.Request.Context()).Warn("exportación de catálogo interrumpida", "rows", rows, "err", err)
+		return
+	}
+
+	start()
+	switch format {
+	case "csv":
+		if rows == 0 {
+			_ = csvw.Write(exportCSVHeader)
+		}
+		csvw.Flush()
+	case "json":
+		if rows == 0 {
+			_, _ = io.WriteString(w, "[")
+		}
+		_, _ = io.WriteString(w, "]\n")
+	}
+	w.Flush()
 }
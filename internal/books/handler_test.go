@@ -0,0 +1,408 @@
+package books_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/http/httpxtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeService responde según el id: 1 existe, 2 tiene historial y préstamos
+// pendientes, cualquier otro no existe. Guarda lo último que recibió.
+type fakeService struct {
+	filter  books.CatalogFilter
+	version int64
+	limit   int
+	reviews int64 // ReviewCount del libro que entrega GetBookByID
+	export  error         // error de ExportBooks antes de entregar filas
+	delay   time.Duration // espera de ExportBooks antes de cada fila
+}
+
+const fakeVersion = 3
+
+func fakeBook(id int64) *books.BookWithInventory {
+	return &books.BookWithInventory{
+		Book: &books.Book{
+			ID: id, BookName: "Rayuela", BookCategory: "Novela", TransactionType: books.ModeSale,
+			Price: 1000, EffectivePrice: 900, RentalPeriodDays: 14, Status: true, Version: fakeVersion,
+		},
+		AvailableQuantity: 5,
+	}
+}
+
+func lookup(id int64) (*books.BookWithInventory, error) {
+	if id != 1 && id != 2 {
+		return nil, books.ErrBookNotFound
+	}
+	return fakeBook(id), nil
+}
+
+func (f *fakeService) ListBook(ctx context.Context, filter books.CatalogFilter) ([]*books.BookWithInventory, error) {
+	f.filter = filter
+	return []*books.BookWithInventory{fakeBook(1)}, nil
+}
+
+func (f *fakeService) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (*books.BookWithInventory, error) {
+	b, err := lookup(id)
+	if err == nil {
+		b.Book.ReviewCount = f.reviews
+	}
+	return b, err
+}
+
+func (f *fakeService) CreateBook(ctx context.Context, input books.CreateBookInput) (int64, error) {
+	if input.Price < 0 {
+		return 0, books.ErrInvalidBook
+	}
+	return 1, nil
+}
+
+func (f *fakeService) UpdateBook(ctx context.Context, id, version int64, input books.UpdateBookInput) (*books.BookWithInventory, error) {
+	f.version = version
+	bwi, err := lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != fakeVersion {
+		return nil, books.ErrVersionMismatch
+	}
+	if input.BookName != nil {
+		bwi.Book.BookName = *input.BookName
+	}
+	bwi.Book.Version++
+	return bwi, nil
+}
+
+func (f *fakeService) ExportBooks(ctx context.Context, filter books.CatalogFilter, fn func(*books.BookWithInventory) error) error {
+	f.filter = filter
+	if f.export != nil {
+		return f.export
+	}
+	for _, id := range []int64{1, 2} {
+		time.Sleep(f.delay)
+		if err := fn(fakeBook(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeService) ArchiveBook(ctx context.Context, id int64) (*books.BookWithInventory, error) {
+	if id == 2 {
+		return nil, books.ErrBookOnLoan
+	}
+	bwi, err := lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	bwi.Book.ArchivedAt = &now
+	return bwi, nil
+}
+
+func (f *fakeService) RestoreBook(ctx context.Context, id int64) (*books.BookWithInventory, error) {
+	return lookup(id)
+}
+
+func (f *fakeService) DeleteBook(ctx context.Context, id int64) error {
+	if id == 2 {
+		return books.ErrBookHasHistory
+	}
+	_, err := lookup(id)
+	return err
+}
+
+func (f *fakeService) ListPopular(ctx context.Context, window books.PopularityWindow, category string, limit int) ([]*books.BookWithInventory, error) {
+	f.limit = limit
+	return []*books.BookWithInventory{fakeBook(1)}, nil
+}
+
+func (f *fakeService) RecomputePopularity(ctx context.Context) error { return nil }
+
+func (f *fakeService) ListRelated(ctx context.Context, bookID int64, limit int) ([]*books.BookWithInventory, error) {
+	f.limit = limit
+	if _, err := lookup(bookID); err != nil {
+		return nil, err
+	}
+	return []*books.BookWithInventory{fakeBook(2)}, nil
+}
+
+func (f *fakeService) ListRecommendations(ctx context.Context, userID int64, limit int) ([]*books.BookWithInventory, error) {
+	f.limit = limit
+	return []*books.BookWithInventory{fakeBook(2)}, nil
+}
+
+func (f *fakeService) ListPriceHistory(ctx context.Context, bookID int64) ([]*books.PriceChange, error) {
+	if _, err := lookup(bookID); err != nil {
+		return nil, err
+	}
+	return []*books.PriceChange{{ID: 7, BookID: bookID, NewPrice: 1000}}, nil
+}
+
+func (f *fakeService) CreatePromotion(ctx context.Context, input books.CreatePromotionInput) (*books.Promotion, error) {
+	if input.DiscountType != books.DiscountPercentage && input.DiscountType != books.DiscountFixed {
+		return nil, books.ErrInvalidPromotion
+	}
+	return &books.Promotion{ID: 4, BookID: input.BookID, DiscountType: input.DiscountType, DiscountValue: input.DiscountValue,
+		StartsAt: input.StartsAt, EndsAt: input.EndsAt}, nil
+}
+
+func (f *fakeService) ListPromotions(ctx context.Context, onlyActive bool) ([]*books.Promotion, error) {
+	return []*books.Promotion{{ID: 4, DiscountType: books.DiscountFixed, DiscountValue: 100}}, nil
+}
+
+func (f *fakeService) DeletePromotion(ctx context.Context, id int64) error {
+	if id != 4 {
+		return books.ErrPromotionNotFound
+	}
+	return nil
+}
+
+func (f *fakeService) AdjustInventory(ctx context.Context, bookID, userID int64, input books.AdjustInventoryInput) (*books.InventoryAdjustment, error) {
+	if _, err := lookup(bookID); err != nil {
+		return nil, err
+	}
+	if 5+input.Delta < 0 {
+		return nil, books.ErrInsufficientStock
+	}
+	return &books.InventoryAdjustment{ID: 9, BookID: bookID, UserID: &userID, Delta: input.Delta, Reason: input.Reason,
+		QuantityAfter: 5 + input.Delta}, nil
+}
+
+func (f *fakeService) ListInventoryAdjustments(ctx context.Context, bookID int64) ([]*books.InventoryAdjustment, error) {
+	return []*books.InventoryAdjustment{{ID: 9, BookID: bookID, Delta: 2, Reason: books.ReasonRestock, QuantityAfter: 5}}, nil
+}
+
+func (f *fakeService) CheckLowStock(ctx context.Context) error { return nil }
+
+func (f *fakeService) ListStockAlerts(ctx context.Context, onlyOpen bool) ([]*books.StockAlert, error) {
+	return []*books.StockAlert{{ID: 3, BookID: 1, BookName: "Rayuela", SuggestedQuantity: 4}}, nil
+}
+
+func newRouter(svc books.Service) *gin.Engine {
+	return httpxtest.Router(books.NewHandler(svc, 0).RegisterRoutes)
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	router := newRouter(&fakeService{})
+	user := map[string]string{"X-User-ID": "5"}
+	const promotion = `{"book_id":1,"discount_type":"porcentaje","discount_value":10,` +
+		`"starts_at":"2026-01-01T00:00:00Z","ends_at":"2026-02-01T00:00:00Z"}`
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "ListBooks", Method: "GET", Path: "/books", Status: 200, Want: []string{`"books":[`, `"book_name":"Rayuela"`, `"effective_price":900`}},
+		{Name: "ListBooksBadStatus", Method: "GET", Path: "/books?status=quizas", Status: 400, Code: "invalid_parameter"},
+		{Name: "ListBooksBadMode", Method: "GET", Path: "/books?mode=trueque", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "ExportCSV", Method: "GET", Path: "/books/export", Status: 200, Want: []string{"id,book_name,book_category", "1,Rayuela,Novela,venta,1000,900"}},
+		{Name: "ExportJSON", Method: "GET", Path: "/books/export?format=json", Status: 200, Want: []string{`[{"id":1`, `,{"id":2`, "]\n"}},
+		{Name: "ExportNDJSON", Method: "GET", Path: "/books/export?format=ndjson", Status: 200, Want: []string{"{\"id\":1", "\n{\"id\":2"}},
+		{Name: "ExportBadFormat", Method: "GET", Path: "/books/export?format=xml", Status: 400, Code: "invalid_parameter"},
+		{Name: "ExportBadMode", Method: "GET", Path: "/books/export?mode=trueque", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "Popular", Method: "GET", Path: "/books/popular?window=7d", Status: 200, Want: []string{`"window":"7d"`, `"books":[`}},
+		{Name: "PopularBadWindow", Method: "GET", Path: "/books/popular?window=1y", Status: 400, Code: "invalid_parameter"},
+		{Name: "PopularLimitZero", Method: "GET", Path: "/books/popular?limit=0", Status: 400, Code: "invalid_parameter"},
+		{Name: "PopularLimitTooBig", Method: "GET", Path: "/books/popular?limit=101", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "GetBook", Method: "GET", Path: "/books/1", Status: 200, Want: []string{`"id":1`, `"sale_price":1000`, `"available_quantity":5`}},
+		{Name: "GetBookNotNumeric", Method: "GET", Path: "/books/abc", Status: 400, Code: "invalid_parameter"},
+		{Name: "GetBookZero", Method: "GET", Path: "/books/0", Status: 400, Code: "invalid_parameter"},
+		{Name: "GetBookMissing", Method: "GET", Path: "/books/404", Status: 404, Code: "book_not_found"},
+
+		{Name: "CreateBook", Method: "POST", Path: "/books", Body: `{"book_name":"Rayuela","book_category":"Novela","transaction_type":"venta","price":1000}`,
+			Status: 201, Want: []string{`"id":1`}},
+		{Name: "CreateBookMissingFields", Method: "POST", Path: "/books", Body: `{"book_name":"Rayuela"}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"book_category"`, `"field":"price"`}},
+		{Name: "CreateBookWrongType", Method: "POST", Path: "/books", Body: `{"book_name":"R","book_category":"N","transaction_type":"venta","price":"mil"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"price"`}},
+		{Name: "CreateBookMalformed", Method: "POST", Path: "/books", Body: `{"book_name":`, Status: 400, Code: "invalid_body"},
+		{Name: "CreateBookNoBody", Method: "POST", Path: "/books", Status: 400, Code: "invalid_body"},
+		{Name: "CreateBookRejected", Method: "POST", Path: "/books", Body: `{"book_name":"R","book_category":"N","transaction_type":"venta","price":-1}`,
+			Status: 400, Code: "invalid_book"},
+
+		{Name: "UpdateBook", Method: "PATCH", Path: "/books/1", Body: `{"book_name":"Otro"}`, Header: map[string]string{"If-Match": `"3"`},
+			Status: 200, Want: []string{`"book_name":"Otro"`}},
+		{Name: "UpdateBookAnyVersion", Method: "PATCH", Path: "/books/1", Body: `{}`, Header: map[string]string{"If-Match": "*"}, Status: 200},
+		{Name: "UpdateBookNoIfMatch", Method: "PATCH", Path: "/books/1", Body: `{}`, Status: 428, Code: "if_match_required"},
+		{Name: "UpdateBookWeakETag", Method: "PATCH", Path: "/books/1", Body: `{}`, Header: map[string]string{"If-Match": `W/"3"`}, Status: 412, Code: "version_mismatch"},
+		{Name: "UpdateBookStale", Method: "PATCH", Path: "/books/1", Body: `{}`, Header: map[string]string{"If-Match": `"2"`}, Status: 412, Code: "version_mismatch"},
+		{Name: "UpdateBookMissing", Method: "PATCH", Path: "/books/404", Body: `{}`, Header: map[string]string{"If-Match": "*"}, Status: 404, Code: "book_not_found"},
+		{Name: "UpdateBookWrongType", Method: "PATCH", Path: "/books/1", Body: `{"stock":"x"}`, Header: map[string]string{"If-Match": "*"},
+			Status: 400, Code: "validation_failed"},
+
+		{Name: "DeleteBook", Method: "DELETE", Path: "/books/1", Status: 204},
+		{Name: "DeleteBookWithHistory", Method: "DELETE", Path: "/books/2", Status: 409, Code: "book_has_history"},
+		{Name: "DeleteBookMissing", Method: "DELETE", Path: "/books/404", Status: 404, Code: "book_not_found"},
+
+		{Name: "Archive", Method: "POST", Path: "/books/1/archive", Status: 200, Want: []string{`"archived":true`}},
+		{Name: "ArchiveOnLoan", Method: "POST", Path: "/books/2/archive", Status: 409, Code: "book_on_loan"},
+		{Name: "Restore", Method: "POST", Path: "/books/1/restore", Status: 200, Want: []string{`"archived":false`}},
+		{Name: "RestoreMissing", Method: "POST", Path: "/books/404/restore", Status: 404, Code: "book_not_found"},
+
+		{Name: "Related", Method: "GET", Path: "/books/1/related", Status: 200, Want: []string{`"id":2`}},
+		{Name: "RelatedBadLimit", Method: "GET", Path: "/books/1/related?limit=x", Status: 400, Code: "invalid_parameter"},
+		{Name: "RelatedMissing", Method: "GET", Path: "/books/404/related", Status: 404, Code: "book_not_found"},
+		{Name: "Recommendations", Method: "GET", Path: "/users/1/recommendations?limit=5", Status: 200, Want: []string{`"books":[`}},
+		{Name: "RecommendationsBadUser", Method: "GET", Path: "/users/x/recommendations", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "PriceHistory", Method: "GET", Path: "/books/1/price-history", Status: 200, Want: []string{`"book_id":1`, `"new_price":1000`, `"old_price":null`}},
+		{Name: "PriceHistoryMissing", Method: "GET", Path: "/books/404/price-history", Status: 404, Code: "book_not_found"},
+
+		{Name: "ListPromotions", Method: "GET", Path: "/promotions?active=true", Status: 200, Want: []string{`"promotions":[`, `"discount_type":"monto"`}},
+		{Name: "ListPromotionsBadActive", Method: "GET", Path: "/promotions?active=quizas", Status: 400, Code: "invalid_parameter"},
+		{Name: "CreatePromotion", Method: "POST", Path: "/promotions", Body: promotion, Status: 201, Want: []string{`"id":4`, `"book_id":1`}},
+		{Name: "CreatePromotionMissingDates", Method: "POST", Path: "/promotions", Body: `{"discount_type":"monto","discount_value":10}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"starts_at"`}},
+		{Name: "CreatePromotionRejected", Method: "POST", Path: "/promotions",
+			Body:   `{"discount_type":"regalo","discount_value":10,"starts_at":"2026-01-01T00:00:00Z","ends_at":"2026-02-01T00:00:00Z"}`,
+			Status: 400, Code: "invalid_promotion"},
+		{Name: "DeletePromotion", Method: "DELETE", Path: "/promotions/4", Status: 204},
+		{Name: "DeletePromotionMissing", Method: "DELETE", Path: "/promotions/9", Status: 404, Code: "promotion_not_found"},
+
+		{Name: "Adjust", Method: "POST", Path: "/books/1/inventory/adjustments", Body: `{"delta":2,"reason":"reposicion"}`, Header: user,
+			Status: 201, Want: []string{`"quantity_after":7`, `"user_id":5`}},
+		{Name: "AdjustNoUser", Method: "POST", Path: "/books/1/inventory/adjustments", Body: `{"delta":2,"reason":"reposicion"}`,
+			Status: 401, Code: "missing_user"},
+		{Name: "AdjustBadUser", Method: "POST", Path: "/books/1/inventory/adjustments", Body: `{"delta":2,"reason":"reposicion"}`,
+			Header: map[string]string{"X-User-ID": "ana"}, Status: 401, Code: "missing_user"},
+		{Name: "AdjustZeroDelta", Method: "POST", Path: "/books/1/inventory/adjustments", Body: `{"delta":0,"reason":"correccion"}`, Header: user,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"delta"`}},
+		{Name: "AdjustBelowZero", Method: "POST", Path: "/books/1/inventory/adjustments", Body: `{"delta":-6,"reason":"perdida"}`, Header: user,
+			Status: 409, Code: "insufficient_stock"},
+		{Name: "AdjustMissingBook", Method: "POST", Path: "/books/404/inventory/adjustments", Body: `{"delta":1,"reason":"reposicion"}`, Header: user,
+			Status: 404, Code: "book_not_found"},
+		{Name: "ListAdjustments", Method: "GET", Path: "/books/1/inventory/adjustments", Status: 200, Want: []string{`"adjustments":[`, `"reason":"reposicion"`}},
+
+		{Name: "Alerts", Method: "GET", Path: "/inventory/alerts", Status: 200, Want: []string{`"alerts":[`, `"suggested_quantity":4`}},
+		{Name: "AlertsAll", Method: "GET", Path: "/inventory/alerts?status=all", Status: 200},
+		{Name: "AlertsBadStatus", Method: "GET", Path: "/inventory/alerts?status=cerradas", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "UnknownRoute", Method: "GET", Path: "/libros", Status: 404, Code: "route_not_found"},
+	})
+}
+
+// Si el servicio falla antes de la primera fila, la exportación responde un
+// problem+json sin los encabezados del archivo
+func TestHandlerExportError(t *testing.T) {
+	for _, format := range []string{"csv", "json", "ndjson"} {
+		t.Run(format, func(t *testing.T) {
+			router := newRouter(&fakeService{export: books.ErrBookNotFound})
+			w := httpxtest.Do(router, "GET", "/books/export?format="+format, "", nil)
+			httpxtest.Check(t, w, http.StatusNotFound, "book_not_found")
+			if cd := w.Header().Get("Content-Disposition"); cd != "" {
+				t.Errorf("Content-Disposition = %q en una respuesta de error", cd)
+			}
+		})
+	}
+}
+
+// La exportación tiene su propio plazo de escritura: termina aunque tarde
+// más que el WriteTimeout del servidor
+func TestHandlerExportOutlivesWriteTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	books.NewHandler(&fakeService{delay: 150 * time.Millisecond}, time.Minute).RegisterRoutes(router.Group("/api/v1"))
+	srv := httptest.NewUnstartedServer(router)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/books/export?format=ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("leyendo la exportación: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.Count(string(body), "\n") != 2 {
+		t.Fatalf("exportación = %d, %q; quiero las dos filas", resp.StatusCode, body)
+	}
+}
+
+func TestHandlerParsesQuery(t *testing.T) {
+	tests := []struct {
+		path   string
+		filter books.CatalogFilter
+		limit  int
+	}{
+		{path: "/books", filter: books.CatalogFilter{}},
+		{path: "/books?status=true&mode=Arriendo", filter: books.CatalogFilter{OnlyAvailable: true, Mode: books.ModeRental}},
+		{path: "/books/export?format=ndjson&mode=venta", filter: books.CatalogFilter{Mode: books.ModeSale}},
+		{path: "/books/popular", limit: 10},
+		{path: "/books/1/related?limit=100", limit: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			svc := &fakeService{}
+			w := httpxtest.Do(newRouter(svc), "GET", tt.path, "", nil)
+			httpxtest.Check(t, w, http.StatusOK, "")
+			if svc.filter != tt.filter || svc.limit != tt.limit {
+				t.Fatalf("el servicio recibió filtro %+v y límite %d, quiero %+v y %d", svc.filter, svc.limit, tt.filter, tt.limit)
+			}
+		})
+	}
+}
+
+func TestHandlerETags(t *testing.T) {
+	svc := &fakeService{}
+	router := newRouter(svc)
+
+	w := httpxtest.Do(router, "GET", "/books/1", "", nil)
+	httpxtest.Check(t, w, http.StatusOK, "")
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"3-`) || !strings.HasSuffix(etag, `"`) {
+		t.Fatalf("ETag = %q, quiero la versión 3 seguida del hash", etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"Same", etag, http.StatusNotModified},
+		{"Weak", "W/" + etag, http.StatusNotModified},
+		{"AnyOf", `"1-00", ` + etag, http.StatusNotModified},
+		{"Star", "*", http.StatusNotModified},
+		{"VersionOnly", `"3"`, http.StatusOK},
+		{"Other", `"2-00"`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httpxtest.Do(router, "GET", "/books/1", "", map[string]string{"If-None-Match": tt.ifNoneMatch})
+			httpxtest.Check(t, w, tt.status, "")
+		})
+	}
+
+	// Una reseña nueva cambia la respuesta sin subir la versión: el ETag
+	// cambia y el If-None-Match anterior ya no responde 304
+	svc.reviews = 1
+	w = httpxtest.Do(router, "GET", "/books/1", "", map[string]string{"If-None-Match": etag})
+	httpxtest.Check(t, w, http.StatusOK, "")
+	if !strings.Contains(w.Body.String(), `"review_count":1`) {
+		t.Fatalf("cuerpo sin la reseña nueva: %s", w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got == etag || !strings.HasPrefix(got, `"3-`) {
+		t.Fatalf("ETag tras la reseña = %q, quiero otro hash con la versión 3 (antes %q)", got, etag)
+	}
+
+	// If-Match llega al servicio como versión, con o sin hash, y la respuesta
+	// trae el ETag nuevo
+	for _, ifMatch := range []string{etag, `"3"`} {
+		w = httpxtest.Do(router, "PATCH", "/books/1", `{}`, map[string]string{"If-Match": ifMatch})
+		httpxtest.Check(t, w, http.StatusOK, "")
+		if svc.version != 3 || !strings.HasPrefix(w.Header().Get("ETag"), `"4-`) {
+			t.Fatalf("If-Match %s: versión enviada %d y ETag %q, quiero 3 y la versión 4", ifMatch, svc.version, w.Header().Get("ETag"))
+		}
+	}
+}
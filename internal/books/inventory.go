@@ -0,0 +1,107 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrInsufficientStock = apperr.Conflict("insufficient_stock", "el ajuste dejaría el inventario bajo cero")
+	ErrInvalidAdjustment = apperr.Validation("invalid_adjustment", "ajuste de inventario inválido")
+)
+
+// Motivos de un ajuste de inventario
+const (
+	ReasonRestock    = "reposicion" // entrada de ejemplares, delta > 0
+	ReasonDamage     = "deterioro"  // ejemplares dañados dados de baja, delta < 0
+	ReasonLoss       = "perdida"    // ejemplares extraviados, delta < 0
+	ReasonCorrection = "correccion" // corrección de conteo, cualquier signo
+)
+
+// InventoryAdjustment registra quién cambió el stock de un libro, en cuánto y por qué.
+// UserID es nil en los cambios hechos con PATCH /books/:id.
+type InventoryAdjustment struct {
+	ID            int64
+	BookID        int64
+	UserID        *int64
+	Delta         int64
+	Reason        string
+	Note          string
+	QuantityAfter int64
+	CreatedAt     time.Time
+	Mode          string // en libros "ambos", "arriendo" mueve también el stock de arriendo; no se guarda
+}
+
+type AdjustInventoryInput struct {
+	Delta  int64  `json:"delta" binding:"required"`
+	Reason string `json:"reason" binding:"required"` // "reposicion" | "deterioro" | "perdida" | "correccion"
+	Note   string `json:"note"`
+	Mode   string `json:"mode"` // "venta" (por defecto) | "arriendo": stock de un libro "ambos" que se ajusta
+}
+
+func validateAdjustment(delta int64, reason string) error {
+	switch reason {
+	case ReasonRestock:
+		if delta <= 0 {
+			return fmt.Errorf("%w: una reposición debe sumar ejemplares", ErrInvalidAdjustment)
+		}
+	case ReasonDamage, ReasonLoss:
+		if delta >= 0 {
+			return fmt.Errorf("%w: %s debe restar ejemplares", ErrInvalidAdjustment, reason)
+		}
+	case ReasonCorrection:
+		if delta == 0 {
+			return fmt.Errorf("%w: delta no puede ser 0", ErrInvalidAdjustment)
+		}
+	default:
+		return fmt.Errorf("%w: motivo debe ser reposicion, deterioro, perdida o correccion", ErrInvalidAdjustment)
+	}
+	return nil
+}
+
+func (s *service) AdjustInventory(ctx context.Context, bookID, userID int64, input AdjustInventoryInput) (*InventoryAdjustment, error) {
+	reason := strings.ToLower(strings.TrimSpace(input.Reason))
+	if err := validateAdjustment(input.Delta, reason); err != nil {
+		return nil, err
+	}
+	mode := normMode(input.Mode)
+	if mode != "" && mode != ModeSale && mode != ModeRental {
+		return nil, fmt.Errorf("%w: mode debe ser venta o arriendo", ErrInvalidAdjustment)
+	}
+	adj := &InventoryAdjustment{
+		BookID:    bookID,
+		UserID:    &userID,
+		Delta:     input.Delta,
+		Reason:    reason,
+		Note:      strings.TrimSpace(input.Note),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Mode:      mode,
+	}
+	if err := s.repo.AdjustInventory(ctx, adj); err != nil {
+		return nil, err
+	}
+	return adj, nil
+}
+
+func (s *service) ListInventoryAdjustments(ctx context.Context, bookID int64) ([]*InventoryAdjustment, error) {
+	book, err := s.repo.GetBookByID(ctx, bookID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if book.Book == nil {
+		return nil, ErrBookNotFound
+	}
+	adjs, err := s.repo.ListInventoryAdjustments(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*InventoryAdjustment, len(adjs))
+	for i := range adjs {
+		out[i] = &adjs[i]
+	}
+	return out, nil
+}
@@ -0,0 +1,768 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/wishlist"
+)
+
+// memoryRepository guarda los libros en un memstore.Store. Replica las
+// consultas de sqlRepository sobre las tablas en memoria, incluidas las
+// ventas y préstamos que escriben copies y los avisos de wishlist.
+type memoryRepository struct{ store *memstore.Store }
+
+func NewMemoryRepository(store *memstore.Store) Repository { return &memoryRepository{store: store} }
+
+// bookView arma la misma fila que bookSelect: libro, reseñas e inventario
+type bookView struct {
+	t       *memstore.Tables
+	ratings map[int64][2]float64 // book_id -> suma de estrellas, cantidad
+}
+
+func newBookView(t *memstore.Tables) bookView {
+	ratings := map[int64][2]float64{}
+	for _, rv := range t.Reviews {
+		r := ratings[rv.BookID]
+		ratings[rv.BookID] = [2]float64{r[0] + float64(rv.Rating), r[1] + 1}
+	}
+	return bookView{t: t, ratings: ratings}
+}
+
+// quantities devuelve el stock total y el de arriendo, como rentalQty
+func (v bookView) quantities(b memstore.Book) (qty, rental int64) {
+	inv := v.t.Inventory[b.ID]
+	switch b.TransactionType {
+	case ModeRental:
+		return inv.Available, inv.Available
+	case ModeBoth:
+		return inv.Available, min(inv.Rental, inv.Available)
+	}
+	return inv.Available, 0
+}
+
+func (v bookView) book(b memstore.Book) BookWithInventory {
+	out := &Book{
+		ID:               b.ID,
+		BookName:         b.Name,
+		BookCategory:     b.Category,
+		TransactionType:  b.TransactionType,
+		Price:            b.Price,
+		RentalPrice:      clonePtr(b.RentalPrice),
+		RentalPeriodDays: b.RentalPeriodDays,
+		Status:           b.Status,
+		PopularityScore:  b.PopularityScore,
+		ArchivedAt:       clonePtr(b.ArchivedAt),
+		ReorderThreshold: b.ReorderThreshold,
+		CoverUpdatedAt:   clonePtr(b.CoverUpdatedAt),
+		Version:          b.Version,
+	}
+	if r, ok := v.ratings[b.ID]; ok {
+		out.RatingAverage = r[0] / r[1]
+		out.ReviewCount = int64(r[1])
+	}
+	qty, rental := v.quantities(b)
+	return BookWithInventory{Book: out, AvailableQuantity: qty, RentalQuantity: rental}
+}
+
+// sortedBooks devuelve los libros ordenados por id
+func sortedBooks(t *memstore.Tables) []memstore.Book {
+	out := make([]memstore.Book, 0, len(t.Books))
+	for _, b := range t.Books {
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func (r *memoryRepository) ListBook(ctx context.Context, filter CatalogFilter) ([]BookWithInventory, error) {
+	var out []BookWithInventory
+	err := r.StreamBooks(ctx, filter, func(bwi BookWithInventory) error {
+		out = append(out, bwi)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StreamBooks arma el catálogo bajo el candado y llama a fn después de
+// soltarlo, para que una exportación lenta no frene las escrituras
+func (r *memoryRepository) StreamBooks(ctx context.Context, filter CatalogFilter, fn func(BookWithInventory) error) error {
+	var list []BookWithInventory
+	err := r.store.View(func(t *memstore.Tables) error {
+		v := newBookView(t)
+		for _, b := range sortedBooks(t) {
+			if b.ArchivedAt != nil {
+				continue
+			}
+			if filter.Mode != "" && b.TransactionType != filter.Mode && b.TransactionType != ModeBoth {
+				continue
+			}
+			bwi := v.book(b)
+			if filter.OnlyAvailable {
+				var stock int64
+				switch filter.Mode {
+				case ModeRental:
+					stock = bwi.RentalQuantity
+				case ModeSale:
+					stock = bwi.AvailableQuantity - bwi.RentalQuantity
+				default:
+					stock = bwi.AvailableQuantity
+				}
+				if stock <= 0 {
+					continue
+				}
+			}
+			list = append(list, bwi)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, bwi := range list {
+		if err := fn(bwi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (out BookWithInventory, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		b, ok := t.Books[id]
+		if !ok {
+			return nil
+		}
+		bwi := newBookView(t).book(b)
+		if onlyAvailable != nil && *onlyAvailable && bwi.AvailableQuantity <= 0 {
+			return nil
+		}
+		out = bwi
+		return nil
+	})
+	return out, err
+}
+
+func (r *memoryRepository) CreateBook(ctx context.Context, b *Book, initialStock, rentalStock int64) (int64, error) {
+	var bookID int64
+	err := r.store.Update(func(t *memstore.Tables) error {
+		bookID = t.NextID("Libro")
+		t.Books[bookID] = memstore.Book{
+			ID:               bookID,
+			Name:             strings.TrimSpace(b.BookName),
+			Category:         strings.TrimSpace(b.BookCategory),
+			TransactionType:  strings.ToLower(strings.TrimSpace(b.TransactionType)),
+			Price:            b.Price,
+			RentalPrice:      clonePtr(b.RentalPrice),
+			RentalPeriodDays: b.RentalPeriodDays,
+			Status:           b.Status,
+			ReorderThreshold: b.ReorderThreshold,
+			Version:          1,
+		}
+		memRecordPriceChange(t, bookID, nil, b.Price)
+		t.Inventory[bookID] = memstore.Inventory{BookID: bookID, Available: initialStock, Rental: rentalStock}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return bookID, nil
+}
+
+// UpdateBook sigue a sqlRepository.UpdateBook: guarda solo si el libro sigue
+// en b.Version y deja en b la versión nueva
+func (r *memoryRepository) UpdateBook(ctx context.Context, b *Book, stock, rentalStock *int64) error {
+	err := r.store.Update(func(t *memstore.Tables) error {
+		row, ok := t.Books[b.ID]
+		if !ok {
+			return ErrBookNotFound
+		}
+		if row.Version != b.Version {
+			return ErrVersionMismatch
+		}
+		if row.Price != b.Price {
+			old := row.Price
+			memRecordPriceChange(t, b.ID, &old, b.Price)
+		}
+		row.Name = strings.TrimSpace(b.BookName)
+		row.Category = strings.TrimSpace(b.BookCategory)
+		row.TransactionType = strings.ToLower(strings.TrimSpace(b.TransactionType))
+		row.Price = b.Price
+		row.RentalPrice = clonePtr(b.RentalPrice)
+		row.RentalPeriodDays = b.RentalPeriodDays
+		row.Status = b.Status
+		row.ReorderThreshold = b.ReorderThreshold
+		row.Version++
+		t.Books[b.ID] = row
+
+		if stock != nil {
+			if err := memCheckManualStock(t, b.ID); err != nil {
+				return err
+			}
+			before := t.Inventory[b.ID].Available
+			if inv, ok := t.Inventory[b.ID]; ok {
+				inv.Available = *stock
+				t.Inventory[b.ID] = inv
+			}
+			memSyncBookStatus(t, b.ID)
+			if before <= 0 && *stock > 0 {
+				wishlist.NotifyBackInStockInMemory(t, b.ID)
+			}
+			if delta := *stock - before; delta != 0 {
+				memInsertAdjustment(t, &InventoryAdjustment{
+					BookID:        b.ID,
+					Delta:         delta,
+					Reason:        ReasonCorrection,
+					Note:          "PATCH /books/:id",
+					QuantityAfter: *stock,
+					CreatedAt:     time.Now().UTC().Truncate(time.Second),
+				})
+			}
+		}
+
+		if rentalStock != nil {
+			inv, ok := t.Inventory[b.ID]
+			if !ok || *rentalStock > inv.Available {
+				return invalidBook("rental_stock", "el stock de arriendo no puede superar el stock disponible")
+			}
+			inv.Rental = *rentalStock
+			t.Inventory[b.ID] = inv
+		} else if stock != nil {
+			memClampRentalStock(t, b.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.Version++
+	return nil
+}
+
+func memClampRentalStock(t *memstore.Tables, bookID int64) {
+	inv, ok := t.Inventory[bookID]
+	if !ok {
+		return
+	}
+	switch {
+	case inv.Available < 0:
+		inv.Rental = 0
+	case inv.Rental > inv.Available:
+		inv.Rental = inv.Available
+	}
+	t.Inventory[bookID] = inv
+}
+
+func memBumpVersion(t *memstore.Tables, bookID int64) {
+	if b, ok := t.Books[bookID]; ok {
+		b.Version++
+		t.Books[bookID] = b
+	}
+}
+
+func memSyncBookStatus(t *memstore.Tables, bookID int64) {
+	if b, ok := t.Books[bookID]; ok {
+		b.Status = t.Inventory[bookID].Available > 0
+		t.Books[bookID] = b
+	}
+}
+
+func memCheckManualStock(t *memstore.Tables, bookID int64) error {
+	for _, c := range t.Copies {
+		if c.BookID == bookID {
+			return ErrStockFromCopies
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) ArchiveBook(ctx context.Context, id int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		b, ok := t.Books[id]
+		if !ok {
+			return ErrBookNotFound
+		}
+		for _, l := range t.Loans {
+			if l.BookID == id && l.Status == memstore.LoanPending {
+				return ErrBookOnLoan
+			}
+		}
+		if b.ArchivedAt == nil {
+			now := time.Now().UTC().Truncate(time.Second)
+			b.ArchivedAt = &now
+		}
+		b.Version++
+		t.Books[id] = b
+		return nil
+	})
+}
+
+func (r *memoryRepository) RestoreBook(ctx context.Context, id int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		b, ok := t.Books[id]
+		if !ok {
+			return ErrBookNotFound
+		}
+		b.ArchivedAt = nil
+		b.Version++
+		t.Books[id] = b
+		return nil
+	})
+}
+
+func (r *memoryRepository) DeleteBook(ctx context.Context, id int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if _, ok := t.Books[id]; !ok {
+			return ErrBookNotFound
+		}
+		for _, s := range t.Sales {
+			if s.BookID == id {
+				return ErrBookHasHistory
+			}
+		}
+		for _, l := range t.Loans {
+			if l.BookID == id {
+				return ErrBookHasHistory
+			}
+		}
+
+		delete(t.Inventory, id)
+		deleteWhere(t.PriceChanges, func(pc memstore.PriceChange) bool { return pc.BookID == id })
+		deleteWhere(t.Adjustments, func(a memstore.Adjustment) bool { return a.BookID == id })
+		deleteWhere(t.StockAlerts, func(a memstore.StockAlert) bool { return a.BookID == id })
+		deleteWhere(t.Copies, func(c memstore.Copy) bool { return c.BookID == id })
+		deleteWhere(t.Notifications, func(n memstore.Notification) bool { return n.BookID == id })
+		deleteWhere(t.Promotions, func(p memstore.Promotion) bool { return p.BookID != nil && *p.BookID == id })
+		for k := range t.Wishlist {
+			if k.BookID == id {
+				delete(t.Wishlist, k)
+			}
+		}
+		delete(t.Books, id)
+		return nil
+	})
+}
+
+func deleteWhere[K comparable, V any](m map[K]V, match func(V) bool) {
+	for k, v := range m {
+		if match(v) {
+			delete(m, k)
+		}
+	}
+}
+
+func (r *memoryRepository) ListActivity(ctx context.Context, since time.Time) (out []Activity, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		for _, s := range t.Sales {
+			if since.IsZero() || !s.Date.Before(since) {
+				out = append(out, Activity{BookID: s.BookID, Kind: "venta", At: s.Date})
+			}
+		}
+		for _, l := range t.Loans {
+			if since.IsZero() || !l.StartDate.Before(since) {
+				out = append(out, Activity{BookID: l.BookID, Kind: "prestamo", At: l.StartDate})
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *memoryRepository) ListPopular(ctx context.Context, scores map[int64]int64, category string, limit int) (out []BookWithInventory, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		var list []memstore.Book
+		for _, b := range t.Books {
+			if b.ArchivedAt == nil && (category == "" || strings.EqualFold(b.Category, category)) {
+				list = append(list, b)
+			}
+		}
+		score := func(b memstore.Book) int64 {
+			if scores != nil {
+				return scores[b.ID]
+			}
+			return b.PopularityScore
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if si, sj := score(list[i]), score(list[j]); si != sj {
+				return si > sj
+			}
+			return list[i].ID < list[j].ID
+		})
+		if limit > 0 && len(list) > limit {
+			list = list[:limit]
+		}
+		v := newBookView(t)
+		for _, b := range list {
+			bwi := v.book(b)
+			bwi.Book.PopularityScore = score(b)
+			out = append(out, bwi)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *memoryRepository) UpdatePopularityScores(ctx context.Context, scores map[int64]int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		for id, b := range t.Books {
+			b.PopularityScore = scores[id]
+			t.Books[id] = b
+		}
+		return nil
+	})
+}
+
+// activity es el equivalente de activityCTE: pares usuario-libro sin repetir
+func activity(t *memstore.Tables) map[memstore.WishlistKey]bool {
+	out := map[memstore.WishlistKey]bool{}
+	for _, s := range t.Sales {
+		out[memstore.WishlistKey{UserID: s.UserID, BookID: s.BookID}] = true
+	}
+	for _, l := range t.Loans {
+		out[memstore.WishlistKey{UserID: l.UserID, BookID: l.BookID}] = true
+	}
+	return out
+}
+
+// rankBooks ordena los candidatos por peso, popularidad e id y aplica limit
+func rankBooks(t *memstore.Tables, weight map[int64]int64, include func(memstore.Book) bool, limit int) []BookWithInventory {
+	var candidates []memstore.Book
+	for _, b := range t.Books {
+		if b.ArchivedAt == nil && include(b) {
+			candidates = append(candidates, b)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if weight[a.ID] != weight[b.ID] {
+			return weight[a.ID] > weight[b.ID]
+		}
+		if a.PopularityScore != b.PopularityScore {
+			return a.PopularityScore > b.PopularityScore
+		}
+		return a.ID < b.ID
+	})
+	if limit >= 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	v := newBookView(t)
+	var out []BookWithInventory
+	for _, b := range candidates {
+		out = append(out, v.book(b))
+	}
+	return out
+}
+
+func (r *memoryRepository) ListRelated(ctx context.Context, bookID int64, limit int) (out []BookWithInventory, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		pairs := activity(t)
+		readers := map[int64]bool{}
+		for p := range pairs {
+			if p.BookID == bookID {
+				readers[p.UserID] = true
+			}
+		}
+		users := map[int64]int64{}
+		for p := range pairs {
+			if p.BookID != bookID && readers[p.UserID] {
+				users[p.BookID]++
+			}
+		}
+		out = rankBooks(t, users, func(b memstore.Book) bool { return users[b.ID] > 0 }, limit)
+		return nil
+	})
+	return out, err
+}
+
+func (r *memoryRepository) ListRecommendations(ctx context.Context, userID int64, limit int) (out []BookWithInventory, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		seen := map[int64]bool{}
+		affinity := map[string]int64{}
+		for p := range activity(t) {
+			if p.UserID != userID {
+				continue
+			}
+			seen[p.BookID] = true
+			if b, ok := t.Books[p.BookID]; ok {
+				affinity[b.Category]++
+			}
+		}
+		weight := map[int64]int64{}
+		for _, b := range t.Books {
+			weight[b.ID] = affinity[b.Category]
+		}
+		out = rankBooks(t, weight, func(b memstore.Book) bool {
+			if seen[b.ID] {
+				return false
+			}
+			_, liked := affinity[b.Category]
+			return liked || len(affinity) == 0
+		}, limit)
+		return nil
+	})
+	return out, err
+}
+
+func memRecordPriceChange(t *memstore.Tables, bookID int64, oldPrice *int64, newPrice int64) {
+	id := t.NextID("HistorialPrecio")
+	t.PriceChanges[id] = memstore.PriceChange{
+		ID: id, BookID: bookID, OldPrice: oldPrice, NewPrice: newPrice,
+		ChangedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func (r *memoryRepository) ListPriceHistory(ctx context.Context, bookID int64) ([]PriceChange, error) {
+	out := []PriceChange{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, pc := range t.PriceChanges {
+			if pc.BookID == bookID {
+				out = append(out, PriceChange{ID: pc.ID, BookID: pc.BookID, OldPrice: clonePtr(pc.OldPrice), NewPrice: pc.NewPrice, ChangedAt: pc.ChangedAt})
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, err
+}
+
+func (r *memoryRepository) CreatePromotion(ctx context.Context, p *Promotion) (int64, error) {
+	var id int64
+	err := r.store.Update(func(t *memstore.Tables) error {
+		id = t.NextID("Promocion")
+		t.Promotions[id] = memstore.Promotion{
+			ID:            id,
+			BookID:        clonePtr(p.BookID),
+			BookCategory:  clonePtr(p.BookCategory),
+			DiscountType:  p.DiscountType,
+			DiscountValue: p.DiscountValue,
+			StartsAt:      memstore.Stamp(p.StartsAt.UTC()),
+			EndsAt:        memstore.Stamp(p.EndsAt.UTC()),
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *memoryRepository) ListPromotions(ctx context.Context, at time.Time, onlyActive bool) ([]Promotion, error) {
+	now := memstore.Stamp(at)
+	out := []Promotion{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, p := range t.Promotions {
+			if onlyActive && (p.StartsAt.After(now) || !p.EndsAt.After(now)) {
+				continue
+			}
+			out = append(out, Promotion{
+				ID:            p.ID,
+				BookID:        clonePtr(p.BookID),
+				BookCategory:  clonePtr(p.BookCategory),
+				DiscountType:  p.DiscountType,
+				DiscountValue: p.DiscountValue,
+				StartsAt:      p.StartsAt,
+				EndsAt:        p.EndsAt,
+			})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].StartsAt.Equal(out[j].StartsAt) {
+			return out[i].StartsAt.Before(out[j].StartsAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, err
+}
+
+func (r *memoryRepository) DeletePromotion(ctx context.Context, id int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if _, ok := t.Promotions[id]; !ok {
+			return ErrPromotionNotFound
+		}
+		delete(t.Promotions, id)
+		return nil
+	})
+}
+
+// AdjustInventory sigue a sqlRepository.AdjustInventory; como Update corre
+// de a una escritura, dos ajustes simultáneos tampoco se pisan aquí
+func (r *memoryRepository) AdjustInventory(ctx context.Context, adj *InventoryAdjustment) error {
+	result := *adj
+	err := r.store.Update(func(t *memstore.Tables) error {
+		b, ok := t.Books[adj.BookID]
+		if !ok {
+			return ErrBookNotFound
+		}
+		if err := memCheckManualStock(t, adj.BookID); err != nil {
+			return err
+		}
+
+		inv := t.Inventory[adj.BookID]
+		inv.BookID = adj.BookID
+		if inv.Available+adj.Delta < 0 {
+			return ErrInsufficientStock
+		}
+		inv.Available += adj.Delta
+
+		if adj.Mode == ModeRental {
+			if b.TransactionType == ModeSale {
+				return fmt.Errorf("%w: el libro no se arrienda", ErrInvalidAdjustment)
+			}
+			// En los libros solo de arriendo todo el stock ya es de arriendo
+			if b.TransactionType == ModeBoth {
+				if inv.Rental+adj.Delta < 0 {
+					return ErrInsufficientStock
+				}
+				inv.Rental += adj.Delta
+			}
+		}
+		t.Inventory[adj.BookID] = inv
+		memClampRentalStock(t, adj.BookID)
+
+		result.QuantityAfter = inv.Available
+		memSyncBookStatus(t, adj.BookID)
+		memBumpVersion(t, adj.BookID)
+		memInsertAdjustment(t, &result)
+		if result.QuantityAfter-result.Delta <= 0 && result.QuantityAfter > 0 {
+			wishlist.NotifyBackInStockInMemory(t, adj.BookID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	adj.ID, adj.QuantityAfter = result.ID, result.QuantityAfter
+	return nil
+}
+
+func memInsertAdjustment(t *memstore.Tables, adj *InventoryAdjustment) {
+	adj.ID = t.NextID("AjusteInventario")
+	t.Adjustments[adj.ID] = memstore.Adjustment{
+		ID:            adj.ID,
+		BookID:        adj.BookID,
+		UserID:        clonePtr(adj.UserID),
+		Delta:         adj.Delta,
+		Reason:        adj.Reason,
+		Note:          adj.Note,
+		QuantityAfter: adj.QuantityAfter,
+		CreatedAt:     memstore.Stamp(adj.CreatedAt.UTC()),
+	}
+}
+
+func (r *memoryRepository) ListInventoryAdjustments(ctx context.Context, bookID int64) ([]InventoryAdjustment, error) {
+	out := []InventoryAdjustment{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, a := range t.Adjustments {
+			if a.BookID != bookID {
+				continue
+			}
+			out = append(out, InventoryAdjustment{
+				ID:            a.ID,
+				BookID:        a.BookID,
+				UserID:        clonePtr(a.UserID),
+				Delta:         a.Delta,
+				Reason:        a.Reason,
+				Note:          a.Note,
+				QuantityAfter: a.QuantityAfter,
+				CreatedAt:     a.CreatedAt,
+			})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, err
+}
+
+func (r *memoryRepository) SyncStockAlerts(ctx context.Context, low []StockAlert, at time.Time) error {
+	now := memstore.Stamp(at.UTC())
+	return r.store.Update(func(t *memstore.Tables) error {
+		lowBooks := map[int64]bool{}
+		for _, a := range low {
+			lowBooks[a.BookID] = true
+			updated := false
+			for id, open := range t.StockAlerts {
+				if open.BookID != a.BookID || open.ResolvedAt != nil {
+					continue
+				}
+				open.AvailableQuantity = a.AvailableQuantity
+				open.ReorderThreshold = a.ReorderThreshold
+				open.DailyVelocity = a.DailyVelocity
+				open.SuggestedQuantity = a.SuggestedQuantity
+				open.UpdatedAt = now
+				t.StockAlerts[id] = open
+				updated = true
+			}
+			if updated {
+				continue
+			}
+			id := t.NextID("AlertaStock")
+			t.StockAlerts[id] = memstore.StockAlert{
+				ID:                id,
+				BookID:            a.BookID,
+				AvailableQuantity: a.AvailableQuantity,
+				ReorderThreshold:  a.ReorderThreshold,
+				DailyVelocity:     a.DailyVelocity,
+				SuggestedQuantity: a.SuggestedQuantity,
+				CreatedAt:         now,
+				UpdatedAt:         now,
+			}
+		}
+
+		for id, open := range t.StockAlerts {
+			if open.ResolvedAt == nil && !lowBooks[open.BookID] {
+				resolved := now
+				open.ResolvedAt, open.UpdatedAt = &resolved, now
+				t.StockAlerts[id] = open
+			}
+		}
+		return nil
+	})
+}
+
+func (r *memoryRepository) ListStockAlerts(ctx context.Context, onlyOpen bool) ([]StockAlert, error) {
+	out := []StockAlert{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, a := range t.StockAlerts {
+			b, ok := t.Books[a.BookID]
+			if !ok || (onlyOpen && a.ResolvedAt != nil) {
+				continue
+			}
+			out = append(out, StockAlert{
+				ID:                a.ID,
+				BookID:            a.BookID,
+				BookName:          b.Name,
+				AvailableQuantity: a.AvailableQuantity,
+				ReorderThreshold:  a.ReorderThreshold,
+				DailyVelocity:     a.DailyVelocity,
+				SuggestedQuantity: a.SuggestedQuantity,
+				CreatedAt:         a.CreatedAt,
+				UpdatedAt:         a.UpdatedAt,
+				ResolvedAt:        clonePtr(a.ResolvedAt),
+			})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].SuggestedQuantity != out[j].SuggestedQuantity {
+			return out[i].SuggestedQuantity > out[j].SuggestedQuantity
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, err
+}
@@ -0,0 +1,71 @@
+package books
+
+import "strings"
+
+// Modalidades en que se ofrece un libro (Libro.transaction_type)
+const (
+	ModeSale   = "venta"
+	ModeRental = "arriendo"
+	ModeBoth   = "ambos" // se vende y se arrienda, cada uno con su precio y su stock
+)
+
+func validMode(mode string) bool {
+	switch mode {
+	case ModeSale, ModeRental, ModeBoth:
+		return true
+	}
+	return false
+}
+
+// normMode limpia una modalidad recibida del cliente
+func normMode(mode string) string { return strings.ToLower(strings.TrimSpace(mode)) }
+
+// ForSale indica si el libro se puede comprar
+func (b *Book) ForSale() bool { return b.TransactionType == ModeSale || b.TransactionType == ModeBoth }
+
+// ForRent indica si el libro se puede arrendar
+func (b *Book) ForRent() bool { return b.TransactionType == ModeRental || b.TransactionType == ModeBoth }
+
+// SalePrice es el precio de venta; los libros solo de arriendo no tienen
+func (b *Book) SalePrice() *int64 {
+	if !b.ForSale() {
+		return nil
+	}
+	p := b.Price
+	return &p
+}
+
+// RentalPricePerPeriod es el precio de arrendar el libro por RentalPeriodDays.
+// En los libros solo de arriendo es Price; en los de ambas modalidades, RentalPrice.
+func (b *Book) RentalPricePerPeriod() *int64 {
+	switch b.TransactionType {
+	case ModeRental:
+		p := b.Price
+		return &p
+	case ModeBoth:
+		return b.RentalPrice
+	}
+	return nil
+}
+
+// SaleQuantity es el stock disponible para la venta
+func (bwi *BookWithInventory) SaleQuantity() int64 {
+	return bwi.AvailableQuantity - bwi.RentalQuantity
+}
+
+// validateModes revisa los campos que dependen de la modalidad del libro
+func validateModes(b *Book) error {
+	if !validMode(b.TransactionType) {
+		return invalidBook("transaction_type", "el tipo de transacción debe ser 'venta', 'arriendo' o 'ambos'")
+	}
+	if b.Price < 0 {
+		return invalidBook("price", "el precio debe ser un valor positivo")
+	}
+	if b.TransactionType == ModeBoth && (b.RentalPrice == nil || *b.RentalPrice <= 0) {
+		return invalidBook("rental_price", "un libro en venta y arriendo necesita un precio de arriendo positivo")
+	}
+	if b.RentalPeriodDays <= 0 {
+		return invalidBook("rental_period_days", "el período de arriendo debe ser de al menos un día")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"uzm-server/internal/logging"
+)
+
+// Activity es un evento (venta o préstamo) que suma a la popularidad de un libro
+type Activity struct {
+	BookID int64
+	Kind   string // "venta" | "prestamo"
+	At     time.Time
+}
+
+// Peso de cada tipo de evento en el puntaje de popularidad
+var activityWeights = map[string]float64{
+	"venta":    10,
+	"prestamo": 6,
+}
+
+// Cada popularityHalfLife el aporte de un evento se reduce a la mitad
+const popularityHalfLife = 14 * 24 * time.Hour
+
+// PopularityWindow limita qué eventos se consideran en el ranking de populares
+type PopularityWindow string
+
+const (
+	Window7Days  PopularityWindow = "7d"
+	Window30Days PopularityWindow = "30d"
+	WindowAll    PopularityWindow = "all"
+)
+
+func ParsePopularityWindow(v string) (PopularityWindow, error) {
+	switch w := PopularityWindow(strings.ToLower(strings.TrimSpace(v))); w {
+	case "":
+		return WindowAll, nil
+	case Window7Days, Window30Days, WindowAll:
+		return w, nil
+	}
+	return "", fmt.Errorf("ventana inválida %q (7d|30d|all)", v)
+}
+
+func (w PopularityWindow) duration() time.Duration {
+	switch w {
+	case Window7Days:
+		return 7 * 24 * time.Hour
+	case Window30Days:
+		return 30 * 24 * time.Hour
+	}
+	return 0
+}
+
+// popularityScores suma el aporte de cada evento con decaimiento exponencial
+// según su antigüedad. Si since no es cero, se ignoran los eventos anteriores.
+func popularityScores(activity []Activity, now, since time.Time) map[int64]int64 {
+	raw := make(map[int64]float64)
+	for _, a := range activity {
+		if !since.IsZero() && a.At.Before(since) {
+			continue
+		}
+		age := now.Sub(a.At)
+		if age < 0 {
+			age = 0
+		}
+		decay := math.Pow(0.5, float64(age)/float64(popularityHalfLife))
+		raw[a.BookID] += activityWeights[a.Kind] * decay
+	}
+	out := make(map[int64]int64, len(raw))
+	for id, v := range raw {
+		out[id] = int64(math.Round(v))
+	}
+	return out
+}
+
+func (s *service) RecomputePopularity(ctx context.Context) error {
+	activity, err := s.repo.ListActivity(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePopularityScores(ctx, popularityScores(activity, time.Now(), time.Time{}))
+}
+
+// ListPopular ordena el catálogo por popularidad. Con ventana "all" usa el
+// puntaje guardado por el job; con 7d/30d lo calcula solo con los eventos de
+// la ventana. La categoría y el límite se aplican en el repositorio.
+func (s *service) ListPopular(ctx context.Context, window PopularityWindow, category string, limit int) ([]*BookWithInventory, error) {
+	var scores map[int64]int64
+	if d := window.duration(); d > 0 {
+		now := time.Now()
+		since := now.Add(-d)
+		activity, err := s.repo.ListActivity(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		scores = popularityScores(activity, now, since)
+	}
+	books, err := s.repo.ListPopular(ctx, scores, strings.TrimSpace(category), limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.pricedBooks(ctx, books)
+}
+
+// RunPopularityJob recalcula popularity_score al iniciar y luego cada interval,
+// hasta que ctx se cancele. report, si no es nil, recibe el resultado de cada
+// ejecución (lo usa el chequeo de salud).
+func RunPopularityJob(ctx context.Context, svc Service, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := svc.RecomputePopularity(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Error("error recalculando popularidad", "err", err)
+		}
+		if report != nil && ctx.Err() == nil {
+			report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package books
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrPromotionNotFound = apperr.NotFound("promotion_not_found", "promoción no encontrada")
+	ErrInvalidPromotion  = apperr.Validation("invalid_promotion", "promoción inválida")
+)
+
+// PriceChange es un cambio de Libro.price; OldPrice es nil en el precio inicial
+type PriceChange struct {
+	ID        int64
+	BookID    int64
+	OldPrice  *int64
+	NewPrice  int64
+	ChangedAt time.Time
+}
+
+// Tipos de descuento de una promoción
+const (
+	DiscountPercentage = "porcentaje" // DiscountValue es un porcentaje entre 1 y 100
+	DiscountFixed      = "monto"      // DiscountValue se resta del precio de lista
+)
+
+// Promotion descuenta el precio de un libro o de toda una categoría entre
+// StartsAt (inclusive) y EndsAt (exclusive). Solo uno de BookID y
+// BookCategory viene definido.
+type Promotion struct {
+	ID            int64
+	BookID        *int64
+	BookCategory  *string
+	DiscountType  string
+	DiscountValue int64
+	StartsAt      time.Time
+	EndsAt        time.Time
+}
+
+type CreatePromotionInput struct {
+	BookID        *int64    `json:"book_id"`
+	BookCategory  *string   `json:"book_category"`
+	DiscountType  string    `json:"discount_type" binding:"required"` // "porcentaje" | "monto"
+	DiscountValue int64     `json:"discount_value" binding:"required"`
+	StartsAt      time.Time `json:"starts_at" binding:"required"`
+	EndsAt        time.Time `json:"ends_at" binding:"required"`
+}
+
+func (p *Promotion) appliesTo(b *Book) bool {
+	if p.BookID != nil {
+		return *p.BookID == b.ID
+	}
+	return p.BookCategory != nil && strings.EqualFold(*p.BookCategory, b.BookCategory)
+}
+
+func (p *Promotion) discounted(price int64) int64 {
+	var out int64
+	switch p.DiscountType {
+	case DiscountPercentage:
+		out = price - price*p.DiscountValue/100
+	case DiscountFixed:
+		out = price - p.DiscountValue
+	default:
+		return price
+	}
+	if out < 0 {
+		return 0
+	}
+	return out
+}
+
+// priceWith calcula el precio efectivo de un libro con la promoción vigente
+// más conveniente para el cliente.
+func priceWith(b *Book, promos []Promotion) {
+	b.EffectivePrice = b.Price
+	b.PromotionID = nil
+	for i := range promos {
+		p := &promos[i]
+		if !p.appliesTo(b) {
+			continue
+		}
+		if price := p.discounted(b.Price); price < b.EffectivePrice {
+			b.EffectivePrice = price
+			b.PromotionID = &p.ID
+		}
+	}
+}
+
+// applyPricing completa EffectivePrice de los libros con las promociones vigentes
+func (s *service) applyPricing(ctx context.Context, books ...*BookWithInventory) error {
+	promos, err := s.repo.ListPromotions(ctx, time.Now(), true)
+	if err != nil {
+		return err
+	}
+	for _, bwi := range books {
+		if bwi != nil && bwi.Book != nil {
+			priceWith(bwi.Book, promos)
+		}
+	}
+	return nil
+}
+
+func (s *service) ListPriceHistory(ctx context.Context, bookID int64) ([]*PriceChange, error) {
+	book, err := s.repo.GetBookByID(ctx, bookID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if book.Book == nil {
+		return nil, ErrBookNotFound
+	}
+	history, err := s.repo.ListPriceHistory(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*PriceChange, len(history))
+	for i := range history {
+		out[i] = &history[i]
+	}
+	return out, nil
+}
+
+func (s *service) CreatePromotion(ctx context.Context, input CreatePromotionInput) (*Promotion, error) {
+	p := &Promotion{
+		BookID:        input.BookID,
+		DiscountType:  strings.ToLower(strings.TrimSpace(input.DiscountType)),
+		DiscountValue: input.DiscountValue,
+		StartsAt:      input.StartsAt.UTC(),
+		EndsAt:        input.EndsAt.UTC(),
+	}
+	if input.BookCategory != nil {
+		if cat := strings.TrimSpace(*input.BookCategory); cat != "" {
+			p.BookCategory = &cat
+		}
+	}
+
+	switch {
+	case (p.BookID == nil) == (p.BookCategory == nil):
+		return nil, fmt.Errorf("%w: se debe indicar book_id o book_category, no ambos", ErrInvalidPromotion)
+	case p.DiscountType != DiscountPercentage && p.DiscountType != DiscountFixed:
+		return nil, fmt.Errorf("%w: discount_type debe ser 'porcentaje' o 'monto'", ErrInvalidPromotion)
+	case p.DiscountValue <= 0 || (p.DiscountType == DiscountPercentage && p.DiscountValue > 100):
+		return nil, fmt.Errorf("%w: discount_value fuera de rango", ErrInvalidPromotion)
+	case !p.EndsAt.After(p.StartsAt):
+		return nil, fmt.Errorf("%w: ends_at debe ser posterior a starts_at", ErrInvalidPromotion)
+	}
+
+	if p.BookID != nil {
+		book, err := s.repo.GetBookByID(ctx, *p.BookID, nil)
+		if err != nil {
+			return nil, err
+		}
+		if book.Book == nil {
+			return nil, ErrBookNotFound
+		}
+	}
+
+	id, err := s.repo.CreatePromotion(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+	return p, nil
+}
+
+func (s *service) ListPromotions(ctx context.Context, onlyActive bool) ([]*Promotion, error) {
+	promos, err := s.repo.ListPromotions(ctx, time.Now(), onlyActive)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Promotion, len(promos))
+	for i := range promos {
+		out[i] = &promos[i]
+	}
+	return out, nil
+}
+
+func (s *service) DeletePromotion(ctx context.Context, id int64) error {
+	return s.repo.DeletePromotion(ctx, id)
+}
@@ -4,50 +4,123 @@ import (
     "context"
     "database/sql"
     "errors"
+    "fmt"
     "strings"
+    "time"
+
+    "uzm-server/internal/db"
+    "uzm-server/internal/wishlist"
 )
 
-type sqliteRepository struct{ dbconn *sql.DB }
+// sqlRepository guarda los libros en SQLite o PostgreSQL. Las consultas se
+// escriben una vez, con marcadores ?, y evitan lo propio de cada motor:
+// RETURNING en vez de LastInsertId y CASE en vez de MIN/MAX escalares.
+type sqlRepository struct{ dbconn *db.DB }
 
-func NewSQLiteRepository(db *sql.DB) Repository { return &sqliteRepository{dbconn: db} }
+func NewSQLiteRepository(conn *sql.DB) Repository { return &sqlRepository{dbconn: db.Wrap(conn, db.SQLite)} }
 
-func (r *sqliteRepository) ListBook(ctx context.Context, onlyAvailable *bool) ([]BookWithInventory, error) {
-    q := `
+func NewPostgresRepository(conn *sql.DB) Repository { return &sqlRepository{dbconn: db.Wrap(conn, db.Postgres)} }
+
+// Columnas comunes para leer un libro junto a su inventario
+const bookSelect = `
 SELECT  b.id, b.book_name, b.book_category, b.transaction_type,
-        b.price, b.status, b.popularity_score,
-        COALESCE(i.available_quantity, 0) AS qty
+        b.price, b.rental_price, b.rental_period_days,
+        b.status, b.popularity_score, b.archived_at, b.reorder_threshold, b.cover_updated_at, b.version,
+        COALESCE(rs.avg_rating, 0), COALESCE(rs.review_count, 0),
+        COALESCE(i.available_quantity, 0) AS qty,
+        ` + rentalQty + ` AS rental_qty
 FROM    Libro b
-LEFT JOIN Inventario i ON i.book_id = b.id`
+LEFT JOIN Inventario i ON i.book_id = b.id
+LEFT JOIN (
+    SELECT book_id, AVG(rating) AS avg_rating, COUNT(*) AS review_count
+    FROM   Resena
+    GROUP BY book_id
+) rs ON rs.book_id = b.id`
+
+// rentalQty es el stock para arriendo: todo en los libros de arriendo, la parte
+// asignada (sin pasar del total) en los "ambos" y nada en los de venta
+const rentalQty = `CASE b.transaction_type
+            WHEN 'arriendo' THEN COALESCE(i.available_quantity, 0)
+            WHEN 'ambos' THEN CASE WHEN COALESCE(i.rental_quantity, 0) < COALESCE(i.available_quantity, 0)
+                THEN COALESCE(i.rental_quantity, 0) ELSE COALESCE(i.available_quantity, 0) END
+            ELSE 0 END`
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanBook(row rowScanner) (BookWithInventory, error) {
+    var b Book
+    var qty, rentalQty int64
+    var rentalPrice sql.NullInt64
+    var archivedAt, coverUpdatedAt sql.NullString
+    if err := row.Scan(&b.ID, &b.BookName, &b.BookCategory, &b.TransactionType,
+        &b.Price, &rentalPrice, &b.RentalPeriodDays,
+        &b.Status, &b.PopularityScore, &archivedAt, &b.ReorderThreshold, &coverUpdatedAt, &b.Version,
+        &b.RatingAverage, &b.ReviewCount, &qty, &rentalQty); err != nil {
+        return BookWithInventory{}, err
+    }
+    if rentalPrice.Valid {
+        b.RentalPrice = &rentalPrice.Int64
+    }
+    if archivedAt.Valid {
+        if t, err := time.Parse(time.RFC3339, archivedAt.String); err == nil {
+            b.ArchivedAt = &t
+        }
+    }
+    if coverUpdatedAt.Valid {
+        if t, err := time.Parse(time.RFC3339, coverUpdatedAt.String); err == nil {
+            b.CoverUpdatedAt = &t
+        }
+    }
+    return BookWithInventory{Book: &b, AvailableQuantity: qty, RentalQuantity: rentalQty}, nil
+}
+
+func (r *sqlRepository) ListBook(ctx context.Context, filter CatalogFilter) ([]BookWithInventory, error) {
+    var out []BookWithInventory
+    err := r.StreamBooks(ctx, filter, func(bwi BookWithInventory) error {
+        out = append(out, bwi)
+        return nil
+    })
+    if err != nil { return nil, err }
+    return out, nil
+}
+
+// StreamBooks recorre el catálogo fila por fila sin cargarlo completo en memoria
+func (r *sqlRepository) StreamBooks(ctx context.Context, filter CatalogFilter, fn func(BookWithInventory) error) error {
+    // Los libros archivados no forman parte del catálogo
+    q := bookSelect + `
+WHERE   b.archived_at IS NULL`
     args := []any{}
-    if onlyAvailable != nil && *onlyAvailable {
-        q += " WHERE COALESCE(i.available_quantity,0) > 0"
+    // Un libro "ambos" aparece tanto al filtrar por venta como por arriendo
+    if filter.Mode != "" {
+        q += " AND b.transaction_type IN (?, 'ambos')"
+        args = append(args, filter.Mode)
+    }
+    if filter.OnlyAvailable {
+        switch filter.Mode {
+        case ModeRental:
+            q += " AND " + rentalQty + " > 0"
+        case ModeSale:
+            q += " AND COALESCE(i.available_quantity,0) - " + rentalQty + " > 0"
+        default:
+            q += " AND COALESCE(i.available_quantity,0) > 0"
+        }
     }
     q += " ORDER BY b.id ASC"
 
     rows, err := r.dbconn.QueryContext(ctx, q, args...)
-    if err != nil { return nil, err }
+    if err != nil { return err }
     defer rows.Close()
 
-    var out []BookWithInventory
     for rows.Next() {
-        var b Book
-        var qty int64
-        if err := rows.Scan(&b.ID, &b.BookName, &b.BookCategory, &b.TransactionType,
-            &b.Price, &b.Status, &b.PopularityScore, &qty); err != nil {
-            return nil, err
-        }
-        out = append(out, BookWithInventory{Book: &b, AvailableQuantity: qty})
+        bwi, err := scanBook(rows)
+        if err != nil { return err }
+        if err := fn(bwi); err != nil { return err }
     }
-    return out, rows.Err()
+    return rows.Err()
 }
 
-func (r *sqliteRepository) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (BookWithInventory, error) {
-    q := `
-SELECT  b.id, b.book_name, b.book_category, b.transaction_type,
-        b.price, b.status, b.popularity_score,
-        COALESCE(i.available_quantity, 0) AS qty
-FROM    Libro b
-LEFT JOIN Inventario i ON i.book_id = b.id
+func (r *sqlRepository) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (BookWithInventory, error) {
+    q := bookSelect + `
 WHERE   b.id = ?`
     args := []any{id}
     if onlyAvailable != nil && *onlyAvailable {
@@ -55,42 +128,43 @@ WHERE   b.id = ?`
     }
 
     row := r.dbconn.QueryRowContext(ctx, q, args...)
-    var b Book
-    var qty int64
-    if err := row.Scan(&b.ID, &b.BookName, &b.BookCategory, &b.TransactionType,
-        &b.Price, &b.Status, &b.PopularityScore, &qty); err != nil {
+    bwi, err := scanBook(row)
+    if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return BookWithInventory{}, nil
         }
         return BookWithInventory{}, err
     }
-    return BookWithInventory{Book: &b, AvailableQuantity: qty}, nil
+    return bwi, nil
 }
 
-func (r *sqliteRepository) CreateBook(ctx context.Context, b *Book, initialStock int64) (int64, error) {
+func (r *sqlRepository) CreateBook(ctx context.Context, b *Book, initialStock, rentalStock int64) (int64, error) {
     tx, err := r.dbconn.BeginTx(ctx, nil)
     if err != nil { return 0, err }
     defer func() { if err != nil { _ = tx.Rollback() } }()
 
-    res, err := tx.ExecContext(ctx, `
-INSERT INTO Libro (book_name, book_category, transaction_type, price, status, popularity_score)
-VALUES (?, ?, ?, ?, ?, ?)`,
+    var bookID int64
+    err = tx.QueryRowContext(ctx, `
+INSERT INTO Libro (book_name, book_category, transaction_type, price, rental_price, rental_period_days, status, reorder_threshold)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING id`,
         strings.TrimSpace(b.BookName),
         strings.TrimSpace(b.BookCategory),
         strings.ToLower(strings.TrimSpace(b.TransactionType)),
         b.Price,
-        b.Status,           
-        b.PopularityScore,
-    )
+        b.RentalPrice,
+        b.RentalPeriodDays,
+        b.Status,
+        b.ReorderThreshold,
+    ).Scan(&bookID)
     if err != nil { return 0, err }
 
-    bookID, err := res.LastInsertId()
-    if err != nil { return 0, err }
+    if err = recordPriceChange(ctx, tx, bookID, nil, b.Price); err != nil { return 0, err }
 
     _, err = tx.ExecContext(ctx, `
-INSERT INTO Inventario (book_id, available_quantity)
-VALUES (?, ?)`,
-        bookID, initialStock,
+INSERT INTO Inventario (book_id, available_quantity, rental_quantity)
+VALUES (?, ?, ?)`,
+        bookID, initialStock, rentalStock,
     )
     if err != nil { return 0, err }
 
@@ -98,31 +172,62 @@ VALUES (?, ?)`,
     return bookID, nil
 }
 
-func (r *sqliteRepository) UpdateBook(ctx context.Context, b *Book, stock *int64) (err error) {
+// UpdateBook guarda el libro solo si sigue en b.Version; si otro PATCH lo
+// cambió entre medio devuelve ErrVersionMismatch. Deja en b la versión nueva.
+// rentalStock, si viene, es la parte del stock que queda para arriendo.
+func (r *sqlRepository) UpdateBook(ctx context.Context, b *Book, stock, rentalStock *int64) (err error) {
     tx, err := r.dbconn.BeginTx(ctx, nil)
     if err != nil { return err }
     defer func() { if err != nil { _ = tx.Rollback() } }()
 
-    _, err = tx.ExecContext(ctx, `
+    var oldPrice int64
+    err = tx.QueryRowContext(ctx, `SELECT price FROM Libro WHERE id = ?`, b.ID).Scan(&oldPrice)
+    if errors.Is(err, sql.ErrNoRows) { err = ErrBookNotFound }
+    if err != nil { return err }
+    if oldPrice != b.Price {
+        if err = recordPriceChange(ctx, tx, b.ID, &oldPrice, b.Price); err != nil { return err }
+    }
+
+    res, err := tx.ExecContext(ctx, `
 UPDATE Libro
 SET     book_name = ?,
         book_category = ?,
         transaction_type = ?,
         price = ?,
+        rental_price = ?,
+        rental_period_days = ?,
         status = ?,
-        popularity_score = ?
-WHERE   id = ?`,
+        reorder_threshold = ?,
+        version = version + 1
+WHERE   id = ? AND version = ?`,
         strings.TrimSpace(b.BookName),
         strings.TrimSpace(b.BookCategory),
         strings.ToLower(strings.TrimSpace(b.TransactionType)),
         b.Price,
+        b.RentalPrice,
+        b.RentalPeriodDays,
         b.Status,
-        b.PopularityScore,
+        b.ReorderThreshold,
         b.ID,
+        b.Version,
     )
     if err != nil { return err }
+    n, err := res.RowsAffected()
+    if err != nil { return err }
+    if n == 0 {
+        err = ErrVersionMismatch
+        return err
+    }
+    b.Version++
 
     if stock != nil {
+        if err = checkManualStock(ctx, tx, b.ID); err != nil { return err }
+
+        var before int64
+        err = tx.QueryRowContext(ctx, `
+SELECT COALESCE((SELECT available_quantity FROM Inventario WHERE book_id = ?), 0)`, b.ID).Scan(&before)
+        if err != nil { return err }
+
         // Update inventory quantity for the book
         _, err = tx.ExecContext(ctx, `
 UPDATE Inventario
@@ -132,14 +237,601 @@ WHERE book_id = ?`,
         )
         if err != nil { return err }
 
-        // (Opcional) sincroniza status si quieres mantenerlo coherente
-        // e.g., status = (available_quantity > 0)
-        _, err = tx.ExecContext(ctx, `
+        if err = syncBookStatus(ctx, tx, b.ID); err != nil { return err }
+        if before <= 0 && *stock > 0 {
+            if err = wishlist.NotifyBackInStock(ctx, tx, b.ID); err != nil { return err }
+        }
+
+        // El valor absoluto queda en la bitácora como una corrección sin usuario
+        if delta := *stock - before; delta != 0 {
+            err = insertAdjustment(ctx, tx, &InventoryAdjustment{
+                BookID:        b.ID,
+                Delta:         delta,
+                Reason:        ReasonCorrection,
+                Note:          "PATCH /books/:id",
+                QuantityAfter: *stock,
+                CreatedAt:     time.Now().UTC().Truncate(time.Second),
+            })
+            if err != nil { return err }
+        }
+    }
+
+    if rentalStock != nil {
+        res, err = tx.ExecContext(ctx, `
+UPDATE Inventario
+SET rental_quantity = ?
+WHERE book_id = ? AND ? <= available_quantity`,
+            *rentalStock, b.ID, *rentalStock,
+        )
+        if err != nil { return err }
+        if n, err = res.RowsAffected(); err != nil { return err }
+        if n == 0 {
+            err = invalidBook("rental_stock", "el stock de arriendo no puede superar el stock disponible")
+            return err
+        }
+    } else if stock != nil {
+        // Si el stock baja, lo asignado a arriendo baja con él
+        if err = clampRentalStock(ctx, tx, b.ID); err != nil { return err }
+    }
+
+    return tx.Commit()
+}
+
+// clampRentalStock evita que el stock de arriendo supere el total
+func clampRentalStock(ctx context.Context, tx *db.Tx, bookID int64) error {
+    _, err := tx.ExecContext(ctx, `
+UPDATE Inventario
+SET rental_quantity = CASE
+        WHEN available_quantity < 0 THEN 0
+        WHEN rental_quantity > available_quantity THEN available_quantity
+        ELSE rental_quantity END
+WHERE book_id = ?`, bookID)
+    return err
+}
+
+// bumpVersion marca el libro como modificado para invalidar su ETag
+func bumpVersion(ctx context.Context, tx *db.Tx, bookID int64) error {
+    _, err := tx.ExecContext(ctx, `UPDATE Libro SET version = version + 1 WHERE id = ?`, bookID)
+    return err
+}
+
+// syncBookStatus deja status = (available_quantity > 0)
+func syncBookStatus(ctx context.Context, tx *db.Tx, bookID int64) error {
+    _, err := tx.ExecContext(ctx, `
 UPDATE Libro
-SET status = CASE WHEN (SELECT available_quantity FROM Inventario WHERE book_id = ?) > 0 THEN 1 ELSE 0 END
-WHERE id = ?`, b.ID, b.ID)
+SET status = COALESCE((SELECT available_quantity FROM Inventario WHERE book_id = ?), 0) > 0
+WHERE id = ?`, bookID, bookID)
+    return err
+}
+
+// ArchiveBook oculta el libro del catálogo sin borrar su historial.
+// Se rechaza si quedan préstamos pendientes del libro.
+func (r *sqlRepository) ArchiveBook(ctx context.Context, id int64) (err error) {
+    tx, err := r.dbconn.BeginTx(ctx, nil)
+    if err != nil { return err }
+    defer func() { if err != nil { _ = tx.Rollback() } }()
+
+    if err = bookExists(ctx, tx, id); err != nil { return err }
+
+    var onLoan int64
+    err = tx.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM Prestamo WHERE book_id = ? AND status = 'pendiente'`, id).Scan(&onLoan)
+    if err != nil { return err }
+    if onLoan > 0 {
+        err = ErrBookOnLoan
+        return err
+    }
+
+    // Si ya estaba archivado se conserva la fecha original
+    _, err = tx.ExecContext(ctx, `
+UPDATE Libro SET archived_at = COALESCE(archived_at, ?), version = version + 1 WHERE id = ?`,
+        time.Now().UTC().Format(time.RFC3339), id,
+    )
+    if err != nil { return err }
+
+    return tx.Commit()
+}
+
+func (r *sqlRepository) RestoreBook(ctx context.Context, id int64) error {
+    res, err := r.dbconn.ExecContext(ctx, `UPDATE Libro SET archived_at = NULL, version = version + 1 WHERE id = ?`, id)
+    if err != nil { return err }
+    n, err := res.RowsAffected()
+    if err != nil { return err }
+    if n == 0 { return ErrBookNotFound }
+    return nil
+}
+
+// DeleteBook borra el libro y su inventario. Solo se permite si nunca
+// tuvo ventas ni préstamos, para no dejar historial huérfano.
+func (r *sqlRepository) DeleteBook(ctx context.Context, id int64) (err error) {
+    tx, err := r.dbconn.BeginTx(ctx, nil)
+    if err != nil { return err }
+    defer func() { if err != nil { _ = tx.Rollback() } }()
+
+    if err = bookExists(ctx, tx, id); err != nil { return err }
+
+    var history int64
+    err = tx.QueryRowContext(ctx, `
+SELECT (SELECT COUNT(*) FROM Venta WHERE book_id = ?) +
+       (SELECT COUNT(*) FROM Prestamo WHERE book_id = ?)`, id, id).Scan(&history)
+    if err != nil { return err }
+    if history > 0 {
+        err = ErrBookHasHistory
+        return err
+    }
+
+    if _, err = tx.ExecContext(ctx, `DELETE FROM Inventario WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM HistorialPrecio WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM AjusteInventario WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM AlertaStock WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM Ejemplar WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM ListaDeseos WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM Notificacion WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM Promocion WHERE book_id = ?`, id); err != nil { return err }
+    if _, err = tx.ExecContext(ctx, `DELETE FROM Libro WHERE id = ?`, id); err != nil { return err }
+
+    return tx.Commit()
+}
+
+// checkManualStock rechaza cambios directos de stock en libros con ejemplares
+// registrados, cuyo inventario se deriva de Ejemplar.
+func checkManualStock(ctx context.Context, tx *db.Tx, id int64) error {
+    var tracked bool
+    err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM Ejemplar WHERE book_id = ?)`, id).Scan(&tracked)
+    if err != nil { return err }
+    if tracked { return ErrStockFromCopies }
+    return nil
+}
+
+func bookExists(ctx context.Context, tx *db.Tx, id int64) error {
+    var one int
+    err := tx.QueryRowContext(ctx, `SELECT 1 FROM Libro WHERE id = ?`, id).Scan(&one)
+    if errors.Is(err, sql.ErrNoRows) { return ErrBookNotFound }
+    return err
+}
+
+// Formatos de fecha presentes en Venta.sale_date y Prestamo.start_date
+var activityDateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseActivityDate(v string) (time.Time, bool) {
+    for _, layout := range activityDateLayouts {
+        if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+            return t, true
+        }
+    }
+    return time.Time{}, false
+}
+
+// ListActivity devuelve las ventas y préstamos con su fecha, insumo del
+// cálculo de popularidad. Si since no es cero, la consulta descarta los días
+// anteriores a since (las fechas se guardan en varios formatos y se comparan
+// por el día); el llamador filtra dentro del día. Las filas con fecha
+// ilegible se ignoran.
+func (r *sqlRepository) ListActivity(ctx context.Context, since time.Time) ([]Activity, error) {
+    saleCond, loanCond := "", ""
+    var args []any
+    if !since.IsZero() {
+        day := since.UTC().Format(time.DateOnly)
+        saleCond, loanCond = " AND CAST(sale_date AS TEXT) >= ?", " AND start_date >= ?"
+        args = append(args, day, day)
+    }
+    rows, err := r.dbconn.QueryContext(ctx, `
+SELECT book_id, 'venta', CAST(sale_date AS TEXT) FROM Venta WHERE book_id IS NOT NULL`+saleCond+`
+UNION ALL
+SELECT book_id, 'prestamo', start_date FROM Prestamo WHERE book_id IS NOT NULL`+loanCond, args...)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    var out []Activity
+    for rows.Next() {
+        var a Activity
+        var date string
+        if err := rows.Scan(&a.BookID, &a.Kind, &date); err != nil { return nil, err }
+        t, ok := parseActivityDate(date)
+        if !ok { continue }
+        a.At = t
+        out = append(out, a)
+    }
+    return out, rows.Err()
+}
+
+// ListPopular ordena el catálogo por popularidad y aplica category (sin
+// distinguir mayúsculas) y limit (0 = sin límite) en la consulta. Con scores
+// nil ordena por el popularity_score guardado; si no, por el puntaje de
+// scores (0 para los libros ausentes), que queda como PopularityScore.
+func (r *sqlRepository) ListPopular(ctx context.Context, scores map[int64]int64, category string, limit int) ([]BookWithInventory, error) {
+    var q string
+    var args []any
+    order := "b.popularity_score DESC"
+    if len(scores) > 0 {
+        // Los puntajes de la ventana entran como una tabla VALUES
+        rows := make([]string, 0, len(scores))
+        for id, score := range scores {
+            rows = append(rows, "(CAST(? AS BIGINT), CAST(? AS BIGINT))")
+            args = append(args, id, score)
+        }
+        q = `
+WITH puntaje (book_id, score) AS (VALUES ` + strings.Join(rows, ", ") + `)` + bookSelect + `
+LEFT JOIN puntaje p ON p.book_id = b.id`
+        order = "COALESCE(p.score, 0) DESC"
+    } else {
+        q = bookSelect
+        if scores != nil {
+            order = "" // ningún libro tiene eventos en la ventana
+        }
+    }
+    q += `
+WHERE   b.archived_at IS NULL`
+    if category != "" {
+        q += " AND LOWER(b.book_category) = LOWER(?)"
+        args = append(args, category)
+    }
+    if order != "" {
+        order += ", "
+    }
+    q += " ORDER BY " + order + "b.id ASC"
+    if limit > 0 {
+        q += " LIMIT ?"
+        args = append(args, limit)
+    }
+
+    out, err := r.queryBooks(ctx, q, args...)
+    if err != nil { return nil, err }
+    if scores != nil {
+        for _, bwi := range out {
+            bwi.Book.PopularityScore = scores[bwi.Book.ID]
+        }
+    }
+    return out, nil
+}
+
+// UpdatePopularityScores reemplaza popularity_score de todo el catálogo; los
+// libros ausentes del mapa quedan en 0.
+func (r *sqlRepository) UpdatePopularityScores(ctx context.Context, scores map[int64]int64) (err error) {
+    tx, err := r.dbconn.BeginTx(ctx, nil)
+    if err != nil { return err }
+    defer func() { if err != nil { _ = tx.Rollback() } }()
+
+    if _, err = tx.ExecContext(ctx, `UPDATE Libro SET popularity_score = 0`); err != nil { return err }
+
+    stmt, err := tx.PrepareContext(ctx, `UPDATE Libro SET popularity_score = ? WHERE id = ?`)
+    if err != nil { return err }
+    defer stmt.Close()
+    for id, score := range scores {
+        if _, err = stmt.ExecContext(ctx, score, id); err != nil { return err }
+    }
+
+    return tx.Commit()
+}
+
+// Ventas y préstamos por usuario, base de las recomendaciones
+const activityCTE = `
+WITH actividad AS (
+    SELECT user_id, book_id FROM Venta WHERE user_id IS NOT NULL AND book_id IS NOT NULL
+    UNION
+    SELECT user_id, book_id FROM Prestamo WHERE user_id IS NOT NULL AND book_id IS NOT NULL
+)`
+
+func (r *sqlRepository) queryBooks(ctx context.Context, q string, args ...any) ([]BookWithInventory, error) {
+    rows, err := r.dbconn.QueryContext(ctx, q, args...)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    var out []BookWithInventory
+    for rows.Next() {
+        bwi, err := scanBook(rows)
+        if err != nil { return nil, err }
+        out = append(out, bwi)
+    }
+    return out, rows.Err()
+}
+
+// ListRelated devuelve los libros que más compraron o arrendaron los mismos
+// usuarios que el libro indicado (co-ocurrencia).
+func (r *sqlRepository) ListRelated(ctx context.Context, bookID int64, limit int) ([]BookWithInventory, error) {
+    q := activityCTE + `,
+relacionados AS (
+    SELECT o.book_id, COUNT(DISTINCT o.user_id) AS usuarios
+    FROM   actividad t
+    JOIN   actividad o ON o.user_id = t.user_id AND o.book_id <> t.book_id
+    WHERE  t.book_id = ?
+    GROUP BY o.book_id
+)` + bookSelect + `
+JOIN    relacionados rel ON rel.book_id = b.id
+WHERE   b.archived_at IS NULL
+ORDER BY rel.usuarios DESC, b.popularity_score DESC, b.id ASC
+LIMIT   ?`
+    return r.queryBooks(ctx, q, bookID, limit)
+}
+
+// ListRecommendations ordena los libros de las categorías que el usuario más
+// consume, excluyendo los que ya compró o arrendó. Sin historial, recurre a
+// los más populares.
+func (r *sqlRepository) ListRecommendations(ctx context.Context, userID int64, limit int) ([]BookWithInventory, error) {
+    q := activityCTE + `,
+afinidad AS (
+    SELECT l.book_category AS categoria, COUNT(*) AS peso
+    FROM   actividad a
+    JOIN   Libro l ON l.id = a.book_id
+    WHERE  a.user_id = ?
+    GROUP BY l.book_category
+)` + bookSelect + `
+LEFT JOIN afinidad af ON af.categoria = b.book_category
+WHERE   b.archived_at IS NULL
+  AND   b.id NOT IN (SELECT book_id FROM actividad WHERE user_id = ?)
+  AND   (af.peso IS NOT NULL OR NOT EXISTS (SELECT 1 FROM afinidad))
+ORDER BY COALESCE(af.peso, 0) DESC, b.popularity_score DESC, b.id ASC
+LIMIT   ?`
+    return r.queryBooks(ctx, q, userID, userID, limit)
+}
+
+// recordPriceChange guarda un cambio de precio en HistorialPrecio dentro de tx
+func recordPriceChange(ctx context.Context, tx *db.Tx, bookID int64, oldPrice *int64, newPrice int64) error {
+    _, err := tx.ExecContext(ctx, `
+INSERT INTO HistorialPrecio (book_id, old_price, new_price, changed_at)
+VALUES (?, ?, ?, ?)`,
+        bookID, oldPrice, newPrice, time.Now().UTC().Format(time.RFC3339),
+    )
+    return err
+}
+
+func (r *sqlRepository) ListPriceHistory(ctx context.Context, bookID int64) ([]PriceChange, error) {
+    rows, err := r.dbconn.QueryContext(ctx, `
+SELECT   id, book_id, old_price, new_price, changed_at
+FROM     HistorialPrecio
+WHERE    book_id = ?
+ORDER BY id DESC`, bookID)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    out := []PriceChange{}
+    for rows.Next() {
+        var pc PriceChange
+        var oldPrice sql.NullInt64
+        var changedAt string
+        if err := rows.Scan(&pc.ID, &pc.BookID, &oldPrice, &pc.NewPrice, &changedAt); err != nil { return nil, err }
+        if oldPrice.Valid { pc.OldPrice = &oldPrice.Int64 }
+        pc.ChangedAt, _ = time.Parse(time.RFC3339, changedAt)
+        out = append(out, pc)
+    }
+    return out, rows.Err()
+}
+
+func (r *sqlRepository) CreatePromotion(ctx context.Context, p *Promotion) (int64, error) {
+    var id int64
+    err := r.dbconn.QueryRowContext(ctx, `
+INSERT INTO Promocion (book_id, book_category, discount_type, discount_value, starts_at, ends_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id`,
+        p.BookID, p.BookCategory, p.DiscountType, p.DiscountValue,
+        p.StartsAt.UTC().Format(time.RFC3339), p.EndsAt.UTC().Format(time.RFC3339),
+    ).Scan(&id)
+    if err != nil { return 0, err }
+    return id, nil
+}
+
+// ListPromotions devuelve las promociones; con onlyActive solo las vigentes en at.
+// Las fechas se guardan en RFC3339 UTC, así que se comparan como texto.
+func (r *sqlRepository) ListPromotions(ctx context.Context, at time.Time, onlyActive bool) ([]Promotion, error) {
+    q := `
+SELECT id, book_id, book_category, discount_type, discount_value, starts_at, ends_at
+FROM   Promocion`
+    args := []any{}
+    if onlyActive {
+        now := at.UTC().Format(time.RFC3339)
+        q += " WHERE starts_at <= ? AND ends_at > ?"
+        args = append(args, now, now)
+    }
+    q += " ORDER BY starts_at ASC, id ASC"
+
+    rows, err := r.dbconn.QueryContext(ctx, q, args...)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    out := []Promotion{}
+    for rows.Next() {
+        var p Promotion
+        var bookID sql.NullInt64
+        var category sql.NullString
+        var startsAt, endsAt string
+        if err := rows.Scan(&p.ID, &bookID, &category, &p.DiscountType, &p.DiscountValue, &startsAt, &endsAt); err != nil { return nil, err }
+        if bookID.Valid { p.BookID = &bookID.Int64 }
+        if category.Valid { p.BookCategory = &category.String }
+        p.StartsAt, _ = time.Parse(time.RFC3339, startsAt)
+        p.EndsAt, _ = time.Parse(time.RFC3339, endsAt)
+        out = append(out, p)
+    }
+    return out, rows.Err()
+}
+
+func (r *sqlRepository) DeletePromotion(ctx context.Context, id int64) error {
+    res, err := r.dbconn.ExecContext(ctx, `DELETE FROM Promocion WHERE id = ?`, id)
+    if err != nil { return err }
+    n, err := res.RowsAffected()
+    if err != nil { return err }
+    if n == 0 { return ErrPromotionNotFound }
+    return nil
+}
+
+// AdjustInventory suma adj.Delta al stock en una sola sentencia, de modo que
+// dos ajustes simultáneos no se pisen, y lo registra en AjusteInventario.
+// Completa adj.ID y adj.QuantityAfter. En un libro "ambos" con adj.Mode
+// "arriendo" el delta se aplica también al stock de arriendo; si no, lo que
+// se resta sale primero del stock de venta.
+func (r *sqlRepository) AdjustInventory(ctx context.Context, adj *InventoryAdjustment) (err error) {
+    tx, err := r.dbconn.BeginTx(ctx, nil)
+    if err != nil { return err }
+    defer func() { if err != nil { _ = tx.Rollback() } }()
+
+    if err = bookExists(ctx, tx, adj.BookID); err != nil { return err }
+    if err = checkManualStock(ctx, tx, adj.BookID); err != nil { return err }
+
+    _, err = tx.ExecContext(ctx, `
+INSERT INTO Inventario (book_id, available_quantity) VALUES (?, 0)
+ON CONFLICT (book_id) DO NOTHING`, adj.BookID)
+    if err != nil { return err }
+
+    res, err := tx.ExecContext(ctx, `
+UPDATE Inventario
+SET    available_quantity = available_quantity + ?
+WHERE  book_id = ? AND available_quantity + ? >= 0`,
+        adj.Delta, adj.BookID, adj.Delta,
+    )
+    if err != nil { return err }
+    n, err := res.RowsAffected()
+    if err != nil { return err }
+    if n == 0 {
+        err = ErrInsufficientStock
+        return err
+    }
+
+    if adj.Mode == ModeRental {
+        var tt string
+        err = tx.QueryRowContext(ctx, `SELECT transaction_type FROM Libro WHERE id = ?`, adj.BookID).Scan(&tt)
+        if err != nil { return err }
+        if tt == ModeSale {
+            err = fmt.Errorf("%w: el libro no se arrienda", ErrInvalidAdjustment)
+            return err
+        }
+        // En los libros solo de arriendo todo el stock ya es de arriendo
+        if tt == ModeBoth {
+            res, err = tx.ExecContext(ctx, `
+UPDATE Inventario
+SET    rental_quantity = rental_quantity + ?
+WHERE  book_id = ? AND rental_quantity + ? >= 0`,
+                adj.Delta, adj.BookID, adj.Delta,
+            )
+            if err != nil { return err }
+            if n, err = res.RowsAffected(); err != nil { return err }
+            if n == 0 {
+                err = ErrInsufficientStock
+                return err
+            }
+        }
+    }
+    if err = clampRentalStock(ctx, tx, adj.BookID); err != nil { return err }
+
+    err = tx.QueryRowContext(ctx, `
+SELECT available_quantity FROM Inventario WHERE book_id = ?`, adj.BookID).Scan(&adj.QuantityAfter)
+    if err != nil { return err }
+
+    if err = syncBookStatus(ctx, tx, adj.BookID); err != nil { return err }
+    if err = bumpVersion(ctx, tx, adj.BookID); err != nil { return err }
+    if err = insertAdjustment(ctx, tx, adj); err != nil { return err }
+    if adj.QuantityAfter-adj.Delta <= 0 && adj.QuantityAfter > 0 {
+        if err = wishlist.NotifyBackInStock(ctx, tx, adj.BookID); err != nil { return err }
+    }
+
+    return tx.Commit()
+}
+
+func insertAdjustment(ctx context.Context, tx *db.Tx, adj *InventoryAdjustment) error {
+    return tx.QueryRowContext(ctx, `
+INSERT INTO AjusteInventario (book_id, user_id, delta, reason, note, quantity_after, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id`,
+        adj.BookID, adj.UserID, adj.Delta, adj.Reason, adj.Note, adj.QuantityAfter,
+        adj.CreatedAt.UTC().Format(time.RFC3339),
+    ).Scan(&adj.ID)
+}
+
+func (r *sqlRepository) ListInventoryAdjustments(ctx context.Context, bookID int64) ([]InventoryAdjustment, error) {
+    rows, err := r.dbconn.QueryContext(ctx, `
+SELECT   id, book_id, user_id, delta, reason, note, quantity_after, created_at
+FROM     AjusteInventario
+WHERE    book_id = ?
+ORDER BY id DESC`, bookID)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    out := []InventoryAdjustment{}
+    for rows.Next() {
+        var a InventoryAdjustment
+        var userID sql.NullInt64
+        var createdAt string
+        if err := rows.Scan(&a.ID, &a.BookID, &userID, &a.Delta, &a.Reason, &a.Note, &a.QuantityAfter, &createdAt); err != nil { return nil, err }
+        if userID.Valid { a.UserID = &userID.Int64 }
+        a.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+        out = append(out, a)
+    }
+    return out, rows.Err()
+}
+
+// SyncStockAlerts deja abierta una alerta por cada libro de low (actualizando
+// stock y sugerencia si ya existía) y resuelve las alertas abiertas del resto.
+func (r *sqlRepository) SyncStockAlerts(ctx context.Context, low []StockAlert, at time.Time) (err error) {
+    tx, err := r.dbconn.BeginTx(ctx, nil)
+    if err != nil { return err }
+    defer func() { if err != nil { _ = tx.Rollback() } }()
+
+    now := at.UTC().Format(time.RFC3339)
+    resolve := `
+UPDATE AlertaStock
+SET    resolved_at = ?, updated_at = ?
+WHERE  resolved_at IS NULL`
+    resolveArgs := []any{now, now}
+    if len(low) > 0 {
+        resolve += " AND book_id NOT IN (?" + strings.Repeat(",?", len(low)-1) + ")"
+    }
+
+    for _, a := range low {
+        resolveArgs = append(resolveArgs, a.BookID)
+
+        var res sql.Result
+        res, err = tx.ExecContext(ctx, `
+UPDATE AlertaStock
+SET    available_quantity = ?, reorder_threshold = ?, daily_velocity = ?, suggested_quantity = ?, updated_at = ?
+WHERE  book_id = ? AND resolved_at IS NULL`,
+            a.AvailableQuantity, a.ReorderThreshold, a.DailyVelocity, a.SuggestedQuantity, now, a.BookID,
+        )
+        if err != nil { return err }
+        var n int64
+        if n, err = res.RowsAffected(); err != nil { return err }
+        if n > 0 { continue }
+
+        _, err = tx.ExecContext(ctx, `
+INSERT INTO AlertaStock (book_id, available_quantity, reorder_threshold, daily_velocity, suggested_quantity, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+            a.BookID, a.AvailableQuantity, a.ReorderThreshold, a.DailyVelocity, a.SuggestedQuantity, now, now,
+        )
         if err != nil { return err }
     }
 
+    if _, err = tx.ExecContext(ctx, resolve, resolveArgs...); err != nil { return err }
+
     return tx.Commit()
 }
+
+func (r *sqlRepository) ListStockAlerts(ctx context.Context, onlyOpen bool) ([]StockAlert, error) {
+    q := `
+SELECT  a.id, a.book_id, b.book_name, a.available_quantity, a.reorder_threshold,
+        a.daily_velocity, a.suggested_quantity, a.created_at, a.updated_at, a.resolved_at
+FROM    AlertaStock a
+JOIN    Libro b ON b.id = a.book_id`
+    if onlyOpen {
+        q += " WHERE a.resolved_at IS NULL"
+    }
+    q += " ORDER BY a.suggested_quantity DESC, a.id ASC"
+
+    rows, err := r.dbconn.QueryContext(ctx, q)
+    if err != nil { return nil, err }
+    defer rows.Close()
+
+    out := []StockAlert{}
+    for rows.Next() {
+        var a StockAlert
+        var createdAt, updatedAt string
+        var resolvedAt sql.NullString
+        if err := rows.Scan(&a.ID, &a.BookID, &a.BookName, &a.AvailableQuantity, &a.ReorderThreshold,
+            &a.DailyVelocity, &a.SuggestedQuantity, &createdAt, &updatedAt, &resolvedAt); err != nil {
+            return nil, err
+        }
+        a.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+        a.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+        if resolvedAt.Valid {
+            if t, err := time.Parse(time.RFC3339, resolvedAt.String); err == nil { a.ResolvedAt = &t }
+        }
+        out = append(out, a)
+    }
+    return out, rows.Err()
+}
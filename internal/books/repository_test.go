@@ -0,0 +1,66 @@
+package books_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/books/bookstest"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	bookstest.RunRepositoryContract(t, func(t *testing.T) books.Repository {
+		return books.NewSQLiteRepository(dbtest.SQLite(t).DB)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	bookstest.RunRepositoryContract(t, func(t *testing.T) books.Repository {
+		return books.NewPostgresRepository(dbtest.Postgres(t).DB)
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	bookstest.RunRepositoryContract(t, func(t *testing.T) books.Repository {
+		return books.NewMemoryRepository(memstore.New())
+	})
+}
+
+// ListActivity descarta en la consulta los días anteriores a since, con las
+// fechas en cualquiera de los formatos que quedan en Venta y Prestamo
+func TestSQLiteListActivitySince(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	repo := books.NewSQLiteRepository(conn.DB)
+	id, err := repo.CreateBook(ctx, &books.Book{BookName: "Dune", BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, RentalPeriodDays: 14}, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.ExecContext(ctx, `
+INSERT INTO Venta (user_id, book_id, sale_date) VALUES (1, ?, '2024-03-31'), (1, ?, '2024-04-02T10:00:00Z');
+INSERT INTO Prestamo (user_id, book_id, start_date, return_date, status) VALUES
+    (1, ?, '2024-03-01 09:00:00', '2024-03-15', 'finalizado'),
+    (1, ?, '2024-04-01 09:00:00', '2024-04-15', 'pendiente')`, id, id, id, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.ListActivity(ctx, time.Time{})
+	if err != nil || len(all) != 4 {
+		t.Fatalf("ListActivity sin since = %d eventos, %v; quiero 4", len(all), err)
+	}
+	recent, err := repo.ListActivity(ctx, time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 {
+		t.Fatalf("ListActivity desde el 1 de abril = %+v, quiero el préstamo de ese día y la venta del 2", recent)
+	}
+}
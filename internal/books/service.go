@@ -2,77 +2,198 @@ package books
 
 import (
 	"context"
-	"errors"
 	"strings"
+	"time"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrBookNotFound    = apperr.NotFound("book_not_found", "libro no encontrado")
+	ErrBookOnLoan      = apperr.Conflict("book_on_loan", "el libro tiene ejemplares en préstamo")
+	ErrBookHasHistory  = apperr.Conflict("book_has_history", "el libro tiene ventas o préstamos registrados")
+	ErrStockFromCopies = apperr.Conflict("stock_from_copies", "el stock de este libro se calcula desde sus ejemplares")
+	ErrVersionMismatch = apperr.New(apperr.KindPreconditionFailed, "version_mismatch", "el libro cambió desde que se leyó; vuelve a obtenerlo")
+	ErrIfMatchRequired = apperr.New(apperr.KindPreconditionRequired, "if_match_required", "se requiere el encabezado If-Match con el ETag del libro")
+	ErrInvalidBook     = apperr.Validation("invalid_book", "libro inválido")
 )
 
+// invalidBook es ErrInvalidBook con el detalle del campo que no cumple
+func invalidBook(field, message string) error {
+	return apperr.Validation(ErrInvalidBook.Code, message).
+		WithFields(apperr.FieldError{Field: field, Code: "invalid", Message: message})
+}
+
 type Book struct {
-    ID              int64
-    BookName        string
-    BookCategory    string
-    TransactionType string
-    Price           int64
-    Status          bool
-    PopularityScore int64
+    ID               int64
+    BookName         string
+    BookCategory     string
+    TransactionType  string // "venta" | "arriendo" | "ambos"
+    Price            int64  // precio de venta; en los libros solo de arriendo, el del arriendo
+    RentalPrice      *int64 // precio de arriendo por período en los libros "ambos"
+    RentalPeriodDays int64  // duración del período de arriendo
+    Status           bool
+    PopularityScore  int64      // calculado por el servidor a partir de ventas y préstamos
+    ArchivedAt       *time.Time // nil si el libro está en el catálogo
+    RatingAverage    float64    // promedio de estrellas de las reseñas (0 sin reseñas)
+    ReviewCount      int64
+    EffectivePrice   int64  // precio con la promoción vigente; lo calcula el servicio
+    PromotionID      *int64 // promoción aplicada en EffectivePrice, si hay
+    ReorderThreshold int64  // bajo este stock se levanta una alerta; 0 la desactiva
+    CoverUpdatedAt   *time.Time // nil si el libro no tiene portada
+    Version          int64      // sube con cada escritura del libro o su stock; se expone como ETag
 }
 
 type BookWithInventory struct {
     Book              *Book
-    AvailableQuantity int64
+    AvailableQuantity int64 // stock total disponible
+    RentalQuantity    int64 // parte de AvailableQuantity asignada a arriendo
+}
+
+// CatalogFilter restringe el listado y la exportación del catálogo
+type CatalogFilter struct {
+    OnlyAvailable bool   // solo libros con stock; si hay Mode, stock en esa modalidad
+    Mode          string // "venta" | "arriendo": libros que se ofrecen así; "" = todos
 }
 
 type CreateBookInput struct {
-    BookName        string `json:"book_name" binding:"required"`
-    BookCategory    string `json:"book_category" binding:"required"`
-    TransactionType string `json:"transaction_type" binding:"required"` // "venta" | "arriendo"
-    Price           int64  `json:"price" binding:"required"`
-    Status          bool   `json:"status"`              // si decides mantenerlo en DB
-    PopularityScore int64  `json:"popularity_score"`    // opcional
-    Stock           int64  `json:"stock"`               // inicial inventario
+    BookName         string `json:"book_name" binding:"required"`
+    BookCategory     string `json:"book_category" binding:"required"`
+    TransactionType  string `json:"transaction_type" binding:"required"` // "venta" | "arriendo" | "ambos"
+    Price            int64  `json:"price" binding:"required"`
+    RentalPrice      *int64 `json:"rental_price"`        // obligatorio en "ambos"
+    RentalPeriodDays int64  `json:"rental_period_days"`  // por defecto, business.loan_days
+    Status           bool   `json:"status"`              // si decides mantenerlo en DB
+    Stock            int64  `json:"stock"`               // inicial inventario
+    RentalStock      int64  `json:"rental_stock"`        // en "ambos", parte de stock para arriendo
+    ReorderThreshold int64  `json:"reorder_threshold"`   // 0 = sin alertas de stock bajo
 }
 
 type UpdateBookInput struct {
-    BookName        *string `json:"book_name"`
-    BookCategory    *string `json:"book_category"`
-    TransactionType *string `json:"transaction_type"` // "venta" | "arriendo"
-    Price           *int64  `json:"price"`
-    Status          *bool   `json:"status"`
-    PopularityScore *int64  `json:"popularity_score"`
-    Stock           *int64  `json:"stock"`
+    BookName         *string `json:"book_name"`
+    BookCategory     *string `json:"book_category"`
+    TransactionType  *string `json:"transaction_type"` // "venta" | "arriendo" | "ambos"
+    Price            *int64  `json:"price"`
+    RentalPrice      *int64  `json:"rental_price"`
+    RentalPeriodDays *int64  `json:"rental_period_days"`
+    Status           *bool   `json:"status"`
+    Stock            *int64  `json:"stock"`
+    RentalStock      *int64  `json:"rental_stock"` // solo "ambos"; no puede superar el stock
+    ReorderThreshold *int64  `json:"reorder_threshold"`
 }
 
 type Service interface { // Interfaz del servicio de libros
-	ListBook(ctx context.Context, onlyAvailable *bool) ([]*BookWithInventory, error)                    // Lista todos los libros, opcionalmente filtrados por estado
+	ListBook(ctx context.Context, filter CatalogFilter) ([]*BookWithInventory, error)                   // Lista el catálogo, opcionalmente solo disponibles o de una modalidad
 	GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (*BookWithInventory, error)         // Obtiene un libro por su ID, opcionalmente filtrado por estado
 	CreateBook(ctx context.Context, input CreateBookInput) (int64, error)                        // Crea un nuevo libro
-	UpdateBook(ctx context.Context, id int64, input UpdateBookInput) (*BookWithInventory, error) // Actualiza un libro existente
+	UpdateBook(ctx context.Context, id, version int64, input UpdateBookInput) (*BookWithInventory, error) // Actualiza un libro si sigue en la versión indicada (0 = cualquiera)
+	ExportBooks(ctx context.Context, filter CatalogFilter, fn func(*BookWithInventory) error) error     // Recorre el catálogo completo fila por fila (exportación)
+	ArchiveBook(ctx context.Context, id int64) (*BookWithInventory, error)                              // Oculta un libro del catálogo conservando su historial
+	RestoreBook(ctx context.Context, id int64) (*BookWithInventory, error)                              // Devuelve un libro archivado al catálogo
+	DeleteBook(ctx context.Context, id int64) error                                                     // Borra un libro sin ventas ni préstamos
+	ListPopular(ctx context.Context, window PopularityWindow, category string, limit int) ([]*BookWithInventory, error) // Libros más populares en una ventana de tiempo
+	RecomputePopularity(ctx context.Context) error                                                      // Recalcula y guarda popularity_score de todo el catálogo
+	ListRelated(ctx context.Context, bookID int64, limit int) ([]*BookWithInventory, error)             // "Quienes leyeron esto también compraron..."
+	ListRecommendations(ctx context.Context, userID int64, limit int) ([]*BookWithInventory, error)     // Recomendaciones por afinidad de categoría del usuario
+	ListPriceHistory(ctx context.Context, bookID int64) ([]*PriceChange, error)                         // Cambios de precio de un libro, del más reciente al más antiguo
+	CreatePromotion(ctx context.Context, input CreatePromotionInput) (*Promotion, error)               // Programa un descuento para un libro o una categoría
+	ListPromotions(ctx context.Context, onlyActive bool) ([]*Promotion, error)                          // Lista las promociones, opcionalmente solo las vigentes
+	DeletePromotion(ctx context.Context, id int64) error                                                // Cancela una promoción
+	AdjustInventory(ctx context.Context, bookID, userID int64, input AdjustInventoryInput) (*InventoryAdjustment, error) // Suma o resta stock dejando registro del motivo
+	ListInventoryAdjustments(ctx context.Context, bookID int64) ([]*InventoryAdjustment, error)         // Bitácora de ajustes de stock de un libro
+	CheckLowStock(ctx context.Context) error                                                            // Levanta o resuelve alertas según el umbral de cada libro
+	ListStockAlerts(ctx context.Context, onlyOpen bool) ([]*StockAlert, error)                          // Alertas de stock bajo con sugerencia de reposición
 }
 
 type Repository interface {
-    ListBook(ctx context.Context, onlyAvailable *bool) ([]BookWithInventory, error)
+    ListBook(ctx context.Context, filter CatalogFilter) ([]BookWithInventory, error)
     GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (BookWithInventory, error)
-    CreateBook(ctx context.Context, book *Book, initialStock int64) (int64, error)
-    UpdateBook(ctx context.Context, book *Book, stock *int64) error
+    CreateBook(ctx context.Context, book *Book, initialStock, rentalStock int64) (int64, error)
+    UpdateBook(ctx context.Context, book *Book, stock, rentalStock *int64) error
+    StreamBooks(ctx context.Context, filter CatalogFilter, fn func(BookWithInventory) error) error
+    ArchiveBook(ctx context.Context, id int64) error
+    RestoreBook(ctx context.Context, id int64) error
+    DeleteBook(ctx context.Context, id int64) error
+    ListActivity(ctx context.Context, since time.Time) ([]Activity, error)
+    ListPopular(ctx context.Context, scores map[int64]int64, category string, limit int) ([]BookWithInventory, error)
+    UpdatePopularityScores(ctx context.Context, scores map[int64]int64) error
+    ListRelated(ctx context.Context, bookID int64, limit int) ([]BookWithInventory, error)
+    ListRecommendations(ctx context.Context, userID int64, limit int) ([]BookWithInventory, error)
+    ListPriceHistory(ctx context.Context, bookID int64) ([]PriceChange, error)
+    CreatePromotion(ctx context.Context, p *Promotion) (int64, error)
+    ListPromotions(ctx context.Context, at time.Time, onlyActive bool) ([]Promotion, error)
+    DeletePromotion(ctx context.Context, id int64) error
+    AdjustInventory(ctx context.Context, adj *InventoryAdjustment) error
+    ListInventoryAdjustments(ctx context.Context, bookID int64) ([]InventoryAdjustment, error)
+    SyncStockAlerts(ctx context.Context, low []StockAlert, at time.Time) error
+    ListStockAlerts(ctx context.Context, onlyOpen bool) ([]StockAlert, error)
 }
 
 type service struct { // Implementación del servicio de libros
-	repo Repository // Repositorio para la gestión de libros
+	repo     Repository // Repositorio para la gestión de libros
+	loanDays int64      // Período de arriendo de los libros que no indican uno
 }
 
-func NewService(repo Repository) Service { // Constructor para crear un nuevo servicio de libros
-	return &service{repo: repo} // Retorna una instancia del servicio con el repositorio inyectado
+func NewService(repo Repository, loanDays int64) Service { // Constructor para crear un nuevo servicio de libros
+	return &service{repo: repo, loanDays: loanDays} // Retorna una instancia del servicio con el repositorio inyectado
 }
 
-func (s *service) ListBook(ctx context.Context, onlyAvailable *bool) ([]*BookWithInventory, error) {
-	books, err := s.repo.ListBook(ctx, onlyAvailable)
+func (s *service) ListBook(ctx context.Context, filter CatalogFilter) ([]*BookWithInventory, error) {
+	books, err := s.repo.ListBook(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+	return s.pricedBooks(ctx, books)
+}
+
+func (s *service) ExportBooks(ctx context.Context, filter CatalogFilter, fn func(*BookWithInventory) error) error {
+	promos, err := s.repo.ListPromotions(ctx, time.Now(), true)
+	if err != nil {
+		return err
+	}
+	return s.repo.StreamBooks(ctx, filter, func(bwi BookWithInventory) error {
+		priceWith(bwi.Book, promos)
+		return fn(&bwi)
+	})
+}
+
+func toBookPointers(books []BookWithInventory) []*BookWithInventory {
 	result := make([]*BookWithInventory, len(books))
 	for i := range books {
 		result[i] = &books[i]
 	}
-	return result, nil
+	return result
+}
+
+func (s *service) pricedBooks(ctx context.Context, books []BookWithInventory) ([]*BookWithInventory, error) {
+	out := toBookPointers(books)
+	if err := s.applyPricing(ctx, out...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *service) ListRelated(ctx context.Context, bookID int64, limit int) ([]*BookWithInventory, error) {
+	book, err := s.repo.GetBookByID(ctx, bookID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if book.Book == nil {
+		return nil, ErrBookNotFound
+	}
+	related, err := s.repo.ListRelated(ctx, bookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.pricedBooks(ctx, related)
+}
+
+func (s *service) ListRecommendations(ctx context.Context, userID int64, limit int) ([]*BookWithInventory, error) {
+	recs, err := s.repo.ListRecommendations(ctx, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.pricedBooks(ctx, recs)
 }
 
 func (s *service) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (*BookWithInventory, error) {
@@ -80,34 +201,54 @@ func (s *service) GetBookByID(ctx context.Context, id int64, onlyAvailable *bool
 	if err != nil {
 		return nil, err
 	}
+	if bookWithInventory.Book == nil {
+		return nil, ErrBookNotFound
+	}
+	if err := s.applyPricing(ctx, &bookWithInventory); err != nil {
+		return nil, err
+	}
 	return &bookWithInventory, nil
 }
 
 func (s *service) CreateBook(ctx context.Context, input CreateBookInput) (int64, error) {
-	tt := strings.ToLower(input.TransactionType)
-	if tt != "venta" && tt != "arriendo" {
-		return 0, errors.New("el tipo de transacción debe ser 'venta' o 'arriendo'") // Valida que el tipo de transacción sea válido
+	if strings.TrimSpace(input.BookName) == "" {
+		return 0, invalidBook("book_name", "se necesita el nombre del libro") // Valida que el nombre del libro no esté vacío
 	}
 
-	if input.Price < 0 {
-		return 0, errors.New("el precio debe ser un valor positivo") // Valida que el precio sea un valor positivo
+	if input.Stock < 0 {
+		return 0, invalidBook("stock", "el stock no puede ser negativo")
 	}
-
-	if strings.TrimSpace(input.BookName) == "" {
-		return 0, errors.New("se necesita el nombre del libro") // Valida que el nombre del libro no esté vacío
+	if input.ReorderThreshold < 0 {
+		return 0, invalidBook("reorder_threshold", "el umbral de reposición no puede ser negativo")
 	}
 
 	book := &Book{
-		BookName:        input.BookName,
-		BookCategory:    input.BookCategory,
-		TransactionType: tt,
-		Price:           input.Price,
-		Status:          input.Status,
-		PopularityScore: input.PopularityScore,
+		BookName:         input.BookName,
+		BookCategory:     input.BookCategory,
+		TransactionType:  normMode(input.TransactionType),
+		Price:            input.Price,
+		RentalPrice:      input.RentalPrice,
+		RentalPeriodDays: input.RentalPeriodDays,
+		Status:           input.Status,
+		ReorderThreshold: input.ReorderThreshold,
+	}
+	if book.RentalPeriodDays == 0 {
+		book.RentalPeriodDays = s.loanDays
+	}
+	// Valida tipo de transacción, precios y período de arriendo
+	if err := validateModes(book); err != nil {
+		return 0, err
+	}
+	if book.TransactionType != ModeBoth {
+		book.RentalPrice = nil
+		input.RentalStock = 0
+	}
+	if input.RentalStock < 0 || input.RentalStock > input.Stock {
+		return 0, invalidBook("rental_stock", "el stock de arriendo debe estar entre 0 y stock")
 	}
 
 	// Crear el libro en la base de datos
-	id, err := s.repo.CreateBook(ctx, book, input.Stock)
+	id, err := s.repo.CreateBook(ctx, book, input.Stock, input.RentalStock)
 	if err != nil {
 		return 0, err
 	}
@@ -116,11 +257,17 @@ func (s *service) CreateBook(ctx context.Context, input CreateBookInput) (int64,
 	return id, nil
 }
 
-func (s *service) UpdateBook(ctx context.Context, id int64, input UpdateBookInput) (*BookWithInventory, error) {
+func (s *service) UpdateBook(ctx context.Context, id, version int64, input UpdateBookInput) (*BookWithInventory, error) {
 	book, err := s.repo.GetBookByID(ctx, id, nil)
 	if err != nil {
 		return nil, err
 	}
+	if book.Book == nil {
+		return nil, ErrBookNotFound
+	}
+	if version != 0 && book.Book.Version != version {
+		return nil, ErrVersionMismatch
+	}
 
 	if input.BookName != nil {
 		book.Book.BookName = *input.BookName
@@ -128,23 +275,82 @@ func (s *service) UpdateBook(ctx context.Context, id int64, input UpdateBookInpu
 	if input.BookCategory != nil {
 		book.Book.BookCategory = *input.BookCategory
 	}
+	prevMode := book.Book.TransactionType
 	if input.TransactionType != nil {
-		book.Book.TransactionType = *input.TransactionType
+		book.Book.TransactionType = normMode(*input.TransactionType)
 	}
 	if input.Price != nil {
 		book.Book.Price = *input.Price
 	}
+	if input.RentalPrice != nil {
+		book.Book.RentalPrice = input.RentalPrice
+	}
+	if input.RentalPeriodDays != nil {
+		book.Book.RentalPeriodDays = *input.RentalPeriodDays
+	}
+	if err := validateModes(book.Book); err != nil {
+		return nil, err
+	}
+	if book.Book.TransactionType != ModeBoth {
+		book.Book.RentalPrice = nil
+	}
+
+	if input.Stock != nil && *input.Stock < 0 {
+		return nil, invalidBook("stock", "el stock no puede ser negativo")
+	}
+
+	// La asignación de stock a arriendo solo existe en los libros "ambos". Al
+	// pasar un libro de arriendo a "ambos" su stock sigue siendo de arriendo.
+	rentalStock := input.RentalStock
+	if rentalStock != nil && book.Book.TransactionType != ModeBoth {
+		return nil, invalidBook("rental_stock", "solo los libros en venta y arriendo asignan stock de arriendo")
+	}
+	if rentalStock != nil && *rentalStock < 0 {
+		return nil, invalidBook("rental_stock", "el stock de arriendo no puede ser negativo")
+	}
+	if rentalStock == nil && prevMode != ModeBoth && book.Book.TransactionType == ModeBoth {
+		all := book.AvailableQuantity
+		if input.Stock != nil {
+			all = *input.Stock
+		}
+		if prevMode != ModeRental {
+			all = 0
+		}
+		rentalStock = &all
+	}
 	if input.Status != nil {
 		book.Book.Status = *input.Status
 	}
-	if input.PopularityScore != nil {
-		book.Book.PopularityScore = *input.PopularityScore
+	if input.ReorderThreshold != nil {
+		if *input.ReorderThreshold < 0 {
+			return nil, invalidBook("reorder_threshold", "el umbral de reposición no puede ser negativo")
+		}
+		book.Book.ReorderThreshold = *input.ReorderThreshold
 	}
 
-	err = s.repo.UpdateBook(ctx, book.Book, input.Stock)
+	err = s.repo.UpdateBook(ctx, book.Book, input.Stock, rentalStock)
 	if err != nil {
 		return nil, err
 	}
 
-	return &book, nil
+	// Se relee para entregar el stock ya actualizado
+	return s.GetBookByID(ctx, id, nil)
+}
+
+func (s *service) ArchiveBook(ctx context.Context, id int64) (*BookWithInventory, error) {
+	if err := s.repo.ArchiveBook(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.GetBookByID(ctx, id, nil)
+}
+
+func (s *service) RestoreBook(ctx context.Context, id int64) (*BookWithInventory, error) {
+	if err := s.repo.RestoreBook(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.GetBookByID(ctx, id, nil)
+}
+
+func (s *service) DeleteBook(ctx context.Context, id int64) error {
+	return s.repo.DeleteBook(ctx, id)
 }
@@ -0,0 +1,260 @@
+package books_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"uzm-server/internal/apperr"
+	"uzm-server/internal/books"
+	"uzm-server/internal/memstore"
+)
+
+const loanDays = 14
+
+func ptr[T any](v T) *T { return &v }
+
+func newService() books.Service {
+	return books.NewService(books.NewMemoryRepository(memstore.New()), loanDays)
+}
+
+// invalidField devuelve el campo que marca un error invalid_book
+func invalidField(t *testing.T, err error) string {
+	t.Helper()
+	e, ok := apperr.As(err)
+	if !ok || !errors.Is(err, books.ErrInvalidBook) || len(e.Fields) != 1 {
+		t.Fatalf("error = %v, quiero invalid_book con un campo", err)
+	}
+	return e.Fields[0].Field
+}
+
+func TestServiceCreateBookValidation(t *testing.T) {
+	valid := func(edit func(*books.CreateBookInput)) books.CreateBookInput {
+		in := books.CreateBookInput{BookName: "Rayuela", BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, Stock: 5}
+		edit(&in)
+		return in
+	}
+
+	tests := []struct {
+		name  string
+		input books.CreateBookInput
+		field string // campo rechazado; vacío si debe crearse
+
+		mode         string
+		periodDays   int64
+		rentalPrice  *int64
+		rentalStock  int64 // en los libros solo de arriendo es todo el stock
+		availability int64
+	}{
+		{name: "Sale", input: valid(func(in *books.CreateBookInput) {}),
+			mode: books.ModeSale, periodDays: loanDays, availability: 5},
+		{name: "ModeIsNormalized", input: valid(func(in *books.CreateBookInput) { in.TransactionType = " Arriendo " }),
+			mode: books.ModeRental, periodDays: loanDays, rentalStock: 5, availability: 5},
+		{name: "ExplicitPeriod", input: valid(func(in *books.CreateBookInput) { in.RentalPeriodDays = 7 }),
+			mode: books.ModeSale, periodDays: 7, availability: 5},
+		{name: "Both", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice, in.RentalStock = books.ModeBoth, ptr(int64(300)), 2
+		}), mode: books.ModeBoth, periodDays: loanDays, rentalPrice: ptr(int64(300)), rentalStock: 2, availability: 5},
+		{name: "BothAllForRent", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice, in.RentalStock = books.ModeBoth, ptr(int64(300)), 5
+		}), mode: books.ModeBoth, periodDays: loanDays, rentalPrice: ptr(int64(300)), rentalStock: 5, availability: 5},
+		{name: "RentalFieldsIgnoredOutsideBoth", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice, in.RentalStock = books.ModeRental, ptr(int64(300)), 9
+		}), mode: books.ModeRental, periodDays: loanDays, rentalStock: 5, availability: 5},
+		{name: "FreeBook", input: valid(func(in *books.CreateBookInput) { in.Price = 0 }),
+			mode: books.ModeSale, periodDays: loanDays, availability: 5},
+
+		{name: "EmptyName", input: valid(func(in *books.CreateBookInput) { in.BookName = "" }), field: "book_name"},
+		{name: "BlankName", input: valid(func(in *books.CreateBookInput) { in.BookName = "  \t" }), field: "book_name"},
+		{name: "NegativeThreshold", input: valid(func(in *books.CreateBookInput) { in.ReorderThreshold = -1 }), field: "reorder_threshold"},
+		{name: "NegativeStock", input: valid(func(in *books.CreateBookInput) { in.Stock = -3 }), field: "stock"},
+		{name: "UnknownMode", input: valid(func(in *books.CreateBookInput) { in.TransactionType = "trueque" }), field: "transaction_type"},
+		{name: "EmptyMode", input: valid(func(in *books.CreateBookInput) { in.TransactionType = "" }), field: "transaction_type"},
+		{name: "NegativePrice", input: valid(func(in *books.CreateBookInput) { in.Price = -1 }), field: "price"},
+		{name: "BothWithoutRentalPrice", input: valid(func(in *books.CreateBookInput) { in.TransactionType = books.ModeBoth }), field: "rental_price"},
+		{name: "BothWithZeroRentalPrice", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice = books.ModeBoth, ptr(int64(0))
+		}), field: "rental_price"},
+		{name: "NegativePeriod", input: valid(func(in *books.CreateBookInput) { in.RentalPeriodDays = -3 }), field: "rental_period_days"},
+		{name: "RentalStockAboveStock", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice, in.RentalStock = books.ModeBoth, ptr(int64(300)), 6
+		}), field: "rental_stock"},
+		{name: "NegativeRentalStock", input: valid(func(in *books.CreateBookInput) {
+			in.TransactionType, in.RentalPrice, in.RentalStock = books.ModeBoth, ptr(int64(300)), -1
+		}), field: "rental_stock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := newService()
+			id, err := svc.CreateBook(ctx, tt.input)
+			if tt.field != "" {
+				if got := invalidField(t, err); got != tt.field {
+					t.Fatalf("campo rechazado %q, quiero %q", got, tt.field)
+				}
+				if list, _ := svc.ListBook(ctx, books.CatalogFilter{}); len(list) != 0 {
+					t.Fatalf("se guardó un libro inválido: %+v", list[0].Book)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := svc.GetBookByID(ctx, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := got.Book
+			if b.TransactionType != tt.mode || b.RentalPeriodDays != tt.periodDays {
+				t.Fatalf("modalidad %q y período %d, quiero %q y %d", b.TransactionType, b.RentalPeriodDays, tt.mode, tt.periodDays)
+			}
+			if !equalPtr(b.RentalPrice, tt.rentalPrice) {
+				t.Fatalf("precio de arriendo %v, quiero %v", deref(b.RentalPrice), deref(tt.rentalPrice))
+			}
+			if got.RentalQuantity != tt.rentalStock || got.AvailableQuantity != tt.availability {
+				t.Fatalf("stock %d (arriendo %d), quiero %d (arriendo %d)",
+					got.AvailableQuantity, got.RentalQuantity, tt.availability, tt.rentalStock)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateBookValidation(t *testing.T) {
+	sale := books.CreateBookInput{BookName: "Rayuela", BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, Stock: 4}
+	rental := books.CreateBookInput{BookName: "Ficciones", BookCategory: "Cuento", TransactionType: books.ModeRental, Price: 200, Stock: 4}
+	both := books.CreateBookInput{BookName: "Aleph", BookCategory: "Cuento", TransactionType: books.ModeBoth, Price: 1000,
+		RentalPrice: ptr(int64(300)), Stock: 4, RentalStock: 1}
+
+	tests := []struct {
+		name    string
+		initial books.CreateBookInput
+		input   books.UpdateBookInput
+		stale   bool  // otro cliente edita el libro antes de este cambio
+		err     error // error esperado fuera de invalid_book
+		field   string
+
+		mode        string
+		rentalPrice *int64
+		stock       int64
+		rentalStock int64
+	}{
+		{name: "Rename", initial: sale, input: books.UpdateBookInput{BookName: ptr("Rayuela (2da ed.)")},
+			mode: books.ModeSale, stock: 4},
+		{name: "Stale", initial: sale, input: books.UpdateBookInput{BookName: ptr("Otro")}, stale: true, err: books.ErrVersionMismatch},
+
+		{name: "UnknownMode", initial: sale, input: books.UpdateBookInput{TransactionType: ptr("trueque")}, field: "transaction_type"},
+		{name: "NegativePrice", initial: sale, input: books.UpdateBookInput{Price: ptr(int64(-5))}, field: "price"},
+		{name: "ZeroPeriod", initial: sale, input: books.UpdateBookInput{RentalPeriodDays: ptr(int64(0))}, field: "rental_period_days"},
+		{name: "NegativeThreshold", initial: sale, input: books.UpdateBookInput{ReorderThreshold: ptr(int64(-1))}, field: "reorder_threshold"},
+		{name: "NegativeStock", initial: sale, input: books.UpdateBookInput{Stock: ptr(int64(-3))}, field: "stock"},
+		{name: "RentalNegativeStock", initial: rental, input: books.UpdateBookInput{Stock: ptr(int64(-3))}, field: "stock"},
+		{name: "RentalStockOutsideBoth", initial: sale, input: books.UpdateBookInput{RentalStock: ptr(int64(1))}, field: "rental_stock"},
+
+		{name: "SaleToBothNeedsRentalPrice", initial: sale, input: books.UpdateBookInput{TransactionType: ptr(books.ModeBoth)}, field: "rental_price"},
+		{name: "SaleToBothKeepsStockForSale", initial: sale,
+			input: books.UpdateBookInput{TransactionType: ptr("AMBOS"), RentalPrice: ptr(int64(300))},
+			mode:  books.ModeBoth, rentalPrice: ptr(int64(300)), stock: 4, rentalStock: 0},
+		{name: "RentalToBothKeepsStockForRent", initial: rental,
+			input: books.UpdateBookInput{TransactionType: ptr(books.ModeBoth), RentalPrice: ptr(int64(300))},
+			mode:  books.ModeBoth, rentalPrice: ptr(int64(300)), stock: 4, rentalStock: 4},
+		{name: "RentalToBothWithNewStock", initial: rental,
+			input: books.UpdateBookInput{TransactionType: ptr(books.ModeBoth), RentalPrice: ptr(int64(300)), Stock: ptr(int64(6))},
+			mode:  books.ModeBoth, rentalPrice: ptr(int64(300)), stock: 6, rentalStock: 6},
+		{name: "BothToSaleClearsRentalPrice", initial: both, input: books.UpdateBookInput{TransactionType: ptr(books.ModeSale)},
+			mode: books.ModeSale, stock: 4, rentalStock: 0},
+
+		{name: "BothRentalStock", initial: both, input: books.UpdateBookInput{RentalStock: ptr(int64(3))},
+			mode: books.ModeBoth, rentalPrice: ptr(int64(300)), stock: 4, rentalStock: 3},
+		{name: "BothNegativeStock", initial: both, input: books.UpdateBookInput{Stock: ptr(int64(-3))}, field: "stock"},
+		{name: "BothNegativeRentalStock", initial: both, input: books.UpdateBookInput{RentalStock: ptr(int64(-1))}, field: "rental_stock"},
+		{name: "BothRentalStockAboveStock", initial: both, input: books.UpdateBookInput{RentalStock: ptr(int64(5))}, field: "rental_stock"},
+		{name: "BothZeroRentalPrice", initial: both, input: books.UpdateBookInput{RentalPrice: ptr(int64(0))}, field: "rental_price"},
+		{name: "BothShrinkStockClampsRental", initial: both, input: books.UpdateBookInput{Stock: ptr(int64(0))},
+			mode: books.ModeBoth, rentalPrice: ptr(int64(300)), stock: 0, rentalStock: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			svc := newService()
+			id, err := svc.CreateBook(ctx, tt.initial)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, err := svc.GetBookByID(ctx, id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			version := before.Book.Version
+			if tt.stale {
+				if before, err = svc.UpdateBook(ctx, id, version, books.UpdateBookInput{Price: ptr(int64(1200))}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := svc.UpdateBook(ctx, id, version, tt.input)
+			switch {
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, quiero %v", err, tt.err)
+				}
+			case tt.field != "":
+				if f := invalidField(t, err); f != tt.field {
+					t.Fatalf("campo rechazado %q, quiero %q", f, tt.field)
+				}
+			}
+			if tt.err != nil || tt.field != "" {
+				after, err := svc.GetBookByID(ctx, id, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if after.Book.Version != before.Book.Version {
+					t.Fatalf("un cambio rechazado movió la versión de %d a %d", before.Book.Version, after.Book.Version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b := got.Book
+			if b.Version != before.Book.Version+1 {
+				t.Fatalf("versión %d, quiero %d", b.Version, before.Book.Version+1)
+			}
+			if tt.input.BookName != nil && b.BookName != *tt.input.BookName {
+				t.Fatalf("nombre %q, quiero %q", b.BookName, *tt.input.BookName)
+			}
+			if b.TransactionType != tt.mode || !equalPtr(b.RentalPrice, tt.rentalPrice) {
+				t.Fatalf("modalidad %q y precio de arriendo %v, quiero %q y %v",
+					b.TransactionType, deref(b.RentalPrice), tt.mode, deref(tt.rentalPrice))
+			}
+			if got.AvailableQuantity != tt.stock || got.RentalQuantity != tt.rentalStock {
+				t.Fatalf("stock %d (arriendo %d), quiero %d (arriendo %d)",
+					got.AvailableQuantity, got.RentalQuantity, tt.stock, tt.rentalStock)
+			}
+		})
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := newService().UpdateBook(context.Background(), 99, 0, books.UpdateBookInput{BookName: ptr("x")})
+		if !errors.Is(err, books.ErrBookNotFound) {
+			t.Fatalf("error = %v, quiero book_not_found", err)
+		}
+	})
+}
+
+func equalPtr(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deref(p *int64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,189 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"uzm-server/internal/config"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, printConfig, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printConfig {
+		t.Fatal("printConfig sin --print-config")
+	}
+	want := config.Default()
+	if cfg.Server.Addr != want.Server.Addr || cfg.Database.DSN != want.Database.DSN || cfg.Business.LoanDays != want.Business.LoanDays {
+		t.Fatalf("Load(nil) = %+v, quiero los valores por defecto", cfg)
+	}
+}
+
+// Cada capa pisa a la anterior: defecto < archivo < entorno < banderas
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "uzm.yaml", `
+server:
+  addr: ":7000"
+  mode: release
+  write_timeout: 45s
+database:
+  dsn: ./archivo.db
+business:
+  loan_days: 21
+  late_fee_per_day: 50
+cors:
+  origins: ["http://archivo.cl"]
+`)
+	t.Setenv("UZM_CONFIG", path)
+	t.Setenv("UZM_DB_DSN", "./entorno.db")
+	t.Setenv("UZM_LOAN_DAYS", "30")
+	t.Setenv("UZM_CORS_ORIGINS", "http://entorno.cl, http://otro.cl")
+
+	cfg, _, err := config.Load([]string{"--loan-days", "7", "--addr=:9000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"server.addr (bandera)", cfg.Server.Addr, ":9000"},
+		{"server.mode (archivo)", cfg.Server.Mode, "release"},
+		{"server.write_timeout (archivo)", time.Duration(cfg.Server.WriteTimeout), 45 * time.Second},
+		{"server.read_timeout (defecto)", time.Duration(cfg.Server.ReadTimeout), 30 * time.Second},
+		{"database.dsn (entorno)", cfg.Database.DSN, "./entorno.db"},
+		{"business.loan_days (bandera)", cfg.Business.LoanDays, int64(7)},
+		{"business.late_fee_per_day (archivo)", cfg.Business.LateFeePerDay, int64(50)},
+		{"cors.origins (entorno)", strings.Join(cfg.CORS.Origins, ","), "http://entorno.cl,http://otro.cl"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, quiero %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+// --config tiene prioridad sobre UZM_CONFIG, y también se leen archivos TOML
+func TestLoadTOMLFlagOverridesEnvPath(t *testing.T) {
+	t.Setenv("UZM_CONFIG", writeFile(t, "env.yaml", "server:\n  addr: \":7000\"\n"))
+	path := writeFile(t, "uzm.toml", `
+[server]
+addr = ":7100"
+
+[business]
+popularity_interval = "1m"
+`)
+	cfg, _, err := config.Load([]string{"--config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":7100" || time.Duration(cfg.Business.PopularityInterval) != time.Minute {
+		t.Fatalf("Load(--config uzm.toml) = addr %q, popularity_interval %v", cfg.Server.Addr, cfg.Business.PopularityInterval)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // contenido de uzm.yaml; vacío = sin archivo
+		env  map[string]string
+		args []string
+		want []string // fragmentos del error
+	}{
+		{name: "UnknownField", file: "server:\n  port: 8080\n", want: []string{"port"}},
+		{name: "BadDuration", file: "server:\n  read_timeout: pronto\n", want: []string{"duración inválida"}},
+		{name: "BadEnv", env: map[string]string{"UZM_LOAN_DAYS": "muchos"}, want: []string{"UZM_LOAN_DAYS"}},
+		{name: "UnknownFlag", args: []string{"--puerto", "80"}, want: []string{"puerto"}},
+		{name: "MissingFile", args: []string{"--config", "no-existe.yaml"}, want: []string{"no-existe.yaml"}},
+		{
+			name: "Validation",
+			args: []string{"--gin-mode", "turbo", "--loan-days", "0", "--late-fee-per-day", "-1", "--write-timeout", "-1s",
+				"--cors-origins", "localhost:3000", "--log-format", "xml"},
+			// Se informan todos los problemas juntos
+			want: []string{"server.mode", "business.loan_days", "business.late_fee_per_day", "server.write_timeout",
+				"cors.origins", "log.format"},
+		},
+		{name: "EmptyDSN", args: []string{"--db-dsn", " "}, want: []string{"database.dsn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeFile(t, "uzm.yaml", tt.file)}, args...)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, _, err := config.Load(args)
+			if err == nil {
+				t.Fatal("Load no devolvió error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("el error %q no menciona %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+// --print-config escribe un YAML que se puede volver a cargar, sin la
+// contraseña de la base de datos
+func TestPrint(t *testing.T) {
+	cfg, printConfig, err := config.Load([]string{"--print-config", "--db-dsn", "postgres://uzm:s3cr3t@db:5432/uzm", "--late-fee-per-day", "25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printConfig {
+		t.Fatal("printConfig = false con --print-config")
+	}
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cr3t") || !strings.Contains(out.String(), "postgres://uzm:xxxxx@db:5432/uzm") {
+		t.Fatalf("Print mostró la contraseña o perdió el DSN:\n%s", out.String())
+	}
+	if cfg.Database.DSN != "postgres://uzm:s3cr3t@db:5432/uzm" {
+		t.Fatalf("Print cambió la configuración: dsn %q", cfg.Database.DSN)
+	}
+
+	reloaded, _, err := config.Load([]string{"--config", writeFile(t, "impresa.yaml", out.String())})
+	if err != nil {
+		t.Fatalf("cargando la configuración impresa: %v", err)
+	}
+	if reloaded.Business.LateFeePerDay != 25 || reloaded.Server.Addr != cfg.Server.Addr {
+		t.Fatalf("configuración recargada = %+v", reloaded)
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct{ dsn, want string }{
+		{"./uzm.db", "./uzm.db"},
+		{"file:uzm.db?_pragma=busy_timeout(5000)", "file:uzm.db?_pragma=busy_timeout(5000)"},
+		{"postgres://uzm:s3cr3t@db:5432/uzm?sslmode=disable", "postgres://uzm:xxxxx@db:5432/uzm?sslmode=disable"},
+		{"postgresql://uzm@db/uzm", "postgresql://uzm@db/uzm"},
+		{"host=db user=uzm password=s3cr3t dbname=uzm", "host=db user=uzm password=xxxxx dbname=uzm"},
+		{"host=db password='con espacio' dbname=uzm", "host=db password=xxxxx dbname=uzm"},
+	}
+	for _, tt := range tests {
+		if got := config.RedactDSN(tt.dsn); got != tt.want {
+			t.Errorf("RedactDSN(%q) = %q, quiero %q", tt.dsn, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Package copiestest tiene la prueba de contrato de copies.Repository: todas
+// las implementaciones deben pasarla igual, sea cual sea el motor que usen.
+package copiestest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/users"
+)
+
+// Repos son los repositorios de una misma base vacía: el que se prueba y los
+// que crean los libros y usuarios de los que dependen los ejemplares
+type Repos struct {
+	Copies copies.Repository
+	Books  books.Repository
+	Users  users.Repository
+}
+
+var at = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// RunRepositoryContract prueba una implementación de copies.Repository.
+// newRepos debe entregar repositorios vacíos en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepos func(t *testing.T) Repos) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		id := createCopy(t, r, bookID, "LIB-1")
+		if id <= 0 {
+			t.Fatalf("CreateCopy devolvió el id %d", id)
+		}
+
+		got, err := r.Copies.GetByBarcode(ctx, "LIB-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := copies.Copy{ID: id, BookID: bookID, Barcode: "LIB-1", Condition: copies.ConditionNew, Location: "A-1",
+			Status: copies.StatusAvailable, CreatedAt: at, UpdatedAt: at}
+		if got == nil || *got != want {
+			t.Fatalf("GetByBarcode = %+v, quiero %+v", got, want)
+		}
+		if c, err := r.Copies.GetByBarcode(ctx, "LIB-9"); err != nil || c != nil {
+			t.Fatalf("GetByBarcode(LIB-9) = %+v, %v; quiero nil, nil", c, err)
+		}
+
+		// Cada ejemplar disponible cuenta en el inventario del libro
+		stock(t, r, bookID, 1, 0)
+		if b := getBook(t, r, bookID); !b.Book.Status {
+			t.Fatal("el libro con un ejemplar disponible quedó sin stock")
+		}
+	})
+
+	t.Run("CreateErrors", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		createCopy(t, r, bookID, "LIB-1")
+
+		if _, err := r.Copies.CreateCopy(ctx, newCopy(999, "LIB-2")); !errors.Is(err, copies.ErrBookNotFound) {
+			t.Fatalf("CreateCopy de un libro inexistente = %v, quiero ErrBookNotFound", err)
+		}
+		if _, err := r.Copies.CreateCopy(ctx, newCopy(bookID, "LIB-1")); !errors.Is(err, copies.ErrBarcodeTaken) {
+			t.Fatalf("CreateCopy con código repetido = %v, quiero ErrBarcodeTaken", err)
+		}
+		stock(t, r, bookID, 1, 0)
+	})
+
+	t.Run("ListByBook", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		other := createBook(t, r, books.ModeSale)
+
+		list, err := r.Copies.ListByBook(ctx, bookID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list == nil || len(list) != 0 {
+			t.Fatalf("ListByBook de un libro sin ejemplares = %#v, quiero una lista vacía", list)
+		}
+
+		b := createCopy(t, r, bookID, "LIB-B")
+		a := createCopy(t, r, bookID, "LIB-A")
+		createCopy(t, r, other, "LIB-C")
+		list, err = r.Copies.ListByBook(ctx, bookID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].ID != b || list[1].ID != a {
+			t.Fatalf("ListByBook = %+v, quiero los ejemplares %d y %d en orden de creación", list, b, a)
+		}
+		if _, err := r.Copies.ListByBook(ctx, 999); !errors.Is(err, copies.ErrBookNotFound) {
+			t.Fatalf("ListByBook de un libro inexistente = %v, quiero ErrBookNotFound", err)
+		}
+	})
+
+	t.Run("UpdateSyncsInventory", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		createCopy(t, r, bookID, "LIB-1")
+		version := getBook(t, r, bookID).Book.Version
+
+		c := getCopy(t, r, "LIB-1")
+		c.Condition, c.Location, c.Status, c.UpdatedAt = copies.ConditionDamaged, "Bodega", copies.StatusRetired, at.Add(time.Hour)
+		if err := r.Copies.UpdateCopy(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		if got := getCopy(t, r, "LIB-1"); *got != *c {
+			t.Fatalf("GetByBarcode tras UpdateCopy = %+v, quiero %+v", got, c)
+		}
+		stock(t, r, bookID, 0, 0)
+		b := getBook(t, r, bookID)
+		if b.Book.Status || b.Book.Version <= version {
+			t.Fatalf("libro sin ejemplares disponibles: status %v, versión %d (antes %d)", b.Book.Status, b.Book.Version, version)
+		}
+	})
+
+	t.Run("Sale", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r)
+		bookID := createBook(t, r, books.ModeSale)
+		createCopy(t, r, bookID, "LIB-1")
+		createCopy(t, r, bookID, "LIB-2")
+		if err := r.Users.UpdateUserUSMPesos(ctx, userID, 1500); err != nil {
+			t.Fatal(err)
+		}
+
+		co := &copies.Checkout{Type: copies.CheckoutSale, Price: 1000}
+		if err := r.Copies.CheckOut(ctx, "LIB-1", co, userID, at); err != nil {
+			t.Fatal(err)
+		}
+		if co.TransactionID <= 0 || co.Copy == nil || co.Copy.Status != copies.StatusSold || co.ReturnDate != nil {
+			t.Fatalf("CheckOut de venta = %+v", co)
+		}
+		if got := getCopy(t, r, "LIB-1"); got.Status != copies.StatusSold || !got.UpdatedAt.Equal(at) {
+			t.Fatalf("ejemplar vendido = %+v", got)
+		}
+		stock(t, r, bookID, 1, 0)
+		balance(t, r, userID, 500)
+
+		// Sin saldo no hay venta, y el rechazo no toca el ejemplar ni el saldo
+		err := r.Copies.CheckOut(ctx, "LIB-2", &copies.Checkout{Type: copies.CheckoutSale, Price: 1000}, userID, at)
+		if !errors.Is(err, copies.ErrInsufficientFunds) {
+			t.Fatalf("vender sin saldo = %v, quiero ErrInsufficientFunds", err)
+		}
+		if got := getCopy(t, r, "LIB-2"); got.Status != copies.StatusAvailable {
+			t.Fatalf("una venta sin saldo cambió el ejemplar: %+v", got)
+		}
+		stock(t, r, bookID, 1, 0)
+		balance(t, r, userID, 500)
+
+		err = r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutSale}, userID, at)
+		if !errors.Is(err, copies.ErrCopyNotAvailable) {
+			t.Fatalf("vender un ejemplar vendido = %v, quiero ErrCopyNotAvailable", err)
+		}
+		err = r.Copies.CheckOut(ctx, "LIB-2", &copies.Checkout{Type: copies.CheckoutLoan}, userID, at)
+		if !errors.Is(err, copies.ErrWrongCheckout) {
+			t.Fatalf("prestar un libro solo de venta = %v, quiero ErrWrongCheckout", err)
+		}
+		if got := getCopy(t, r, "LIB-2"); got.Status != copies.StatusAvailable {
+			t.Fatalf("una salida rechazada cambió el ejemplar: %+v", got)
+		}
+		stock(t, r, bookID, 1, 0)
+	})
+
+	t.Run("LoanAndReturn", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r)
+		bookID := createBook(t, r, books.ModeRental)
+		createCopy(t, r, bookID, "LIB-1")
+
+		co := &copies.Checkout{Type: copies.CheckoutLoan}
+		if err := r.Copies.CheckOut(ctx, "LIB-1", co, userID, at); err != nil {
+			t.Fatal(err)
+		}
+		// Sin fecha de devolución se usa el período de arriendo del libro
+		if co.TransactionID <= 0 || co.ReturnDate == nil || !co.ReturnDate.Equal(at.AddDate(0, 0, 7)) {
+			t.Fatalf("CheckOut de préstamo = %+v, quiero devolución el %v", co, at.AddDate(0, 0, 7))
+		}
+		if got := getCopy(t, r, "LIB-1"); got.Status != copies.StatusOnLoan {
+			t.Fatalf("ejemplar prestado = %+v", got)
+		}
+		stock(t, r, bookID, 0, 0)
+
+		worn, shelf := copies.ConditionWorn, "B-2"
+		back := at.Add(48 * time.Hour)
+		ci, err := r.Copies.CheckIn(ctx, "LIB-1", &worn, &shelf, 100, back)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ci.LoanID != co.TransactionID || ci.DaysLate != 0 || ci.LateFee != 0 {
+			t.Fatalf("CheckIn a tiempo = %+v, quiero el préstamo %d sin multa", ci, co.TransactionID)
+		}
+		got := ci.Copy
+		if got.Status != copies.StatusAvailable || got.Condition != worn || got.Location != shelf || !got.UpdatedAt.Equal(back) {
+			t.Fatalf("CheckIn = %+v", got)
+		}
+		if stored := getCopy(t, r, "LIB-1"); *stored != *got {
+			t.Fatalf("GetByBarcode tras CheckIn = %+v, quiero %+v", stored, got)
+		}
+		stock(t, r, bookID, 1, 1)
+
+		if _, err := r.Copies.CheckIn(ctx, "LIB-1", nil, nil, 100, back); !errors.Is(err, copies.ErrCopyNotOnLoan) {
+			t.Fatalf("devolver un ejemplar disponible = %v, quiero ErrCopyNotOnLoan", err)
+		}
+		err = r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutSale}, userID, at)
+		if !errors.Is(err, copies.ErrWrongCheckout) {
+			t.Fatalf("vender un libro solo de arriendo = %v, quiero ErrWrongCheckout", err)
+		}
+
+		// Una fecha de devolución explícita se respeta
+		due := at.AddDate(0, 0, 3)
+		co = &copies.Checkout{Type: copies.CheckoutLoan, ReturnDate: &due}
+		if err := r.Copies.CheckOut(ctx, "LIB-1", co, userID, at); err != nil {
+			t.Fatal(err)
+		}
+		if !co.ReturnDate.Equal(due) {
+			t.Fatalf("ReturnDate = %v, quiero %v", co.ReturnDate, due)
+		}
+	})
+
+	t.Run("BothModesSplitStock", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r)
+		bookID := createBook(t, r, books.ModeBoth)
+		createCopy(t, r, bookID, "LIB-1")
+		createCopy(t, r, bookID, "LIB-2")
+		stock(t, r, bookID, 2, 0)
+
+		err := r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutLoan}, userID, at)
+		if !errors.Is(err, copies.ErrNoRentalStock) {
+			t.Fatalf("prestar sin stock de arriendo = %v, quiero ErrNoRentalStock", err)
+		}
+
+		// Los dos ejemplares pasan a arriendo: ya no queda nada para vender
+		b := getBook(t, r, bookID)
+		rental := int64(2)
+		if err := r.Books.UpdateBook(ctx, b.Book, nil, &rental); err != nil {
+			t.Fatal(err)
+		}
+		err = r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutSale}, userID, at)
+		if !errors.Is(err, copies.ErrNoSaleStock) {
+			t.Fatalf("vender con todo el stock en arriendo = %v, quiero ErrNoSaleStock", err)
+		}
+
+		if err := r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutLoan}, userID, at); err != nil {
+			t.Fatal(err)
+		}
+		stock(t, r, bookID, 1, 1)
+		if _, err := r.Copies.CheckIn(ctx, "LIB-1", nil, nil, 0, at); err != nil {
+			t.Fatal(err)
+		}
+		stock(t, r, bookID, 2, 2)
+	})
+
+	t.Run("LateCheckIn", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r)
+		bookID := createBook(t, r, books.ModeRental)
+		createCopy(t, r, bookID, "LIB-1")
+
+		due := at.AddDate(0, 0, 3)
+		co := &copies.Checkout{Type: copies.CheckoutLoan, ReturnDate: &due}
+		if err := r.Copies.CheckOut(ctx, "LIB-1", co, userID, at); err != nil {
+			t.Fatal(err)
+		}
+
+		// Un día y una hora de atraso cuentan como dos días; la multa puede
+		// dejar el saldo negativo
+		ci, err := r.Copies.CheckIn(ctx, "LIB-1", nil, nil, 100, due.Add(25*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ci.LoanID != co.TransactionID || ci.DaysLate != 2 || ci.LateFee != 200 || ci.Copy.Status != copies.StatusAvailable {
+			t.Fatalf("CheckIn atrasado = %+v, quiero 2 días y 200 de multa", ci)
+		}
+		balance(t, r, userID, -200)
+	})
+
+	t.Run("CheckOutErrors", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r)
+		bookID := createBook(t, r, books.ModeSale)
+		createCopy(t, r, bookID, "LIB-1")
+
+		err := r.Copies.CheckOut(ctx, "LIB-9", &copies.Checkout{Type: copies.CheckoutSale}, userID, at)
+		if !errors.Is(err, copies.ErrCopyNotFound) {
+			t.Fatalf("ejemplar inexistente = %v, quiero ErrCopyNotFound", err)
+		}
+		err = r.Copies.CheckOut(ctx, "LIB-1", &copies.Checkout{Type: copies.CheckoutSale}, 999, at)
+		if !errors.Is(err, copies.ErrUserNotFound) {
+			t.Fatalf("usuario inexistente = %v, quiero ErrUserNotFound", err)
+		}
+		if _, err := r.Copies.CheckIn(ctx, "LIB-9", nil, nil, 0, at); !errors.Is(err, copies.ErrCopyNotFound) {
+			t.Fatalf("devolver un ejemplar inexistente = %v, quiero ErrCopyNotFound", err)
+		}
+		stock(t, r, bookID, 1, 0)
+	})
+}
+
+func newCopy(bookID int64, barcode string) *copies.Copy {
+	return &copies.Copy{BookID: bookID, Barcode: barcode, Condition: copies.ConditionNew, Location: "A-1",
+		Status: copies.StatusAvailable, CreatedAt: at, UpdatedAt: at}
+}
+
+func createCopy(t *testing.T, r Repos, bookID int64, barcode string) int64 {
+	t.Helper()
+	id, err := r.Copies.CreateCopy(context.Background(), newCopy(bookID, barcode))
+	if err != nil {
+		t.Fatalf("CreateCopy(%s): %v", barcode, err)
+	}
+	return id
+}
+
+func getCopy(t *testing.T, r Repos, barcode string) *copies.Copy {
+	t.Helper()
+	c, err := r.Copies.GetByBarcode(context.Background(), barcode)
+	if err != nil || c == nil {
+		t.Fatalf("GetByBarcode(%s) = %+v, %v", barcode, c, err)
+	}
+	return c
+}
+
+// createBook crea un libro sin stock: el inventario sale de los ejemplares
+func createBook(t *testing.T, r Repos, mode string) int64 {
+	t.Helper()
+	b := &books.Book{BookName: "Rayuela", BookCategory: "Novela", TransactionType: mode, Price: 1000, RentalPeriodDays: 7}
+	if mode == books.ModeBoth {
+		rental := int64(300)
+		b.RentalPrice = &rental
+	}
+	id, err := r.Books.CreateBook(context.Background(), b, 0, 0)
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	return id
+}
+
+func getBook(t *testing.T, r Repos, id int64) books.BookWithInventory {
+	t.Helper()
+	b, err := r.Books.GetBookByID(context.Background(), id, nil)
+	if err != nil || b.Book == nil {
+		t.Fatalf("GetBookByID(%d) = %+v, %v", id, b, err)
+	}
+	return b
+}
+
+// stock revisa el inventario del libro; en los libros solo de arriendo todo
+// el stock es de arriendo
+func stock(t *testing.T, r Repos, bookID, available, rental int64) {
+	t.Helper()
+	b := getBook(t, r, bookID)
+	if b.AvailableQuantity != available || b.RentalQuantity != rental {
+		t.Fatalf("stock = %d (arriendo %d), quiero %d (arriendo %d)", b.AvailableQuantity, b.RentalQuantity, available, rental)
+	}
+}
+
+func balance(t *testing.T, r Repos, userID, want int64) {
+	t.Helper()
+	u, err := r.Users.GetUserByID(context.Background(), userID)
+	if err != nil || u == nil {
+		t.Fatalf("GetUserByID(%d) = %+v, %v", userID, u, err)
+	}
+	if u.USMPesos != want {
+		t.Fatalf("saldo = %d, quiero %d", u.USMPesos, want)
+	}
+}
+
+func createUser(t *testing.T, r Repos) int64 {
+	t.Helper()
+	id, err := r.Users.CreateUser(context.Background(), &users.Usuario{FirstName: "Ana", LastName: "Rojas", Email: "ana@usm.cl", Password: "x"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,151 @@
+package copies
+
+import (
+	"net/http"
+	"time"
+
+	httpx "uzm-server/internal/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type copyResponse struct {
+	ID        int64     `json:"id"`
+	BookID    int64     `json:"book_id"`
+	Barcode   string    `json:"barcode"`
+	Condition string    `json:"condition"`
+	Location  string    `json:"location"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func toCopyResponse(c *Copy) copyResponse {
+	return copyResponse{
+		ID:        c.ID,
+		BookID:    c.BookID,
+		Barcode:   c.Barcode,
+		Condition: c.Condition,
+		Location:  c.Location,
+		Status:    c.Status,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler { return &Handler{service: service} }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/books/:id/copies", h.createCopy)
+	r.GET("/books/:id/copies", h.listCopies)
+	r.GET("/copies/:barcode", h.getCopy)
+	r.PATCH("/copies/:barcode", h.updateCopy)
+	r.POST("/copies/:barcode/checkout", h.checkOut)
+	r.POST("/copies/:barcode/checkin", h.checkIn)
+}
+
+func (h *Handler) createCopy(c *gin.Context) {
+	bookID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	var input CreateCopyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+	cp, err := h.service.CreateCopy(c.Request.Context(), bookID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, toCopyResponse(cp))
+}
+
+func (h *Handler) listCopies(c *gin.Context) {
+	bookID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	list, err := h.service.ListByBook(c.Request.Context(), bookID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]copyResponse, 0, len(list))
+	for _, cp := range list {
+		out = append(out, toCopyResponse(cp))
+	}
+	c.JSON(http.StatusOK, gin.H{"copies": out})
+}
+
+func (h *Handler) getCopy(c *gin.Context) {
+	cp, err := h.service.GetByBarcode(c.Request.Context(), c.Param("barcode"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, toCopyResponse(cp))
+}
+
+func (h *Handler) updateCopy(c *gin.Context) {
+	var input UpdateCopyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+	cp, err := h.service.UpdateCopy(c.Request.Context(), c.Param("barcode"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, toCopyResponse(cp))
+}
+
+// checkOut presta o vende el ejemplar escaneado
+func (h *Handler) checkOut(c *gin.Context) {
+	var input CheckOutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+	co, err := h.service.CheckOut(c.Request.Context(), c.Param("barcode"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	resp := gin.H{"copy": toCopyResponse(co.Copy), "type": co.Type}
+	if co.Type == CheckoutLoan {
+		resp["loan_id"] = co.TransactionID
+		resp["return_date"] = co.ReturnDate
+	} else {
+		resp["sale_id"] = co.TransactionID
+		resp["price"] = co.Price
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// checkIn recibe un ejemplar prestado; el cuerpo es opcional
+func (h *Handler) checkIn(c *gin.Context) {
+	var input CheckInInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			httpx.BindError(c, err)
+			return
+		}
+	}
+	ci, err := h.service.CheckIn(c.Request.Context(), c.Param("barcode"), input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	resp := gin.H{"copy": toCopyResponse(ci.Copy), "days_late": ci.DaysLate, "late_fee": ci.LateFee}
+	if ci.LoanID != 0 {
+		resp["loan_id"] = ci.LoanID
+	}
+	c.JSON(http.StatusOK, resp)
+}
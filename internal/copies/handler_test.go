@@ -0,0 +1,154 @@
+package copies_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"uzm-server/internal/copies"
+	"uzm-server/internal/http/httpxtest"
+)
+
+// fakeService: el libro 1 existe; el ejemplar LIB-1 está disponible y LIB-2
+// prestado. El usuario 5 existe y el 6 no tiene saldo para comprar.
+type fakeService struct{}
+
+func copyOf(barcode, status string) *copies.Copy {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &copies.Copy{ID: 1, BookID: 1, Barcode: barcode, Condition: copies.ConditionGood, Location: "A-1", Status: status,
+		CreatedAt: now, UpdatedAt: now}
+}
+
+func find(barcode string) (*copies.Copy, error) {
+	switch barcode {
+	case "LIB-1":
+		return copyOf(barcode, copies.StatusAvailable), nil
+	case "LIB-2":
+		return copyOf(barcode, copies.StatusOnLoan), nil
+	}
+	return nil, copies.ErrCopyNotFound
+}
+
+func (fakeService) CreateCopy(ctx context.Context, bookID int64, input copies.CreateCopyInput) (*copies.Copy, error) {
+	switch {
+	case bookID != 1:
+		return nil, copies.ErrBookNotFound
+	case input.Barcode == "LIB-1":
+		return nil, copies.ErrBarcodeTaken
+	case input.Condition == "roto":
+		return nil, copies.ErrInvalidCopy
+	}
+	return copyOf(input.Barcode, copies.StatusAvailable), nil
+}
+
+func (fakeService) ListByBook(ctx context.Context, bookID int64) ([]*copies.Copy, error) {
+	if bookID != 1 {
+		return nil, copies.ErrBookNotFound
+	}
+	return []*copies.Copy{copyOf("LIB-1", copies.StatusAvailable), copyOf("LIB-2", copies.StatusOnLoan)}, nil
+}
+
+func (fakeService) GetByBarcode(ctx context.Context, barcode string) (*copies.Copy, error) {
+	return find(barcode)
+}
+
+func (fakeService) UpdateCopy(ctx context.Context, barcode string, input copies.UpdateCopyInput) (*copies.Copy, error) {
+	cp, err := find(barcode)
+	if err != nil {
+		return nil, err
+	}
+	if input.Status != nil {
+		if *input.Status != copies.StatusRetired {
+			return nil, copies.ErrInvalidCopy
+		}
+		cp.Status = *input.Status
+	}
+	return cp, nil
+}
+
+func (fakeService) CheckOut(ctx context.Context, barcode string, input copies.CheckOutInput) (*copies.Checkout, error) {
+	cp, err := find(barcode)
+	switch {
+	case err != nil:
+		return nil, err
+	case input.UserID == 6 && input.Type == copies.CheckoutSale:
+		return nil, copies.ErrInsufficientFunds
+	case input.UserID != 5:
+		return nil, copies.ErrUserNotFound
+	case input.Type != copies.CheckoutLoan && input.Type != copies.CheckoutSale:
+		return nil, copies.ErrInvalidCopy
+	case cp.Status != copies.StatusAvailable:
+		return nil, copies.ErrCopyNotAvailable
+	}
+	co := &copies.Checkout{Copy: cp, Type: input.Type, TransactionID: 8}
+	if input.Type == copies.CheckoutLoan {
+		cp.Status = copies.StatusOnLoan
+		due := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+		co.ReturnDate = &due
+	} else {
+		cp.Status = copies.StatusSold
+		co.Price = 800
+	}
+	return co, nil
+}
+
+func (fakeService) CheckIn(ctx context.Context, barcode string, input copies.CheckInInput) (*copies.Checkin, error) {
+	cp, err := find(barcode)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Status != copies.StatusOnLoan {
+		return nil, copies.ErrCopyNotOnLoan
+	}
+	cp.Status = copies.StatusAvailable
+	if input.Condition != nil {
+		cp.Condition = *input.Condition
+	}
+	return &copies.Checkin{Copy: cp, LoanID: 8, DaysLate: 2, LateFee: 200}, nil
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	router := httpxtest.Router(copies.NewHandler(fakeService{}).RegisterRoutes)
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "Create", Method: "POST", Path: "/books/1/copies", Body: `{"barcode":"LIB-3","location":"A-1"}`, Status: 201,
+			Want: []string{`"barcode":"LIB-3"`, `"status":"disponible"`}},
+		{Name: "CreateNoBarcode", Method: "POST", Path: "/books/1/copies", Body: `{"location":"A-1"}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"barcode"`}},
+		{Name: "CreateBadCondition", Method: "POST", Path: "/books/1/copies", Body: `{"barcode":"LIB-3","condition":"roto"}`, Status: 400, Code: "invalid_copy"},
+		{Name: "CreateTaken", Method: "POST", Path: "/books/1/copies", Body: `{"barcode":"LIB-1"}`, Status: 409, Code: "barcode_taken"},
+		{Name: "CreateMissingBook", Method: "POST", Path: "/books/9/copies", Body: `{"barcode":"LIB-3"}`, Status: 404, Code: "book_not_found"},
+		{Name: "CreateBadBook", Method: "POST", Path: "/books/x/copies", Body: `{"barcode":"LIB-3"}`, Status: 400, Code: "invalid_parameter"},
+
+		{Name: "List", Method: "GET", Path: "/books/1/copies", Status: 200, Want: []string{`"copies":[`, `"barcode":"LIB-2"`}},
+		{Name: "ListMissingBook", Method: "GET", Path: "/books/9/copies", Status: 404, Code: "book_not_found"},
+
+		{Name: "Get", Method: "GET", Path: "/copies/LIB-1", Status: 200, Want: []string{`"condition":"bueno"`, `"location":"A-1"`}},
+		{Name: "GetMissing", Method: "GET", Path: "/copies/LIB-9", Status: 404, Code: "copy_not_found"},
+
+		{Name: "Retire", Method: "PATCH", Path: "/copies/LIB-1", Body: `{"status":"retirado"}`, Status: 200, Want: []string{`"status":"retirado"`}},
+		{Name: "UpdateBadStatus", Method: "PATCH", Path: "/copies/LIB-1", Body: `{"status":"vendido"}`, Status: 400, Code: "invalid_copy"},
+		{Name: "UpdateMissing", Method: "PATCH", Path: "/copies/LIB-9", Body: `{}`, Status: 404, Code: "copy_not_found"},
+		{Name: "UpdateWrongType", Method: "PATCH", Path: "/copies/LIB-1", Body: `{"location":3}`, Status: 400, Code: "validation_failed"},
+
+		{Name: "Loan", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":5,"type":"prestamo"}`, Status: 201,
+			Want: []string{`"type":"prestamo"`, `"loan_id":8`, `"return_date":"2026-03-15T12:00:00Z"`, `"status":"prestado"`}},
+		{Name: "Sale", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":5,"type":"venta"}`, Status: 201,
+			Want: []string{`"type":"venta"`, `"sale_id":8`, `"price":800`, `"status":"vendido"`}},
+		{Name: "SaleNoFunds", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":6,"type":"venta"}`, Status: 402, Code: "insufficient_funds"},
+		{Name: "CheckoutMissingFields", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"user_id"`, `"field":"type"`}},
+		{Name: "CheckoutBadType", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":5,"type":"regalo"}`, Status: 400, Code: "invalid_copy"},
+		{Name: "CheckoutMissingUser", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":9,"type":"venta"}`, Status: 404, Code: "user_not_found"},
+		{Name: "CheckoutOnLoan", Method: "POST", Path: "/copies/LIB-2/checkout", Body: `{"user_id":5,"type":"venta"}`, Status: 409, Code: "copy_not_available"},
+		{Name: "CheckoutMissingCopy", Method: "POST", Path: "/copies/LIB-9/checkout", Body: `{"user_id":5,"type":"venta"}`, Status: 404, Code: "copy_not_found"},
+
+		{Name: "CheckIn", Method: "POST", Path: "/copies/LIB-2/checkin", Status: 200,
+			Want: []string{`"status":"disponible"`, `"loan_id":8`, `"days_late":2`, `"late_fee":200`}},
+		{Name: "CheckInWithCondition", Method: "POST", Path: "/copies/LIB-2/checkin", Body: `{"condition":"gastado"}`, Status: 200,
+			Want: []string{`"condition":"gastado"`}},
+		{Name: "CheckInNotOnLoan", Method: "POST", Path: "/copies/LIB-1/checkin", Status: 409, Code: "copy_not_on_loan"},
+		{Name: "CheckInMalformed", Method: "POST", Path: "/copies/LIB-2/checkin", Body: `{"condition":`, Status: 400, Code: "invalid_body"},
+		{Name: "CheckInMissing", Method: "POST", Path: "/copies/LIB-9/checkin", Status: 404, Code: "copy_not_found"},
+	})
+}
@@ -0,0 +1,276 @@
+package copies
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/wishlist"
+)
+
+// memoryRepository guarda los ejemplares en un memstore.Store. Los préstamos
+// y ventas que crea quedan en las tablas Loans y Sales del mismo Store.
+type memoryRepository struct{ store *memstore.Store }
+
+func NewMemoryRepository(store *memstore.Store) Repository {
+	return &memoryRepository{store: store}
+}
+
+func toCopy(c memstore.Copy) *Copy {
+	return &Copy{ID: c.ID, BookID: c.BookID, Barcode: c.Barcode, Condition: c.Condition,
+		Location: c.Location, Status: c.Status, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+}
+
+func fromCopy(c *Copy) memstore.Copy {
+	return memstore.Copy{ID: c.ID, BookID: c.BookID, Barcode: c.Barcode, Condition: c.Condition,
+		Location: c.Location, Status: c.Status, CreatedAt: memstore.Stamp(c.CreatedAt), UpdatedAt: memstore.Stamp(c.UpdatedAt)}
+}
+
+func memGetByBarcode(t *memstore.Tables, barcode string) *Copy {
+	for _, c := range t.Copies {
+		if c.Barcode == barcode {
+			return toCopy(c)
+		}
+	}
+	return nil
+}
+
+// memSyncInventory es syncInventory sobre las tablas en memoria
+func memSyncInventory(t *memstore.Tables, bookID int64) {
+	inv, ok := t.Inventory[bookID]
+	before := inv.Available
+
+	var available int64
+	for _, c := range t.Copies {
+		if c.BookID == bookID && c.Status == StatusAvailable {
+			available++
+		}
+	}
+	if !ok {
+		inv = memstore.Inventory{BookID: bookID}
+	}
+	inv.Available = available
+	inv.Rental = min(inv.Rental, available)
+	t.Inventory[bookID] = inv
+
+	if b, ok := t.Books[bookID]; ok {
+		b.Status = available > 0
+		b.Version++
+		t.Books[bookID] = b
+	}
+	if before <= 0 && available > 0 {
+		wishlist.NotifyBackInStockInMemory(t, bookID)
+	}
+}
+
+func (r *memoryRepository) CreateCopy(ctx context.Context, c *Copy) (int64, error) {
+	var id int64
+	err := r.store.Update(func(t *memstore.Tables) error {
+		if _, ok := t.Books[c.BookID]; !ok {
+			return ErrBookNotFound
+		}
+		if memGetByBarcode(t, c.Barcode) != nil {
+			return ErrBarcodeTaken
+		}
+		id = t.NextID("Ejemplar")
+		row := fromCopy(c)
+		row.ID = id
+		t.Copies[id] = row
+		memSyncInventory(t, c.BookID)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *memoryRepository) ListByBook(ctx context.Context, bookID int64) ([]Copy, error) {
+	out := []Copy{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		if _, ok := t.Books[bookID]; !ok {
+			return ErrBookNotFound
+		}
+		for _, c := range t.Copies {
+			if c.BookID == bookID {
+				out = append(out, *toCopy(c))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (r *memoryRepository) GetByBarcode(ctx context.Context, barcode string) (c *Copy, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		c = memGetByBarcode(t, barcode)
+		return nil
+	})
+	return c, err
+}
+
+func (r *memoryRepository) UpdateCopy(ctx context.Context, c *Copy) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if row, ok := t.Copies[c.ID]; ok {
+			row.Condition, row.Location, row.Status = c.Condition, c.Location, c.Status
+			row.UpdatedAt = memstore.Stamp(c.UpdatedAt)
+			t.Copies[c.ID] = row
+		}
+		memSyncInventory(t, c.BookID)
+		return nil
+	})
+}
+
+// CheckOut sigue a sqlRepository.CheckOut: crea el préstamo o la venta y
+// completa co.Copy y co.TransactionID solo si todo salió bien
+func (r *memoryRepository) CheckOut(ctx context.Context, barcode string, co *Checkout, userID int64, at time.Time) error {
+	result := *co
+	err := r.store.Update(func(t *memstore.Tables) error {
+		c := memGetByBarcode(t, barcode)
+		if c == nil {
+			return ErrCopyNotFound
+		}
+		if c.Status != StatusAvailable {
+			return ErrCopyNotAvailable
+		}
+		user, ok := t.Users[userID]
+		if !ok {
+			return ErrUserNotFound
+		}
+		book, ok := t.Books[c.BookID]
+		if !ok {
+			return fmt.Errorf("copies: el ejemplar %s apunta al libro %d, que no existe", c.Barcode, c.BookID)
+		}
+		inv := t.Inventory[c.BookID]
+
+		// En los libros "ambos" cada salida sale de su parte del stock
+		switch result.Type {
+		case CheckoutLoan:
+			if book.TransactionType != "arriendo" && book.TransactionType != "ambos" {
+				return ErrWrongCheckout
+			}
+			if book.TransactionType == "ambos" {
+				if inv.Rental <= 0 {
+					return ErrNoRentalStock
+				}
+				if _, ok := t.Inventory[c.BookID]; ok {
+					inv.Rental--
+					t.Inventory[c.BookID] = inv
+				}
+			}
+			if result.ReturnDate == nil {
+				due := at.AddDate(0, 0, int(book.RentalPeriodDays))
+				result.ReturnDate = &due
+			}
+			c.Status = StatusOnLoan
+			result.TransactionID = t.NextID("Prestamo")
+			t.Loans[result.TransactionID] = memstore.Loan{
+				ID:         result.TransactionID,
+				UserID:     userID,
+				BookID:     c.BookID,
+				CopyID:     c.ID,
+				StartDate:  memstore.Stamp(at),
+				ReturnDate: memstore.Stamp(*result.ReturnDate),
+				Status:     memstore.LoanPending,
+			}
+		case CheckoutSale:
+			if book.TransactionType != "venta" && book.TransactionType != "ambos" {
+				return ErrWrongCheckout
+			}
+			if book.TransactionType == "ambos" && inv.Available-min(inv.Rental, inv.Available) <= 0 {
+				return ErrNoSaleStock
+			}
+			// Se cobra en la misma escritura: sin saldo no hay venta
+			if user.USMPesos < result.Price {
+				return ErrInsufficientFunds
+			}
+			user.USMPesos -= result.Price
+			t.Users[userID] = user
+			c.Status = StatusSold
+			result.TransactionID = t.NextID("Venta")
+			t.Sales[result.TransactionID] = memstore.Sale{
+				ID:     result.TransactionID,
+				UserID: userID,
+				BookID: c.BookID,
+				CopyID: c.ID,
+				Date:   memstore.Stamp(at),
+				Price:  result.Price,
+			}
+		}
+
+		c.UpdatedAt = at
+		t.Copies[c.ID] = fromCopy(c)
+		memSyncInventory(t, c.BookID)
+		result.Copy = c
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*co = result
+	return nil
+}
+
+// CheckIn finaliza el préstamo pendiente del ejemplar, descuenta la multa por
+// atraso y lo vuelve a dejar disponible
+func (r *memoryRepository) CheckIn(ctx context.Context, barcode string, condition, location *string, lateFeePerDay int64, at time.Time) (*Checkin, error) {
+	var out *Checkin
+	err := r.store.Update(func(t *memstore.Tables) error {
+		c := memGetByBarcode(t, barcode)
+		if c == nil {
+			return ErrCopyNotFound
+		}
+		if c.Status != StatusOnLoan {
+			return ErrCopyNotOnLoan
+		}
+
+		ci := &Checkin{}
+		var loan memstore.Loan
+		for id, l := range t.Loans {
+			if l.CopyID == c.ID && l.Status == memstore.LoanPending {
+				if id > ci.LoanID {
+					ci.LoanID, loan = id, l
+				}
+				l.Status = memstore.LoanFinished
+				t.Loans[id] = l
+			}
+		}
+		// La multa se cobra aunque deje el saldo negativo: la devolución no se puede rechazar
+		if ci.LoanID != 0 {
+			ci.DaysLate, ci.LateFee = LateFee(loan.ReturnDate, at, lateFeePerDay)
+			if u, ok := t.Users[loan.UserID]; ok && ci.LateFee > 0 {
+				u.USMPesos -= ci.LateFee
+				t.Users[loan.UserID] = u
+			}
+		}
+		// El ejemplar devuelto vuelve al stock de arriendo
+		if inv, ok := t.Inventory[c.BookID]; ok && t.Books[c.BookID].TransactionType == "ambos" {
+			inv.Rental++
+			t.Inventory[c.BookID] = inv
+		}
+
+		c.Status = StatusAvailable
+		if condition != nil {
+			c.Condition = *condition
+		}
+		if location != nil {
+			c.Location = *location
+		}
+		c.UpdatedAt = at
+		t.Copies[c.ID] = fromCopy(c)
+		memSyncInventory(t, c.BookID)
+		ci.Copy = c
+		out = ci
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,416 @@
+package copies
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"uzm-server/internal/db"
+	"uzm-server/internal/wishlist"
+)
+
+type sqlRepository struct{ dbconn *db.DB }
+
+func NewSQLiteRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.SQLite)}
+}
+
+func NewPostgresRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.Postgres)}
+}
+
+const copySelect = `
+SELECT id, book_id, barcode, condition, location, status, created_at, updated_at
+FROM   Ejemplar`
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanCopy(row rowScanner) (Copy, error) {
+	var c Copy
+	var createdAt, updatedAt string
+	if err := row.Scan(&c.ID, &c.BookID, &c.Barcode, &c.Condition, &c.Location, &c.Status, &createdAt, &updatedAt); err != nil {
+		return Copy{}, err
+	}
+	c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return c, nil
+}
+
+// queryer es lo común entre *db.DB y *db.Tx para las lecturas
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func getByBarcode(ctx context.Context, q queryer, barcode string) (*Copy, error) {
+	c, err := scanCopy(q.QueryRowContext(ctx, copySelect+` WHERE barcode = ?`, barcode))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// syncInventory deja Inventario.available_quantity igual a los ejemplares
+// disponibles del libro, sin que el stock de arriendo lo supere, y ajusta
+// Libro.status en consecuencia. Si el libro vuelve a tener stock se avisa a
+// quienes lo desean.
+func syncInventory(ctx context.Context, tx *db.Tx, bookID int64) error {
+	var before int64
+	err := tx.QueryRowContext(ctx, `
+SELECT COALESCE((SELECT available_quantity FROM Inventario WHERE book_id = ?), 0)`, bookID).Scan(&before)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO Inventario (book_id, available_quantity)
+VALUES (?, (SELECT COUNT(*) FROM Ejemplar WHERE book_id = ? AND status = 'disponible'))
+ON CONFLICT (book_id) DO UPDATE SET available_quantity = excluded.available_quantity,
+    rental_quantity = CASE WHEN Inventario.rental_quantity < excluded.available_quantity
+        THEN Inventario.rental_quantity ELSE excluded.available_quantity END`, bookID, bookID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+UPDATE Libro
+SET status = COALESCE((SELECT available_quantity FROM Inventario WHERE book_id = ?), 0) > 0,
+    version = version + 1
+WHERE id = ?`, bookID, bookID)
+	if err != nil {
+		return err
+	}
+
+	var after int64
+	err = tx.QueryRowContext(ctx, `SELECT available_quantity FROM Inventario WHERE book_id = ?`, bookID).Scan(&after)
+	if err != nil {
+		return err
+	}
+	if before <= 0 && after > 0 {
+		return wishlist.NotifyBackInStock(ctx, tx, bookID)
+	}
+	return nil
+}
+
+func (r *sqlRepository) CreateCopy(ctx context.Context, c *Copy) (id int64, err error) {
+	tx, err := r.dbconn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var one int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM Libro WHERE id = ?`, c.BookID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrBookNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	existing, err := getByBarcode(ctx, tx, c.Barcode)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		err = ErrBarcodeTaken
+		return 0, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO Ejemplar (book_id, barcode, condition, location, status, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+RETURNING id`,
+		c.BookID, c.Barcode, c.Condition, c.Location, c.Status,
+		c.CreatedAt.Format(time.RFC3339), c.UpdatedAt.Format(time.RFC3339),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if err = syncInventory(ctx, tx, c.BookID); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *sqlRepository) ListByBook(ctx context.Context, bookID int64) ([]Copy, error) {
+	var one int
+	err := r.dbconn.QueryRowContext(ctx, `SELECT 1 FROM Libro WHERE id = ?`, bookID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.dbconn.QueryContext(ctx, copySelect+` WHERE book_id = ? ORDER BY id ASC`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Copy{}
+	for rows.Next() {
+		c, err := scanCopy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepository) GetByBarcode(ctx context.Context, barcode string) (*Copy, error) {
+	return getByBarcode(ctx, r.dbconn, barcode)
+}
+
+func (r *sqlRepository) UpdateCopy(ctx context.Context, c *Copy) (err error) {
+	tx, err := r.dbconn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE Ejemplar SET condition = ?, location = ?, status = ?, updated_at = ? WHERE id = ?`,
+		c.Condition, c.Location, c.Status, c.UpdatedAt.Format(time.RFC3339), c.ID)
+	if err != nil {
+		return err
+	}
+	if err = syncInventory(ctx, tx, c.BookID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CheckOut presta o vende el ejemplar creando la fila en Prestamo o Venta ligada
+// a él. Completa co.Copy y co.TransactionID.
+func (r *sqlRepository) CheckOut(ctx context.Context, barcode string, co *Checkout, userID int64, at time.Time) (err error) {
+	tx, err := r.dbconn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	c, err := getByBarcode(ctx, tx, barcode)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		err = ErrCopyNotFound
+		return err
+	}
+	if c.Status != StatusAvailable {
+		err = ErrCopyNotAvailable
+		return err
+	}
+
+	var one int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM Usuario WHERE id = ?`, userID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var transactionType string
+	var periodDays, available, rental int64
+	err = tx.QueryRowContext(ctx, `
+SELECT b.transaction_type, b.rental_period_days,
+       COALESCE(i.available_quantity, 0), COALESCE(i.rental_quantity, 0)
+FROM   Libro b
+LEFT JOIN Inventario i ON i.book_id = b.id
+WHERE  b.id = ?`, c.BookID).Scan(&transactionType, &periodDays, &available, &rental)
+	if err != nil {
+		return err
+	}
+
+	// En los libros "ambos" cada salida sale de su parte del stock
+	var insert string
+	var args []any
+	switch co.Type {
+	case CheckoutLoan:
+		if transactionType != "arriendo" && transactionType != "ambos" {
+			err = ErrWrongCheckout
+			return err
+		}
+		if transactionType == "ambos" {
+			if rental <= 0 {
+				err = ErrNoRentalStock
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `
+UPDATE Inventario SET rental_quantity = rental_quantity - 1 WHERE book_id = ?`, c.BookID)
+			if err != nil {
+				return err
+			}
+		}
+		if co.ReturnDate == nil {
+			due := at.AddDate(0, 0, int(periodDays))
+			co.ReturnDate = &due
+		}
+		c.Status = StatusOnLoan
+		insert = `
+INSERT INTO Prestamo (user_id, book_id, copy_id, start_date, return_date, status)
+VALUES (?, ?, ?, ?, ?, 'pendiente')
+RETURNING id`
+		args = []any{userID, c.BookID, c.ID, at.Format(time.RFC3339), co.ReturnDate.Format(time.RFC3339)}
+	case CheckoutSale:
+		if transactionType != "venta" && transactionType != "ambos" {
+			err = ErrWrongCheckout
+			return err
+		}
+		if transactionType == "ambos" && available-min(rental, available) <= 0 {
+			err = ErrNoSaleStock
+			return err
+		}
+		// Se cobra en la misma transacción: sin saldo no hay venta
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, `
+UPDATE Usuario SET usm_pesos = usm_pesos - ? WHERE id = ? AND usm_pesos >= ?`, co.Price, userID, co.Price)
+		if err != nil {
+			return err
+		}
+		var n int64
+		if n, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if n == 0 {
+			err = ErrInsufficientFunds
+			return err
+		}
+		c.Status = StatusSold
+		insert = `
+INSERT INTO Venta (user_id, book_id, copy_id, sale_date, price) VALUES (?, ?, ?, ?, ?)
+RETURNING id`
+		args = []any{userID, c.BookID, c.ID, at.Format(time.RFC3339), co.Price}
+	}
+	if err = tx.QueryRowContext(ctx, insert, args...).Scan(&co.TransactionID); err != nil {
+		return err
+	}
+
+	c.UpdatedAt = at
+	_, err = tx.ExecContext(ctx, `UPDATE Ejemplar SET status = ?, updated_at = ? WHERE id = ?`,
+		c.Status, at.Format(time.RFC3339), c.ID)
+	if err != nil {
+		return err
+	}
+	if err = syncInventory(ctx, tx, c.BookID); err != nil {
+		return err
+	}
+	co.Copy = c
+	return tx.Commit()
+}
+
+// Formatos de fecha presentes en Prestamo.return_date
+var loanDateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseLoanDate(v string) (time.Time, bool) {
+	for _, layout := range loanDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CheckIn finaliza el préstamo pendiente del ejemplar, descuenta la multa por
+// atraso al usuario y deja el ejemplar disponible. La multa se cobra aunque
+// deje el saldo negativo: la devolución no se puede rechazar.
+func (r *sqlRepository) CheckIn(ctx context.Context, barcode string, condition, location *string, lateFeePerDay int64, at time.Time) (ci *Checkin, err error) {
+	tx, err := r.dbconn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	c, err := getByBarcode(ctx, tx, barcode)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		err = ErrCopyNotFound
+		return nil, err
+	}
+	if c.Status != StatusOnLoan {
+		err = ErrCopyNotOnLoan
+		return nil, err
+	}
+
+	ci = &Checkin{}
+	var userID int64
+	var returnDate string
+	err = tx.QueryRowContext(ctx, `
+SELECT id, user_id, CAST(return_date AS TEXT) FROM Prestamo
+WHERE  copy_id = ? AND status = 'pendiente'
+ORDER BY id DESC LIMIT 1`, c.ID).Scan(&ci.LoanID, &userID, &returnDate)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = nil
+	case err != nil:
+		return nil, err
+	default:
+		if due, ok := parseLoanDate(returnDate); ok {
+			ci.DaysLate, ci.LateFee = LateFee(due, at, lateFeePerDay)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+UPDATE Prestamo SET status = 'finalizado' WHERE copy_id = ? AND status = 'pendiente'`, c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ci.LateFee > 0 {
+		_, err = tx.ExecContext(ctx, `UPDATE Usuario SET usm_pesos = usm_pesos - ? WHERE id = ?`, ci.LateFee, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// El ejemplar devuelto vuelve al stock de arriendo
+	_, err = tx.ExecContext(ctx, `
+UPDATE Inventario SET rental_quantity = rental_quantity + 1
+WHERE  book_id = ? AND (SELECT transaction_type FROM Libro WHERE id = ?) = 'ambos'`, c.BookID, c.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Status = StatusAvailable
+	if condition != nil {
+		c.Condition = *condition
+	}
+	if location != nil {
+		c.Location = *location
+	}
+	c.UpdatedAt = at
+	_, err = tx.ExecContext(ctx, `
+UPDATE Ejemplar SET condition = ?, location = ?, status = ?, updated_at = ? WHERE id = ?`,
+		c.Condition, c.Location, c.Status, at.Format(time.RFC3339), c.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = syncInventory(ctx, tx, c.BookID); err != nil {
+		return nil, err
+	}
+	ci.Copy = c
+	return ci, tx.Commit()
+}
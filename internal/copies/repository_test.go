@@ -0,0 +1,49 @@
+package copies_test
+
+import (
+	"os"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/copies/copiestest"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/users"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	copiestest.RunRepositoryContract(t, func(t *testing.T) copiestest.Repos {
+		conn := dbtest.SQLite(t).DB
+		return copiestest.Repos{
+			Copies: copies.NewSQLiteRepository(conn),
+			Books:  books.NewSQLiteRepository(conn),
+			Users:  users.NewSQLiteRepository(conn),
+		}
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	copiestest.RunRepositoryContract(t, func(t *testing.T) copiestest.Repos {
+		conn := dbtest.Postgres(t).DB
+		return copiestest.Repos{
+			Copies: copies.NewPostgresRepository(conn),
+			Books:  books.NewPostgresRepository(conn),
+			Users:  users.NewPostgresRepository(conn),
+		}
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	copiestest.RunRepositoryContract(t, func(t *testing.T) copiestest.Repos {
+		store := memstore.New()
+		return copiestest.Repos{
+			Copies: copies.NewMemoryRepository(store),
+			Books:  books.NewMemoryRepository(store),
+			Users:  users.NewMemoryRepository(store),
+		}
+	})
+}
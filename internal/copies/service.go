@@ -0,0 +1,307 @@
+package copies
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"uzm-server/internal/apperr"
+	"uzm-server/internal/books"
+	"uzm-server/internal/users"
+)
+
+var (
+	ErrCopyNotFound     = apperr.NotFound("copy_not_found", "ejemplar no encontrado")
+	ErrBookNotFound     = apperr.NotFound("book_not_found", "libro no encontrado")
+	ErrUserNotFound     = apperr.NotFound("user_not_found", "usuario no encontrado")
+	ErrBarcodeTaken     = apperr.Conflict("barcode_taken", "ya existe un ejemplar con ese código de barras")
+	ErrCopyNotAvailable = apperr.Conflict("copy_not_available", "el ejemplar no está disponible")
+	ErrCopyNotOnLoan    = apperr.Conflict("copy_not_on_loan", "el ejemplar no está prestado")
+	ErrWrongCheckout    = apperr.Conflict("wrong_checkout_type", "el libro no admite ese tipo de transacción")
+	ErrNoRentalStock    = apperr.Conflict("no_rental_stock", "el libro no tiene ejemplares asignados a arriendo")
+	ErrNoSaleStock      = apperr.Conflict("no_sale_stock", "los ejemplares disponibles del libro están asignados a arriendo")
+	ErrInvalidCopy      = apperr.Validation("invalid_copy", "ejemplar inválido")
+
+	// ErrInsufficientFunds es el mismo error de saldo de users: la venta de un
+	// ejemplar se cobra igual que cualquier compra
+	ErrInsufficientFunds = users.ErrInsufficientFunds
+)
+
+// Estado físico de un ejemplar
+const (
+	ConditionNew     = "nuevo"
+	ConditionGood    = "bueno"
+	ConditionWorn    = "gastado"
+	ConditionDamaged = "deteriorado"
+)
+
+// Situación de un ejemplar; solo los "disponible" cuentan en Inventario.available_quantity
+const (
+	StatusAvailable = "disponible"
+	StatusOnLoan    = "prestado"
+	StatusSold      = "vendido"
+	StatusRetired   = "retirado"
+)
+
+// Tipos de salida de un ejemplar, iguales a los de Prestamo/Venta
+const (
+	CheckoutLoan = "prestamo"
+	CheckoutSale = "venta"
+)
+
+// Copy es un ejemplar físico de un libro identificado por su código de barras
+type Copy struct {
+	ID        int64
+	BookID    int64
+	Barcode   string
+	Condition string
+	Location  string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CreateCopyInput struct {
+	Barcode   string `json:"barcode" binding:"required"`
+	Condition string `json:"condition"` // por defecto "nuevo"
+	Location  string `json:"location"`
+}
+
+type UpdateCopyInput struct {
+	Condition *string `json:"condition"`
+	Location  *string `json:"location"`
+	Status    *string `json:"status"` // solo "retirado" o, desde retirado, "disponible"
+}
+
+type CheckOutInput struct {
+	UserID     int64      `json:"user_id" binding:"required"`
+	Type       string     `json:"type" binding:"required"` // "prestamo" | "venta"
+	ReturnDate *time.Time `json:"return_date"`             // solo préstamos; por defecto, el período de arriendo del libro
+}
+
+type CheckInInput struct {
+	Condition *string `json:"condition"`
+	Location  *string `json:"location"`
+}
+
+// Checkout es el resultado de sacar un ejemplar: el préstamo o la venta creada
+type Checkout struct {
+	Copy          *Copy
+	Type          string
+	TransactionID int64 // id en Prestamo o Venta según Type
+	ReturnDate    *time.Time
+	Price         int64 // solo ventas: USM Pesos cobrados, con la promoción vigente
+}
+
+// Checkin es el resultado de recibir un ejemplar: el préstamo finalizado y la
+// multa cobrada si se devolvió después de la fecha acordada
+type Checkin struct {
+	Copy     *Copy
+	LoanID   int64
+	DaysLate int64
+	LateFee  int64 // USM Pesos descontados al usuario del préstamo
+}
+
+type Service interface { // Interfaz del servicio de ejemplares
+	CreateCopy(ctx context.Context, bookID int64, input CreateCopyInput) (*Copy, error)   // Registra un ejemplar físico de un libro
+	ListByBook(ctx context.Context, bookID int64) ([]*Copy, error)                        // Ejemplares de un libro
+	GetByBarcode(ctx context.Context, barcode string) (*Copy, error)                      // Busca un ejemplar por código de barras
+	UpdateCopy(ctx context.Context, barcode string, input UpdateCopyInput) (*Copy, error) // Cambia estado físico, ubicación o lo retira
+	CheckOut(ctx context.Context, barcode string, input CheckOutInput) (*Checkout, error) // Presta o vende un ejemplar
+	CheckIn(ctx context.Context, barcode string, input CheckInInput) (*Checkin, error)    // Recibe la devolución de un ejemplar prestado
+}
+
+type Repository interface {
+	CreateCopy(ctx context.Context, c *Copy) (int64, error)
+	ListByBook(ctx context.Context, bookID int64) ([]Copy, error)
+	GetByBarcode(ctx context.Context, barcode string) (*Copy, error)
+	UpdateCopy(ctx context.Context, c *Copy) error
+	CheckOut(ctx context.Context, barcode string, co *Checkout, userID int64, at time.Time) error // En las ventas descuenta co.Price al usuario
+	CheckIn(ctx context.Context, barcode string, condition, location *string, lateFeePerDay int64, at time.Time) (*Checkin, error)
+}
+
+// Pricer entrega un libro con su precio efectivo; lo implementa books.Service
+type Pricer interface {
+	GetBookByID(ctx context.Context, id int64, onlyAvailable *bool) (*books.BookWithInventory, error)
+}
+
+type service struct {
+	repo          Repository
+	prices        Pricer
+	lateFeePerDay int64 // USM Pesos por día de atraso; 0 no cobra multa
+}
+
+func NewService(repo Repository, prices Pricer, lateFeePerDay int64) Service {
+	return &service{repo: repo, prices: prices, lateFeePerDay: lateFeePerDay}
+}
+
+// LateFee calcula los días de atraso de una devolución hecha en at para un
+// préstamo que vencía en due, contando cada día empezado, y la multa que
+// corresponde a perDay USM Pesos por día
+func LateFee(due, at time.Time, perDay int64) (days, fee int64) {
+	if !at.After(due) {
+		return 0, 0
+	}
+	late := at.Sub(due)
+	days = int64(late / (24 * time.Hour))
+	if late%(24*time.Hour) != 0 {
+		days++
+	}
+	return days, days * perDay
+}
+
+func validCondition(c string) bool {
+	switch c {
+	case ConditionNew, ConditionGood, ConditionWorn, ConditionDamaged:
+		return true
+	}
+	return false
+}
+
+// normCondition limpia la condición recibida y la valida
+func normCondition(c *string) (*string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	v := strings.ToLower(strings.TrimSpace(*c))
+	if !validCondition(v) {
+		return nil, fmt.Errorf("%w: condition debe ser nuevo, bueno, gastado o deteriorado", ErrInvalidCopy)
+	}
+	return &v, nil
+}
+
+func (s *service) CreateCopy(ctx context.Context, bookID int64, input CreateCopyInput) (*Copy, error) {
+	barcode := strings.TrimSpace(input.Barcode)
+	if barcode == "" {
+		return nil, fmt.Errorf("%w: se necesita el código de barras", ErrInvalidCopy)
+	}
+	condition := ConditionNew
+	if input.Condition != "" {
+		c, err := normCondition(&input.Condition)
+		if err != nil {
+			return nil, err
+		}
+		condition = *c
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	c := &Copy{
+		BookID:    bookID,
+		Barcode:   barcode,
+		Condition: condition,
+		Location:  strings.TrimSpace(input.Location),
+		Status:    StatusAvailable,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	id, err := s.repo.CreateCopy(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	c.ID = id
+	return c, nil
+}
+
+func (s *service) ListByBook(ctx context.Context, bookID int64) ([]*Copy, error) {
+	list, err := s.repo.ListByBook(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Copy, len(list))
+	for i := range list {
+		out[i] = &list[i]
+	}
+	return out, nil
+}
+
+func (s *service) GetByBarcode(ctx context.Context, barcode string) (*Copy, error) {
+	c, err := s.repo.GetByBarcode(ctx, strings.TrimSpace(barcode))
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrCopyNotFound
+	}
+	return c, nil
+}
+
+func (s *service) UpdateCopy(ctx context.Context, barcode string, input UpdateCopyInput) (*Copy, error) {
+	c, err := s.GetByBarcode(ctx, barcode)
+	if err != nil {
+		return nil, err
+	}
+	condition, err := normCondition(input.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if condition != nil {
+		c.Condition = *condition
+	}
+	if input.Location != nil {
+		c.Location = strings.TrimSpace(*input.Location)
+	}
+	if input.Status != nil {
+		switch status := strings.ToLower(strings.TrimSpace(*input.Status)); {
+		case status == c.Status:
+		case status == StatusRetired && c.Status == StatusAvailable,
+			status == StatusAvailable && c.Status == StatusRetired:
+			c.Status = status
+		default:
+			return nil, fmt.Errorf("%w: no se puede pasar de %s a %s", ErrInvalidCopy, c.Status, status)
+		}
+	}
+	c.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	if err := s.repo.UpdateCopy(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) CheckOut(ctx context.Context, barcode string, input CheckOutInput) (*Checkout, error) {
+	co := &Checkout{Type: strings.ToLower(strings.TrimSpace(input.Type))}
+	now := time.Now().UTC().Truncate(time.Second)
+	switch co.Type {
+	case CheckoutLoan:
+		// Sin fecha de devolución el repositorio usa el período de arriendo del libro
+		if input.ReturnDate != nil {
+			due := input.ReturnDate.UTC()
+			if !due.After(now) {
+				return nil, fmt.Errorf("%w: return_date debe ser futura", ErrInvalidCopy)
+			}
+			co.ReturnDate = &due
+		}
+	case CheckoutSale:
+		// La venta se cobra al precio con la promoción vigente, igual que en el catálogo
+		c, err := s.GetByBarcode(ctx, barcode)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.prices.GetBookByID(ctx, c.BookID, nil)
+		if err != nil {
+			return nil, err
+		}
+		co.Price = b.Book.EffectivePrice
+	default:
+		return nil, fmt.Errorf("%w: type debe ser 'prestamo' o 'venta'", ErrInvalidCopy)
+	}
+
+	if err := s.repo.CheckOut(ctx, strings.TrimSpace(barcode), co, input.UserID, now); err != nil {
+		return nil, err
+	}
+	return co, nil
+}
+
+func (s *service) CheckIn(ctx context.Context, barcode string, input CheckInInput) (*Checkin, error) {
+	condition, err := normCondition(input.Condition)
+	if err != nil {
+		return nil, err
+	}
+	var location *string
+	if input.Location != nil {
+		l := strings.TrimSpace(*input.Location)
+		location = &l
+	}
+	return s.repo.CheckIn(ctx, strings.TrimSpace(barcode), condition, location, s.lateFeePerDay, time.Now().UTC().Truncate(time.Second))
+}
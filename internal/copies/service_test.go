@@ -0,0 +1,107 @@
+package copies_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/users"
+)
+
+// backend arma los servicios sobre un motor y lee el precio guardado en una venta
+type backend struct {
+	copies    copies.Repository
+	books     books.Repository
+	users     users.Repository
+	salePrice func(t *testing.T, id int64) int64
+}
+
+func backends() map[string]func(t *testing.T) backend {
+	return map[string]func(t *testing.T) backend{
+		"SQLite": func(t *testing.T) backend {
+			conn := dbtest.SQLite(t)
+			return backend{
+				copies: copies.NewSQLiteRepository(conn.DB),
+				books:  books.NewSQLiteRepository(conn.DB),
+				users:  users.NewSQLiteRepository(conn.DB),
+				salePrice: func(t *testing.T, id int64) int64 {
+					var price sql.NullInt64
+					if err := conn.QueryRowContext(context.Background(), `SELECT price FROM Venta WHERE id = ?`, id).Scan(&price); err != nil {
+						t.Fatal(err)
+					}
+					return price.Int64
+				},
+			}
+		},
+		"Memory": func(t *testing.T) backend {
+			store := memstore.New()
+			return backend{
+				copies: copies.NewMemoryRepository(store),
+				books:  books.NewMemoryRepository(store),
+				users:  users.NewMemoryRepository(store),
+				salePrice: func(t *testing.T, id int64) int64 {
+					var price int64
+					_ = store.View(func(tb *memstore.Tables) error {
+						price = tb.Sales[id].Price
+						return nil
+					})
+					return price
+				},
+			}
+		},
+	}
+}
+
+// La venta cobra y guarda el precio con la promoción vigente, no el de lista
+func TestServiceSaleChargesPrice(t *testing.T) {
+	for name, newBackend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			b := newBackend(t)
+			bookService := books.NewService(b.books, 14)
+			service := copies.NewService(b.copies, bookService, 0)
+
+			bookID, err := bookService.CreateBook(ctx, books.CreateBookInput{BookName: "Rayuela", BookCategory: "Novela",
+				TransactionType: books.ModeSale, Price: 1000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now().UTC()
+			_, err = bookService.CreatePromotion(ctx, books.CreatePromotionInput{BookID: &bookID, DiscountType: books.DiscountPercentage,
+				DiscountValue: 20, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			userID, err := b.users.CreateUser(ctx, &users.Usuario{FirstName: "Ana", LastName: "Rojas", Email: "ana@usm.cl", Password: "x", USMPesos: 5000})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := service.CreateCopy(ctx, bookID, copies.CreateCopyInput{Barcode: "LIB-1"}); err != nil {
+				t.Fatal(err)
+			}
+
+			co, err := service.CheckOut(ctx, "LIB-1", copies.CheckOutInput{UserID: userID, Type: copies.CheckoutSale})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if co.Price != 800 {
+				t.Fatalf("CheckOut cobró %d, quiero 800 (20%% de descuento sobre 1000)", co.Price)
+			}
+			if got := b.salePrice(t, co.TransactionID); got != 800 {
+				t.Fatalf("Venta.price = %d, quiero 800", got)
+			}
+			u, err := b.users.GetUserByID(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.USMPesos != 4200 {
+				t.Fatalf("saldo tras la compra = %d, quiero 4200", u.USMPesos)
+			}
+		})
+	}
+}
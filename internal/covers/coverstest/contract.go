@@ -0,0 +1,109 @@
+// Package coverstest tiene la prueba de contrato de covers.Repository: todas
+// las implementaciones deben pasarla igual, sea cual sea el motor que usen.
+package coverstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/covers"
+)
+
+// Repos son los repositorios de una misma base vacía: el que se prueba y el
+// de libros, dueño de las filas donde se guarda la portada
+type Repos struct {
+	Covers covers.Repository
+	Books  books.Repository
+}
+
+// RunRepositoryContract prueba una implementación de covers.Repository.
+// newRepos debe entregar repositorios vacíos en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepos func(t *testing.T) Repos) {
+	ctx := context.Background()
+
+	t.Run("MissingBook", func(t *testing.T) {
+		r := newRepos(t)
+		if _, err := r.Covers.GetCover(ctx, 999); !errors.Is(err, covers.ErrBookNotFound) {
+			t.Fatalf("GetCover(999) = %v, quiero ErrBookNotFound", err)
+		}
+		err := r.Covers.SetCover(ctx, &covers.Cover{BookID: 999, ContentType: "image/png", UpdatedAt: time.Now()})
+		if !errors.Is(err, covers.ErrBookNotFound) {
+			t.Fatalf("SetCover(999) = %v, quiero ErrBookNotFound", err)
+		}
+	})
+
+	t.Run("NoCover", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r)
+		if _, err := r.Covers.GetCover(ctx, bookID); !errors.Is(err, covers.ErrCoverNotFound) {
+			t.Fatalf("GetCover de un libro sin portada = %v, quiero ErrCoverNotFound", err)
+		}
+	})
+
+	t.Run("SetAndReplace", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r)
+		version := bookVersion(t, r, bookID)
+
+		// Se guarda en UTC y al segundo, sin importar la zona recibida
+		santiago := time.FixedZone("CLT", -3*3600)
+		first := time.Date(2026, 3, 1, 9, 0, 0, 500, santiago)
+		set(t, r, bookID, "image/png", first)
+		got := get(t, r, bookID)
+		if got.BookID != bookID || got.ContentType != "image/png" || !got.UpdatedAt.Equal(first.Truncate(time.Second)) {
+			t.Fatalf("GetCover = %+v, quiero image/png del %v", got, first)
+		}
+		// Cambiar la portada cambia la representación del libro
+		if v := bookVersion(t, r, bookID); v != version+1 {
+			t.Fatalf("versión del libro %d, quiero %d", v, version+1)
+		}
+
+		second := first.Add(time.Hour)
+		set(t, r, bookID, "image/jpeg", second)
+		got = get(t, r, bookID)
+		if got.ContentType != "image/jpeg" || !got.UpdatedAt.Equal(second.Truncate(time.Second)) {
+			t.Fatalf("GetCover tras reemplazar = %+v", got)
+		}
+		if v := bookVersion(t, r, bookID); v != version+2 {
+			t.Fatalf("versión del libro %d, quiero %d", v, version+2)
+		}
+	})
+}
+
+func createBook(t *testing.T, r Repos) int64 {
+	t.Helper()
+	b := &books.Book{BookName: "Rayuela", BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, RentalPeriodDays: 7}
+	id, err := r.Books.CreateBook(context.Background(), b, 1, 0)
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	return id
+}
+
+func bookVersion(t *testing.T, r Repos, id int64) int64 {
+	t.Helper()
+	b, err := r.Books.GetBookByID(context.Background(), id, nil)
+	if err != nil || b.Book == nil {
+		t.Fatalf("GetBookByID(%d) = %+v, %v", id, b, err)
+	}
+	return b.Book.Version
+}
+
+func set(t *testing.T, r Repos, bookID int64, contentType string, at time.Time) {
+	t.Helper()
+	if err := r.Covers.SetCover(context.Background(), &covers.Cover{BookID: bookID, ContentType: contentType, UpdatedAt: at}); err != nil {
+		t.Fatalf("SetCover: %v", err)
+	}
+}
+
+func get(t *testing.T, r Repos, bookID int64) *covers.Cover {
+	t.Helper()
+	c, err := r.Covers.GetCover(context.Background(), bookID)
+	if err != nil {
+		t.Fatalf("GetCover: %v", err)
+	}
+	return c
+}
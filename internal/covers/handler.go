@@ -0,0 +1,118 @@
+package covers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	httpx "uzm-server/internal/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BasePath es el prefijo de la versión vigente de la API, usado para armar las
+// URLs públicas; el alias obsoleto /api también las responde
+var BasePath = "/api/v1"
+
+// URL arma la dirección pública de la portada (o su miniatura). El parámetro v
+// cambia con cada subida, así que los clientes pueden cachearla sin miedo.
+func URL(bookID int64, updatedAt time.Time, thumbnail bool) string {
+	suffix := ""
+	if thumbnail {
+		suffix = "/thumbnail"
+	}
+	return fmt.Sprintf("%s/books/%d/cover%s?v=%d", BasePath, bookID, suffix, updatedAt.Unix())
+}
+
+// Tiempo que clientes y proxies pueden reutilizar una portada sin revalidar
+const coverMaxAge = 24 * time.Hour
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler { return &Handler{service: service} }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.PUT("/books/:id/cover", h.uploadCover)
+	r.GET("/books/:id/cover", h.serveCover)
+	r.GET("/books/:id/cover/thumbnail", h.serveThumbnail)
+}
+
+// uploadCover recibe la imagen en el campo multipart "cover"
+func (h *Handler) uploadCover(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	// Margen para los encabezados multipart sobre el tamaño máximo de la imagen
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxCoverSize+64<<10)
+
+	fh, err := c.FormFile("cover")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			_ = c.Error(ErrImageTooLarge)
+			return
+		}
+		_ = c.Error(ErrMissingCover)
+		return
+	}
+	if fh.Size > MaxCoverSize {
+		_ = c.Error(ErrImageTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxCoverSize+1))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	cover, err := h.service.SetCover(c.Request.Context(), id, data)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"book_id":       id,
+		"content_type":  cover.ContentType,
+		"cover_url":     URL(id, cover.UpdatedAt, false),
+		"thumbnail_url": URL(id, cover.UpdatedAt, true),
+		"updated_at":    cover.UpdatedAt,
+	})
+}
+
+func (h *Handler) serveCover(c *gin.Context)     { h.serve(c, false) }
+func (h *Handler) serveThumbnail(c *gin.Context) { h.serve(c, true) }
+
+// serve entrega la imagen con encabezados de caché; http.ServeContent responde
+// 304 ante If-None-Match / If-Modified-Since y atiende rangos.
+func (h *Handler) serve(c *gin.Context, thumbnail bool) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	f, cover, err := h.service.OpenCover(c.Request.Context(), id, thumbnail)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	defer f.Close()
+
+	etag := fmt.Sprintf(`"%d-%d"`, id, cover.UpdatedAt.Unix())
+	if thumbnail {
+		etag = fmt.Sprintf(`"%d-%d-thumb"`, id, cover.UpdatedAt.Unix())
+	}
+	c.Header("Content-Type", cover.ContentType)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(coverMaxAge.Seconds())))
+	c.Header("ETag", etag)
+	http.ServeContent(c.Writer, c.Request, "", cover.UpdatedAt, f)
+}
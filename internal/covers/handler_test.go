@@ -0,0 +1,126 @@
+package covers_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"uzm-server/internal/covers"
+	"uzm-server/internal/http/httpxtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	pngData   = []byte("\x89PNG\r\n\x1a\n-imagen")
+	updatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// fakeService: el libro 1 tiene portada, el 2 no y el resto no existe.
+// Acepta cualquier cuerpo que empiece con la firma PNG.
+type fakeService struct{}
+
+func (fakeService) SetCover(ctx context.Context, bookID int64, data []byte) (*covers.Cover, error) {
+	if bookID != 1 && bookID != 2 {
+		return nil, covers.ErrBookNotFound
+	}
+	if !bytes.HasPrefix(data, pngData[:8]) {
+		return nil, covers.ErrUnsupportedImage
+	}
+	return &covers.Cover{BookID: bookID, ContentType: "image/png", UpdatedAt: updatedAt}, nil
+}
+
+type nopCloser struct{ io.ReadSeeker }
+
+func (nopCloser) Close() error { return nil }
+
+func (fakeService) OpenCover(ctx context.Context, bookID int64, thumbnail bool) (io.ReadSeekCloser, *covers.Cover, error) {
+	switch bookID {
+	case 1:
+		cover := &covers.Cover{BookID: 1, ContentType: "image/png", UpdatedAt: updatedAt}
+		if thumbnail {
+			cover.ContentType = "image/jpeg"
+			return nopCloser{bytes.NewReader([]byte("miniatura"))}, cover, nil
+		}
+		return nopCloser{bytes.NewReader(pngData)}, cover, nil
+	case 2:
+		return nil, nil, covers.ErrCoverNotFound
+	}
+	return nil, nil, covers.ErrBookNotFound
+}
+
+func newRouter() *gin.Engine {
+	return httpxtest.Router(covers.NewHandler(fakeService{}).RegisterRoutes)
+}
+
+// upload sube data en el campo multipart field
+func upload(router http.Handler, path, field string, data []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile(field, "portada.png")
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+
+	req := httptest.NewRequest("PUT", "/api/v1"+path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandlerUpload(t *testing.T) {
+	router := newRouter()
+	tests := []struct {
+		name   string
+		path   string
+		field  string
+		data   []byte
+		status int
+		code   string
+		want   []string
+	}{
+		{"OK", "/books/1/cover", "cover", pngData, 200, "",
+			[]string{`"content_type":"image/png"`, `"cover_url":"/api/v1/books/1/cover?v=`, `"thumbnail_url":"/api/v1/books/1/cover/thumbnail?v=`}},
+		{"NotAnImage", "/books/1/cover", "cover", []byte("hola"), 415, "unsupported_image", nil},
+		{"WrongField", "/books/1/cover", "imagen", pngData, 400, "missing_cover", []string{`"field":"cover"`}},
+		{"TooLarge", "/books/1/cover", "cover", make([]byte, covers.MaxCoverSize+1), 413, "image_too_large", nil},
+		{"MissingBook", "/books/9/cover", "cover", pngData, 404, "book_not_found", nil},
+		{"BadID", "/books/x/cover", "cover", pngData, 400, "invalid_parameter", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpxtest.Check(t, upload(router, tt.path, tt.field, tt.data), tt.status, tt.code, tt.want...)
+		})
+	}
+
+	// Sin cuerpo multipart alguno
+	t.Run("NoForm", func(t *testing.T) {
+		w := httpxtest.Do(router, "PUT", "/books/1/cover", "", nil)
+		httpxtest.Check(t, w, 400, "missing_cover")
+	})
+}
+
+func TestHandlerServe(t *testing.T) {
+	httpxtest.Run(t, newRouter(), []httpxtest.Case{
+		{Name: "Cover", Method: "GET", Path: "/books/1/cover", Status: 200, Want: []string{"-imagen"}},
+		{Name: "Thumbnail", Method: "GET", Path: "/books/1/cover/thumbnail", Status: 200, Want: []string{"miniatura"}},
+		{Name: "NoCover", Method: "GET", Path: "/books/2/cover", Status: 404, Code: "cover_not_found"},
+		{Name: "NoThumbnail", Method: "GET", Path: "/books/2/cover/thumbnail", Status: 404, Code: "cover_not_found"},
+		{Name: "MissingBook", Method: "GET", Path: "/books/9/cover", Status: 404, Code: "book_not_found"},
+		{Name: "BadID", Method: "GET", Path: "/books/0/cover", Status: 400, Code: "invalid_parameter"},
+		{Name: "SameETag", Method: "GET", Path: "/books/1/cover", Header: map[string]string{"If-None-Match": `"1-1772366400"`}, Status: 304},
+		{Name: "ThumbnailETag", Method: "GET", Path: "/books/1/cover/thumbnail", Header: map[string]string{"If-None-Match": `"1-1772366400-thumb"`}, Status: 304},
+		{Name: "OtherETag", Method: "GET", Path: "/books/1/cover", Header: map[string]string{"If-None-Match": `"1-1"`}, Status: 200},
+		{Name: "Range", Method: "GET", Path: "/books/1/cover", Header: map[string]string{"Range": "bytes=8-"}, Status: 206, Want: []string{"-imagen"}},
+	})
+
+	w := httpxtest.Do(newRouter(), "GET", "/books/1/cover/thumbnail", "", nil)
+	if ct, cc := w.Header().Get("Content-Type"), w.Header().Get("Cache-Control"); ct != "image/jpeg" || cc != "public, max-age=86400" {
+		t.Fatalf("Content-Type %q y Cache-Control %q", ct, cc)
+	}
+}
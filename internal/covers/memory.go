@@ -0,0 +1,47 @@
+package covers
+
+import (
+	"context"
+
+	"uzm-server/internal/memstore"
+)
+
+type memoryRepository struct{ store *memstore.Store }
+
+func NewMemoryRepository(store *memstore.Store) Repository {
+	return &memoryRepository{store: store}
+}
+
+// GetCover devuelve ErrBookNotFound si el libro no existe y ErrCoverNotFound si no tiene portada
+func (r *memoryRepository) GetCover(ctx context.Context, bookID int64) (*Cover, error) {
+	var c *Cover
+	err := r.store.View(func(t *memstore.Tables) error {
+		b, ok := t.Books[bookID]
+		if !ok {
+			return ErrBookNotFound
+		}
+		if b.CoverContentType == nil || b.CoverUpdatedAt == nil {
+			return ErrCoverNotFound
+		}
+		c = &Cover{BookID: bookID, ContentType: *b.CoverContentType, UpdatedAt: *b.CoverUpdatedAt}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *memoryRepository) SetCover(ctx context.Context, c *Cover) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		b, ok := t.Books[c.BookID]
+		if !ok {
+			return ErrBookNotFound
+		}
+		contentType, updatedAt := c.ContentType, memstore.Stamp(c.UpdatedAt.UTC())
+		b.CoverContentType, b.CoverUpdatedAt = &contentType, &updatedAt
+		b.Version++
+		t.Books[c.BookID] = b
+		return nil
+	})
+}
@@ -0,0 +1,56 @@
+package covers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"uzm-server/internal/db"
+)
+
+type sqlRepository struct{ dbconn *db.DB }
+
+func NewSQLiteRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.SQLite)}
+}
+
+func NewPostgresRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.Postgres)}
+}
+
+// GetCover devuelve ErrBookNotFound si el libro no existe y ErrCoverNotFound si no tiene portada
+func (r *sqlRepository) GetCover(ctx context.Context, bookID int64) (*Cover, error) {
+	var contentType, updatedAt sql.NullString
+	err := r.dbconn.QueryRowContext(ctx, `
+SELECT cover_content_type, cover_updated_at FROM Libro WHERE id = ?`, bookID).Scan(&contentType, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !contentType.Valid || !updatedAt.Valid {
+		return nil, ErrCoverNotFound
+	}
+	c := &Cover{BookID: bookID, ContentType: contentType.String}
+	c.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt.String)
+	return c, nil
+}
+
+func (r *sqlRepository) SetCover(ctx context.Context, c *Cover) error {
+	res, err := r.dbconn.ExecContext(ctx, `
+UPDATE Libro SET cover_content_type = ?, cover_updated_at = ?, version = version + 1 WHERE id = ?`,
+		c.ContentType, c.UpdatedAt.UTC().Format(time.RFC3339), c.BookID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrBookNotFound
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package covers_test
+
+import (
+	"os"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/covers/coverstest"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	coverstest.RunRepositoryContract(t, func(t *testing.T) coverstest.Repos {
+		conn := dbtest.SQLite(t).DB
+		return coverstest.Repos{Covers: covers.NewSQLiteRepository(conn), Books: books.NewSQLiteRepository(conn)}
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	coverstest.RunRepositoryContract(t, func(t *testing.T) coverstest.Repos {
+		conn := dbtest.Postgres(t).DB
+		return coverstest.Repos{Covers: covers.NewPostgresRepository(conn), Books: books.NewPostgresRepository(conn)}
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	coverstest.RunRepositoryContract(t, func(t *testing.T) coverstest.Repos {
+		store := memstore.New()
+		return coverstest.Repos{Covers: covers.NewMemoryRepository(store), Books: books.NewMemoryRepository(store)}
+	})
+}
@@ -0,0 +1,120 @@
+package covers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"uzm-server/internal/apperr"
+	"uzm-server/internal/storage"
+)
+
+var (
+	ErrBookNotFound       = apperr.NotFound("book_not_found", "libro no encontrado")
+	ErrCoverNotFound      = apperr.NotFound("cover_not_found", "el libro no tiene portada")
+	ErrUnsupportedImage   = apperr.New(apperr.KindUnsupportedMedia, "unsupported_image", "la portada debe ser una imagen JPEG o PNG")
+	ErrImageTooLarge      = apperr.New(apperr.KindTooLarge, "image_too_large", fmt.Sprintf("la portada no puede superar %d MB", MaxCoverSize>>20))
+	ErrImageTooManyPixels = apperr.New(apperr.KindTooLarge, "image_too_many_pixels",
+		fmt.Sprintf("la portada no puede superar %d megapíxeles", MaxCoverPixels/1_000_000))
+	ErrMissingCover = apperr.Validation("missing_cover", "falta la imagen en el campo multipart \"cover\"").
+			WithFields(apperr.FieldError{Field: "cover", Code: "required", Message: "es obligatorio"})
+)
+
+// MaxCoverSize es el tamaño máximo aceptado para una portada
+const MaxCoverSize = 5 << 20
+
+// MaxCoverPixels es el máximo de píxeles (ancho × alto) de una portada. Una
+// imagen decodificada ocupa unos 4 bytes por píxel, así que esto acota la
+// memoria de cada subida a unos 100 MB sin importar lo que pese el archivo.
+const MaxCoverPixels = 25_000_000
+
+// Cover describe la portada guardada de un libro
+type Cover struct {
+	BookID      int64
+	ContentType string // "image/jpeg" | "image/png"; la miniatura siempre es JPEG
+	UpdatedAt   time.Time
+}
+
+type Service interface { // Interfaz del servicio de portadas
+	SetCover(ctx context.Context, bookID int64, data []byte) (*Cover, error)                        // Valida la imagen, guarda original y miniatura
+	OpenCover(ctx context.Context, bookID int64, thumbnail bool) (io.ReadSeekCloser, *Cover, error) // Abre la portada o su miniatura para servirla
+}
+
+type Repository interface {
+	GetCover(ctx context.Context, bookID int64) (*Cover, error)
+	SetCover(ctx context.Context, c *Cover) error
+}
+
+type service struct {
+	repo  Repository
+	store storage.Storage
+}
+
+func NewService(repo Repository, store storage.Storage) Service {
+	return &service{repo: repo, store: store}
+}
+
+func coverKey(bookID int64) string     { return fmt.Sprintf("%d", bookID) }
+func thumbnailKey(bookID int64) string { return fmt.Sprintf("%d_thumb.jpg", bookID) }
+
+func (s *service) SetCover(ctx context.Context, bookID int64, data []byte) (*Cover, error) {
+	if len(data) > MaxCoverSize {
+		return nil, ErrImageTooLarge
+	}
+	// El tipo se detecta por el contenido, no por lo que declare el cliente
+	contentType := http.DetectContentType(data)
+	if contentType != "image/jpeg" && contentType != "image/png" {
+		return nil, ErrUnsupportedImage
+	}
+	thumb, err := makeThumbnail(data)
+	if errors.Is(err, ErrImageTooManyPixels) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	// GetCover distingue libro inexistente de libro sin portada
+	if _, err := s.repo.GetCover(ctx, bookID); err != nil && !errors.Is(err, ErrCoverNotFound) {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, coverKey(bookID), data); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, thumbnailKey(bookID), thumb); err != nil {
+		return nil, err
+	}
+
+	c := &Cover{BookID: bookID, ContentType: contentType, UpdatedAt: time.Now().UTC().Truncate(time.Second)}
+	if err := s.repo.SetCover(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) OpenCover(ctx context.Context, bookID int64, thumbnail bool) (io.ReadSeekCloser, *Cover, error) {
+	c, err := s.repo.GetCover(ctx, bookID)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := coverKey(bookID)
+	if thumbnail {
+		key = thumbnailKey(bookID)
+	}
+	f, _, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrCoverNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if thumbnail {
+		cc := *c
+		cc.ContentType = "image/jpeg"
+		c = &cc
+	}
+	return f, c, nil
+}
@@ -0,0 +1,80 @@
+package covers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/storage"
+)
+
+// pngHeader es un PNG que solo trae la firma y el chunk IHDR: declara las
+// dimensiones sin datos de imagen, como una bomba de descompresión
+func pngHeader(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6 // 8 bits por canal, RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func TestServiceSetCover(t *testing.T) {
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 300, 150))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"PNG", small.Bytes(), nil},
+		{"NotAnImage", []byte("hola"), covers.ErrUnsupportedImage},
+		{"Truncated", pngHeader(300, 150), covers.ErrUnsupportedImage},
+		{"TooManyPixels", pngHeader(100_000, 100_000), covers.ErrImageTooManyPixels},
+		{"TooLarge", make([]byte, covers.MaxCoverSize+1), covers.ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mem := memstore.New()
+			bookID, err := books.NewMemoryRepository(mem).CreateBook(ctx,
+				&books.Book{BookName: "Rayuela", BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, RentalPeriodDays: 7}, 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, err := storage.NewLocalStorage(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc := covers.NewService(covers.NewMemoryRepository(mem), store)
+
+			c, err := svc.SetCover(ctx, bookID, tt.data)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("SetCover = %v, quiero %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil || c.ContentType != "image/png" {
+				t.Fatalf("SetCover = %+v, %v", c, err)
+			}
+		})
+	}
+}
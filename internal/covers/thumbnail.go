@@ -0,0 +1,70 @@
+package covers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // registra el decodificador PNG para image.Decode
+)
+
+// Ancho máximo de la miniatura; el alto se ajusta para mantener la proporción
+const thumbnailWidth = 200
+
+// makeThumbnail reduce la imagen promediando los píxeles de cada celda y la
+// codifica como JPEG. Las transparencias de un PNG quedan sobre fondo blanco.
+// Antes de decodificar lee solo el encabezado: un archivo chico puede
+// declarar dimensiones enormes y agotar la memoria al decodificarlo, así que
+// las imágenes de más de MaxCoverPixels se rechazan con ErrImageTooManyPixels.
+func makeThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, image.ErrFormat
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > MaxCoverPixels {
+		return nil, ErrImageTooManyPixels
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, image.ErrFormat
+	}
+	tw, th := w, h
+	if w > thumbnailWidth {
+		tw = thumbnailWidth
+		th = max(1, h*thumbnailWidth/w)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var rs, gs, bs, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					// Mezcla con blanco según el canal alfa (colores premultiplicados)
+					rs += uint64(cr + 0xffff - ca)
+					gs += uint64(cg + 0xffff - ca)
+					bs += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(rs / n), G: uint16(gs / n), B: uint16(bs / n), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
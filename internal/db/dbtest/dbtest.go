@@ -0,0 +1,172 @@
+// Package dbtest abre bases de datos temporales, con el esquema ya migrado,
+// para probar los repositorios contra cada motor.
+//
+// SQLite usa un archivo en t.TempDir(). PostgreSQL usa el servidor de la
+// variable UZM_TEST_POSTGRES_DSN (una URL postgres://) o, si no está
+// definida, un clúster temporal levantado con initdb y pg_ctl cuando están
+// en el PATH; sin ninguno de los dos la prueba se salta. Cada prueba trabaja
+// en un schema propio que se borra al terminar. Los paquetes que usan
+// Postgres deben pasar su TestMain por Main para apagar el clúster.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"uzm-server/internal/db"
+)
+
+// PostgresEnv es la variable con la URL del servidor PostgreSQL de pruebas
+const PostgresEnv = "UZM_TEST_POSTGRES_DSN"
+
+// SQLite entrega una base SQLite nueva y migrada que se cierra al terminar la prueba
+func SQLite(t testing.TB) *db.DB {
+	t.Helper()
+	return open(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// Postgres entrega un schema nuevo y migrado en el servidor de pruebas, o
+// salta la prueba si no hay servidor
+func Postgres(t testing.TB) *db.DB {
+	t.Helper()
+	server, err := postgresServer()
+	if err != nil {
+		t.Skipf("sin PostgreSQL para las pruebas: %v", err)
+	}
+
+	admin, err := sql.Open("pgx", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := "uzm_test_" + randomSuffix()
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("creando el schema de prueba: %v", err)
+	}
+	t.Cleanup(func() {
+		admin, err := sql.Open("pgx", server)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer admin.Close()
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("borrando el schema de prueba: %v", err)
+		}
+	})
+
+	u, err := url.Parse(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return open(t, u.String())
+}
+
+func open(t testing.TB, dsn string) *db.DB {
+	t.Helper()
+	conn, err := db.Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if _, err := db.MigrateUp(context.Background(), conn); err != nil {
+		t.Fatalf("migrando la base de prueba: %v", err)
+	}
+	return conn
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Clúster local, compartido por todas las pruebas del paquete
+var local struct {
+	once sync.Once
+	dir  string
+	dsn  string
+	err  error
+}
+
+func postgresServer() (string, error) {
+	if dsn := os.Getenv(PostgresEnv); dsn != "" {
+		if db.DialectOf(dsn) != db.Postgres {
+			return "", fmt.Errorf("%s debe ser una URL postgres://", PostgresEnv)
+		}
+		return dsn, nil
+	}
+	local.once.Do(startLocal)
+	return local.dsn, local.err
+}
+
+// startLocal crea un clúster en un directorio temporal que solo escucha en
+// un socket Unix dentro del mismo directorio, así no choca con otro servidor
+func startLocal() {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		local.err = fmt.Errorf("%s no está definida e initdb no está en el PATH", PostgresEnv)
+		return
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		local.err = fmt.Errorf("%s no está definida y pg_ctl no está en el PATH", PostgresEnv)
+		return
+	}
+	if os.Geteuid() == 0 {
+		local.err = fmt.Errorf("initdb no corre como root; define %s", PostgresEnv)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "uzm-pg-")
+	if err != nil {
+		local.err = err
+		return
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		local.err = fmt.Errorf("initdb: %v\n%s", err, out)
+		return
+	}
+	opts := fmt.Sprintf("-c listen_addresses='' -k %s -p 5432 -F", dir)
+	if out, err := exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "log"), "-o", opts, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		local.err = fmt.Errorf("pg_ctl start: %v\n%s", err, out)
+		return
+	}
+	local.dir = dir
+	local.dsn = "postgres://postgres@/postgres?host=" + url.QueryEscape(dir) + "&port=5432&sslmode=disable"
+}
+
+func stopLocal() {
+	if local.dir == "" {
+		return
+	}
+	if pgCtl, err := exec.LookPath("pg_ctl"); err == nil {
+		_ = exec.Command(pgCtl, "-D", filepath.Join(local.dir, "data"), "-m", "immediate", "-w", "stop").Run()
+	}
+	os.RemoveAll(local.dir)
+}
+
+// Main corre las pruebas del paquete y después apaga el clúster local, si se
+// levantó. Se usa desde TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	stopLocal()
+	return code
+}
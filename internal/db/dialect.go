@@ -0,0 +1,122 @@
+// Package db abre la base de datos del servidor y administra su esquema. El
+// servidor funciona sobre SQLite o PostgreSQL; el motor se elige por el DSN.
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib" // driver "pgx"
+	"modernc.org/sqlite"               // driver "sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect es el motor de base de datos de una conexión
+type Dialect string
+
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// DialectOf deduce el motor del DSN: las URL postgres:// y postgresql:// son
+// de PostgreSQL y todo lo demás es una ruta o DSN de SQLite.
+func DialectOf(dsn string) Dialect {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return Postgres
+	}
+	return SQLite
+}
+
+// Open abre la base de datos del DSN con el driver de su motor. Como
+// sql.Open, no se conecta todavía.
+func Open(dsn string) (*DB, error) {
+	d := DialectOf(dsn)
+	driver := "sqlite"
+	if d == Postgres {
+		driver = "pgx"
+	} else {
+		dsn = sqliteDSN(dsn)
+	}
+	conn, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(conn, d), nil
+}
+
+// sqliteDSN agrega al DSN de SQLite las opciones que necesitan las
+// escrituras concurrentes, salvo que ya vengan en el DSN: busy_timeout para
+// que una conexión espere a que se libere el archivo en vez de fallar con
+// SQLITE_BUSY, y transacciones IMMEDIATE, que toman el bloqueo de escritura
+// al empezar. Con las transacciones diferidas por defecto, dos que leen y
+// después escriben chocan al escribir y SQLite rechaza una sin esperar.
+func sqliteDSN(dsn string) string {
+	var opts []string
+	if !strings.Contains(dsn, "busy_timeout") {
+		opts = append(opts, "_pragma=busy_timeout(5000)")
+	}
+	if !strings.Contains(dsn, "_txlock=") {
+		opts = append(opts, "_txlock=immediate")
+	}
+	if len(opts) == 0 {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + strings.Join(opts, "&")
+}
+
+// Rebind pasa los marcadores ? de una consulta a los del motor: $1, $2… en
+// PostgreSQL. Los ? dentro de literales de texto entre comillas simples no
+// se tocan.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	quoted := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			quoted = !quoted
+		case c == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// IsUniqueViolation indica si err es la violación de la restricción UNIQUE
+// de table.column, en cualquiera de los dos motores, según el código de error
+// del driver. En PostgreSQL se usa el nombre que le da a la restricción por
+// defecto: tabla_columna_key. SQLite no entrega el nombre de la restricción
+// aparte: la columna solo aparece en el mensaje del error.
+func IsUniqueViolation(err error, table, column string) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505" &&
+			pgErr.ConstraintName == strings.ToLower(table+"_"+column+"_key")
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE &&
+			strings.Contains(sqliteErr.Error(), table+"."+column)
+	}
+	return false
+}
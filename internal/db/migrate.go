@@ -1,20 +1,281 @@
 package db
 
 import (
-	"database/sql"
-	"os"
-	"log"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func MakeMigrate(db *sql.DB, schemaFile string) {
-	schema, err := os.ReadFile(schemaFile)
+// Las migraciones van dentro del binario, en una carpeta por motor
+// (migrations/sqlite, migrations/postgres). Cada versión es un par de archivos
+// NNNN_nombre.up.sql y NNNN_nombre.down.sql; las versiones no se reescriben
+// una vez publicadas, los cambios nuevos van en una versión siguiente. Las dos
+// carpetas tienen las mismas versiones con el mismo esquema.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+// Migration es una versión del esquema
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus indica si una migración ya se aplicó a la base de datos
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // nil si está pendiente
+}
+
+// Migrations entrega las migraciones embebidas del motor ordenadas por versión
+func Migrations(d Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", string(d))
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		file := e.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migración %s: el nombre debe terminar en .up.sql o .down.sql", file)
+		}
+		num, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(num, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migración %s: el nombre debe empezar con un número de versión", file)
+		}
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migración %d: los archivos up y down tienen nombres distintos", version)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migración %d: faltan el archivo up o el down", m.Version)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// ensureTable crea la tabla que registra las versiones aplicadas
+func ensureTable(ctx context.Context, db *DB) error {
+	_, err := db.ExecContext(ctx, `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TEXT NOT NULL
+)`)
+	return err
+}
+
+func applied(ctx context.Context, db *DB) (map[int64]time.Time, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	_, err = db.Exec(string(schema))
+	out := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		t, _ := time.Parse(time.RFC3339, at)
+		out[version] = t
+	}
+	return out, rows.Err()
+}
+
+// Status lista todas las migraciones con su estado en la base de datos
+func Status(ctx context.Context, db *DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+	done, err := applied(ctx, db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	out := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		out[i].Migration = m
+		if at, ok := done[m.Version]; ok {
+			out[i].AppliedAt = &at
+		}
+	}
+	return out, nil
+}
+
+// schemaColumns son las tablas y columnas que usan los repositorios con todas
+// las migraciones aplicadas. Una migración que agrega o quita columnas
+// también se anota aquí.
+var schemaColumns = []struct {
+	table   string
+	columns string
+}{
+	{"Usuario", "id, first_name, last_name, email, password, usm_pesos"},
+	{"Libro", "id, book_name, book_category, transaction_type, price, rental_price, rental_period_days, status, " +
+		"popularity_score, archived_at, reorder_threshold, cover_content_type, cover_updated_at, version"},
+	{"Inventario", "book_id, available_quantity, rental_quantity"},
+	{"Ejemplar", "id, book_id, barcode, condition, location, status, created_at, updated_at"},
+	{"Prestamo", "id, user_id, book_id, copy_id, start_date, return_date, status"},
+	{"Venta", "id, user_id, book_id, copy_id, sale_date, price"},
+	{"Resena", "id, user_id, book_id, rating, comment, created_at, updated_at"},
+	{"HistorialPrecio", "id, book_id, old_price, new_price, changed_at"},
+	{"Promocion", "id, book_id, book_category, discount_type, discount_value, starts_at, ends_at"},
+	{"AjusteInventario", "id, book_id, user_id, delta, reason, note, quantity_after, created_at"},
+	{"AlertaStock", "id, book_id, available_quantity, reorder_threshold, daily_velocity, suggested_quantity, " +
+		"created_at, updated_at, resolved_at"},
+	{"ListaDeseos", "user_id, book_id, created_at"},
+	{"Notificacion", "id, user_id, book_id, kind, message, created_at, read_at"},
+}
+
+// probeColumns consulta sin filas cada tabla con las columnas de
+// schemaColumns, para detectar un esquema que no coincide con lo que dice
+// schema_migrations (por ejemplo una migración registrada que no alteró nada)
+func probeColumns(ctx context.Context, db *DB) error {
+	for _, tc := range schemaColumns {
+		rows, err := db.QueryContext(ctx, "SELECT "+tc.columns+" FROM "+tc.table+" LIMIT 0")
+		if err != nil {
+			return fmt.Errorf("la tabla %s no tiene el esquema esperado: %w", tc.table, err)
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+// CheckSchema confirma, sin escribir en la base, que tiene aplicadas
+// exactamente las migraciones embebidas en el binario y que las tablas tienen
+// las columnas que usan los repositorios. Devuelve la versión.
+func CheckSchema(ctx context.Context, db *DB) (int64, error) {
+	migrations, err := Migrations(db.Dialect)
+	if err != nil {
+		return 0, err
+	}
+	done, err := applied(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo leer schema_migrations: %w", err)
+	}
+	var pending []string
+	var current int64
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+			continue
+		}
+		current = m.Version
+		delete(done, m.Version)
+	}
+	if len(pending) > 0 {
+		return current, fmt.Errorf("migraciones pendientes: %s", strings.Join(pending, ", "))
+	}
+	if len(done) > 0 {
+		return current, fmt.Errorf("la base tiene migraciones que este binario no conoce")
+	}
+	return current, probeColumns(ctx, db)
+}
+
+// MigrateUp aplica en orden las migraciones pendientes, cada una en su propia
+// transacción: si una falla, las anteriores quedan aplicadas y esa no.
+func MigrateUp(ctx context.Context, db *DB) ([]Migration, error) {
+	status, err := Status(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, s := range status {
+		if s.AppliedAt != nil {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *Tx) error {
+			// El SQL de la migración va tal cual, sin traducir marcadores
+			if _, err := tx.Tx.ExecContext(ctx, s.Up); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `
+INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				s.Version, s.Name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migración %04d_%s: %w", s.Version, s.Name, err)
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// MigrateDown revierte la última migración aplicada. Devuelve nil si no hay
+// ninguna que revertir.
+func MigrateDown(ctx context.Context, db *DB) (*Migration, error) {
+	status, err := Status(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(status) - 1; i >= 0; i-- {
+		s := status[i]
+		if s.AppliedAt == nil {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *Tx) error {
+			if _, err := tx.Tx.ExecContext(ctx, s.Down); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = ?`, s.Version)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migración %04d_%s: %w", s.Version, s.Name, err)
+		}
+		return &s.Migration, nil
+	}
+	return nil, nil
+}
+
+func inTx(ctx context.Context, db *DB, fn func(*Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
 	}
-	log.Println("Base de datos y tablas creadas exitosamente")
-}
\ No newline at end of file
+	return tx.Commit()
+}
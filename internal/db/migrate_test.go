@@ -0,0 +1,158 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/db"
+	"uzm-server/internal/db/dbtest"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+// Las dos carpetas de migraciones deben tener las mismas versiones
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	sqlite, err := db.Migrations(db.SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := db.Migrations(db.Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite tiene %d migraciones y postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migración %d: sqlite %04d_%s, postgres %04d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
+
+// Una base creada con el schema.sql de antes de las migraciones, como el
+// uzm.db del repositorio, debe quedar con el esquema completo al migrarla
+func TestMigrateBaselineDatabase(t *testing.T) {
+	ctx := context.Background()
+	baseline, err := os.ReadFile(filepath.Join("testdata", "baseline.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.Open(filepath.Join(t.TempDir(), "uzm.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, string(baseline)); err != nil {
+		t.Fatalf("creando la base con el esquema inicial: %v", err)
+	}
+
+	done, err := db.MigrateUp(ctx, conn)
+	if err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	all, _ := db.Migrations(db.SQLite)
+	if len(done) != len(all) {
+		t.Fatalf("se aplicaron %d migraciones, quiero %d", len(done), len(all))
+	}
+	if _, err := db.CheckSchema(ctx, conn); err != nil {
+		t.Fatalf("CheckSchema: %v", err)
+	}
+
+	// Los libros que ya estaban se leen con los valores por defecto de las columnas nuevas
+	repo := books.NewSQLiteRepository(conn.DB)
+	list, err := repo.ListBook(ctx, books.CatalogFilter{})
+	if err != nil {
+		t.Fatalf("ListBook: %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("ListBook devolvió %d libros, quiero 1", len(list))
+	}
+	b := list[0]
+	if b.Book.BookName != "Dune" || b.AvailableQuantity != 3 || b.Book.Version != 1 || b.Book.RentalPeriodDays != 14 || b.Book.ArchivedAt != nil {
+		t.Errorf("libro migrado = %+v, stock %d", *b.Book, b.AvailableQuantity)
+	}
+
+	// La venta anterior a Venta.price queda con el precio de lista del libro
+	var price int64
+	if err := conn.QueryRowContext(ctx, `SELECT price FROM Venta WHERE id = 1`).Scan(&price); err != nil {
+		t.Fatal(err)
+	}
+	if price != 300 {
+		t.Errorf("Venta.price migrado = %d, quiero 300", price)
+	}
+}
+
+// Revertir todas las migraciones deja la base vacía y se pueden volver a aplicar
+func TestMigrateDownAndUp(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	all, _ := db.Migrations(db.SQLite)
+	for i := len(all) - 1; i >= 0; i-- {
+		m, err := db.MigrateDown(ctx, conn)
+		if err != nil {
+			t.Fatalf("MigrateDown: %v", err)
+		}
+		if m == nil || m.Version != all[i].Version {
+			t.Fatalf("MigrateDown revirtió %v, quiero la versión %d", m, all[i].Version)
+		}
+	}
+	if m, err := db.MigrateDown(ctx, conn); m != nil || err != nil {
+		t.Fatalf("MigrateDown sin migraciones = %v, %v", m, err)
+	}
+	var tables int
+	if err := conn.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')`).Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Errorf("quedaron %d tablas después de revertir todo", tables)
+	}
+	if done, err := db.MigrateUp(ctx, conn); err != nil || len(done) != len(all) {
+		t.Fatalf("MigrateUp = %d migraciones, %v", len(done), err)
+	}
+}
+
+// CheckSchema no se fía solo de schema_migrations: una base que registra
+// todas las versiones sin tener sus columnas no pasa
+func TestCheckSchemaProbesColumns(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	if _, err := db.CheckSchema(ctx, conn); err != nil {
+		t.Fatalf("CheckSchema en una base migrada: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, `ALTER TABLE Libro DROP COLUMN rental_price`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := db.CheckSchema(ctx, conn)
+	if err == nil || !strings.Contains(err.Error(), "rental_price") {
+		t.Fatalf("CheckSchema sin Libro.rental_price = %v, quiero un error que la nombre", err)
+	}
+}
+
+// IsUniqueViolation reconoce la restricción por el código de error de SQLite
+// y distingue la columna que la violó
+func TestIsUniqueViolation(t *testing.T) {
+	ctx := context.Background()
+	conn := dbtest.SQLite(t)
+	insert := `INSERT INTO Usuario (first_name, last_name, email, password, usm_pesos) VALUES ('Ana', 'Rojas', 'ana@usm.cl', 'x', 0)`
+	if _, err := conn.ExecContext(ctx, insert); err != nil {
+		t.Fatal(err)
+	}
+	_, err := conn.ExecContext(ctx, insert)
+	if !db.IsUniqueViolation(err, "Usuario", "email") {
+		t.Fatalf("IsUniqueViolation(%v, Usuario, email) = false", err)
+	}
+	if db.IsUniqueViolation(err, "Ejemplar", "barcode") {
+		t.Fatalf("IsUniqueViolation(%v, Ejemplar, barcode) = true", err)
+	}
+	if db.IsUniqueViolation(errors.New("UNIQUE constraint failed: Usuario.email"), "Usuario", "email") {
+		t.Fatal("un error que no viene del driver no es una violación de UNIQUE")
+	}
+}
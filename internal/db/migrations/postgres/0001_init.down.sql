@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS Venta;
+DROP TABLE IF EXISTS Prestamo;
+DROP TABLE IF EXISTS Inventario;
+DROP TABLE IF EXISTS Libro;
+DROP TABLE IF EXISTS Usuario;
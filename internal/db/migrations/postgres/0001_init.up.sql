@@ -0,0 +1,47 @@
+-- Mismo esquema que migrations/sqlite/0001_init.up.sql. Las fechas se guardan
+-- como texto RFC3339, igual que en SQLite, para que las consultas las
+-- comparen de la misma forma en los dos motores.
+CREATE TABLE IF NOT EXISTS Usuario (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL UNIQUE,
+    password TEXT NOT NULL,
+    usm_pesos BIGINT DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS Libro (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_name TEXT NOT NULL,
+    book_category TEXT NOT NULL,
+    transaction_type TEXT NOT NULL,
+    price BIGINT NOT NULL,
+    status BOOLEAN NOT NULL DEFAULT TRUE,
+    popularity_score BIGINT DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS Inventario (
+    book_id BIGINT PRIMARY KEY,
+    available_quantity BIGINT NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+CREATE TABLE IF NOT EXISTS Prestamo (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT,
+    book_id BIGINT,
+    start_date TEXT NOT NULL,
+    return_date TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pendiente','finalizado')),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+CREATE TABLE IF NOT EXISTS Venta (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT,
+    book_id BIGINT,
+    sale_date TEXT NOT NULL, -- DATE en SQLite, que igual guarda el texto RFC3339
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS Resena (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    book_id BIGINT NOT NULL,
+    rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    UNIQUE (user_id, book_id),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
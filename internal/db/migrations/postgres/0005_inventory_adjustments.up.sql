@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS AjusteInventario (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    user_id BIGINT,
+    delta BIGINT NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('reposicion','deterioro','perdida','correccion')),
+    note TEXT NOT NULL DEFAULT '',
+    quantity_after BIGINT NOT NULL,
+    created_at TEXT NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id)
+);
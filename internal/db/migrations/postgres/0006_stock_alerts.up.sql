@@ -0,0 +1,14 @@
+ALTER TABLE Libro ADD COLUMN reorder_threshold BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS AlertaStock (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    available_quantity BIGINT NOT NULL,
+    reorder_threshold BIGINT NOT NULL,
+    daily_velocity DOUBLE PRECISION NOT NULL DEFAULT 0,
+    suggested_quantity BIGINT NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    resolved_at TEXT,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
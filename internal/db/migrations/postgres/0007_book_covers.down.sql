@@ -0,0 +1,2 @@
+ALTER TABLE Libro DROP COLUMN cover_updated_at;
+ALTER TABLE Libro DROP COLUMN cover_content_type;
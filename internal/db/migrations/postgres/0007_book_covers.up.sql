@@ -0,0 +1,2 @@
+ALTER TABLE Libro ADD COLUMN cover_content_type TEXT;
+ALTER TABLE Libro ADD COLUMN cover_updated_at TEXT;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS Ejemplar (
+    id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    barcode TEXT NOT NULL UNIQUE,
+    condition TEXT NOT NULL CHECK (condition IN ('nuevo','bueno','gastado','deteriorado')),
+    location TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('disponible','prestado','vendido','retirado')),
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+ALTER TABLE Prestamo ADD COLUMN copy_id BIGINT REFERENCES Ejemplar(id);
+ALTER TABLE Venta ADD COLUMN copy_id BIGINT REFERENCES Ejemplar(id);
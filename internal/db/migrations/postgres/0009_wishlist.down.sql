@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Notificacion;
+DROP TABLE IF EXISTS ListaDeseos;
@@ -0,0 +1 @@
+ALTER TABLE Libro ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE Inventario DROP COLUMN rental_quantity;
+ALTER TABLE Libro DROP COLUMN rental_period_days;
+ALTER TABLE Libro DROP COLUMN rental_price;
@@ -0,0 +1,4 @@
+-- transaction_type pasa a aceptar "ambos": venta y arriendo del mismo libro
+ALTER TABLE Libro ADD COLUMN rental_price BIGINT; -- precio de arriendo por período en los libros "ambos"
+ALTER TABLE Libro ADD COLUMN rental_period_days BIGINT NOT NULL DEFAULT 14;
+ALTER TABLE Inventario ADD COLUMN rental_quantity BIGINT NOT NULL DEFAULT 0; -- parte del stock para arriendo en los libros "ambos"
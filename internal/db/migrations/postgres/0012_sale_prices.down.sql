@@ -0,0 +1 @@
+ALTER TABLE Venta DROP COLUMN price;
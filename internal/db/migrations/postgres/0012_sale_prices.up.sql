@@ -0,0 +1,11 @@
+-- Precio cobrado en cada venta, con la promoción vigente al vender
+ALTER TABLE Venta ADD COLUMN price BIGINT;
+
+-- Las ventas anteriores no guardaron el precio: se usa el de lista vigente en
+-- su fecha (HistorialPrecio) o, si no hay, el precio actual del libro
+UPDATE Venta SET price = COALESCE(
+    (SELECT h.new_price FROM HistorialPrecio h
+     WHERE  h.book_id = Venta.book_id AND h.changed_at <= CAST(Venta.sale_date AS TEXT)
+     ORDER BY h.changed_at DESC, h.id DESC LIMIT 1),
+    (SELECT b.price FROM Libro b WHERE b.id = Venta.book_id),
+    0);
@@ -0,0 +1 @@
+ALTER TABLE Libro ADD COLUMN archived_at TEXT;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Promocion;
+DROP TABLE IF EXISTS HistorialPrecio;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS HistorialPrecio (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL,
+    old_price INTEGER,
+    new_price INTEGER NOT NULL,
+    changed_at TEXT NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+CREATE TABLE IF NOT EXISTS Promocion (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER,
+    book_category TEXT,
+    discount_type TEXT NOT NULL CHECK (discount_type IN ('porcentaje','monto')),
+    discount_value INTEGER NOT NULL CHECK (discount_value > 0),
+    starts_at TEXT NOT NULL,
+    ends_at TEXT NOT NULL,
+    CHECK ((book_id IS NULL) <> (book_category IS NULL)),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS AjusteInventario (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL,
+    user_id INTEGER,
+    delta INTEGER NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('reposicion','deterioro','perdida','correccion')),
+    note TEXT NOT NULL DEFAULT '',
+    quantity_after INTEGER NOT NULL,
+    created_at TEXT NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id)
+);
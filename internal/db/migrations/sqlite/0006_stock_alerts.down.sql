@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS AlertaStock;
+ALTER TABLE Libro DROP COLUMN reorder_threshold;
@@ -0,0 +1,14 @@
+ALTER TABLE Libro ADD COLUMN reorder_threshold INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS AlertaStock (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_id INTEGER NOT NULL,
+    available_quantity INTEGER NOT NULL,
+    reorder_threshold INTEGER NOT NULL,
+    daily_velocity REAL NOT NULL DEFAULT 0,
+    suggested_quantity INTEGER NOT NULL,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    resolved_at TEXT,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
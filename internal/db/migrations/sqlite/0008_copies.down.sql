@@ -0,0 +1,3 @@
+ALTER TABLE Venta DROP COLUMN copy_id;
+ALTER TABLE Prestamo DROP COLUMN copy_id;
+DROP TABLE IF EXISTS Ejemplar;
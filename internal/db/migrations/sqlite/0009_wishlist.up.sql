@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS ListaDeseos (
+    user_id INTEGER NOT NULL,
+    book_id INTEGER NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (user_id, book_id),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+CREATE TABLE IF NOT EXISTS Notificacion (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    book_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    read_at TEXT,
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
@@ -0,0 +1 @@
+ALTER TABLE Libro DROP COLUMN version;
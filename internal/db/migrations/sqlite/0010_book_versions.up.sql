@@ -0,0 +1 @@
+ALTER TABLE Libro ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
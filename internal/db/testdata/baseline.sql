@@ -0,0 +1,50 @@
+-- schema.sql del servidor antes de las migraciones (commit inicial), con algunas filas
+CREATE TABLE IF NOT EXISTS Usuario (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name TEXT NOT NULL,
+	last_name TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL UNIQUE,
+	password TEXT NOT NULL,
+	usm_pesos INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS Libro (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    book_name TEXT NOT NULL,
+    book_category TEXT NOT NULL,
+    transaction_type TEXT NOT NULL,
+    price INTEGER NOT NULL,
+    status BOOLEAN NOT NULL DEFAULT 1,
+    popularity_score INTEGER DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS Inventario (
+    book_id INTEGER PRIMARY KEY,
+    available_quantity INTEGER NOT NULL,
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+); 
+
+CREATE TABLE IF NOT EXISTS Prestamo (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    book_id INTEGER,
+    start_date TEXT NOT NULL,
+    return_date TEXT NOT NULL,
+    status TEXT NOT NULL CHECK (status IN ('pendiente','finalizado')),
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+CREATE TABLE IF NOT EXISTS Venta (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER,
+    book_id INTEGER,
+    sale_date DATE NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES Usuario(id),
+    FOREIGN KEY (book_id) REFERENCES Libro(id)
+);
+
+INSERT INTO Usuario (first_name, last_name, email, password, usm_pesos) VALUES ('Ana', 'Rojas', 'ana@usm.cl', 'x', 500);
+INSERT INTO Libro (book_name, book_category, transaction_type, price, status, popularity_score) VALUES ('Dune', 'ciencia ficcion', 'venta', 300, 1, 4);
+INSERT INTO Inventario (book_id, available_quantity) VALUES (1, 3);
+INSERT INTO Venta (user_id, book_id, sale_date) VALUES (1, 1, '2024-04-01');
@@ -0,0 +1,138 @@
+// Package health implementa las sondas de vida (/livez) y de preparación
+// (/readyz) que usan el orquestador y el balanceador.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"uzm-server/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Estados de un chequeo y de la respuesta completa
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// checkTimeout acota cada chequeo para que /readyz responda aunque la base
+// esté bloqueada.
+const checkTimeout = 2 * time.Second
+
+// Check es una verificación con nombre. Devuelve un detalle opcional para
+// mostrar cuando está sana y un error cuando no lo está.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (detail string, err error)
+}
+
+// Result es el resultado de un chequeo en la respuesta de /readyz
+type Result struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMS float64 `json:"duration_ms"`
+	Detail     string  `json:"detail,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Report es el cuerpo de /livez y /readyz
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks,omitempty"`
+}
+
+// Checker ejecuta los chequeos de preparación
+type Checker struct {
+	checks []Check
+}
+
+// NewChecker crea un Checker con los chequeos indicados
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Add agrega un chequeo
+func (c *Checker) Add(check Check) {
+	c.checks = append(c.checks, check)
+}
+
+// Run ejecuta todos los chequeos en paralelo, cada uno con su timeout
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Go(func() { results[i] = run(ctx, check) })
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check.Run(ctx)
+	r := Result{
+		Name:       check.Name,
+		Status:     StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:     detail,
+	}
+	if err != nil {
+		r.Status = StatusFail
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Livez responde 200 mientras el proceso pueda atender solicitudes. No revisa
+// dependencias: si la base cae, reiniciar el proceso no la arregla.
+func Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusOK})
+}
+
+// Readyz responde 200 si todos los chequeos pasan y 503 si alguno falla,
+// siempre con el resultado de cada uno.
+func (c *Checker) Readyz(ctx *gin.Context) {
+	report := c.Run(ctx.Request.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(status, report)
+}
+
+// Database revisa que la base de datos responda
+func Database(conn *sql.DB) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) (string, error) {
+		return "", conn.PingContext(ctx)
+	}}
+}
+
+// Schema revisa que la base tenga aplicadas exactamente las migraciones que
+// trae el binario para su motor y que las tablas tengan las columnas que se
+// consultan.
+func Schema(conn *db.DB) Check {
+	return Check{Name: "schema", Run: func(ctx context.Context) (string, error) {
+		version, err := db.CheckSchema(ctx, conn)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("versión %d", version), nil
+	}}
+}
@@ -0,0 +1,94 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"uzm-server/internal/health"
+
+	"github.com/gin-gonic/gin"
+)
+
+func check(name string, err error) health.Check {
+	return health.Check{Name: name, Run: func(ctx context.Context) (string, error) { return "detalle de " + name, err }}
+}
+
+func TestProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		path   string
+		checks []health.Check
+		status int
+		want   []string
+	}{
+		{"Livez", "/livez", []health.Check{check("database", errors.New("caída"))}, 200, []string{`{"status":"ok"}`}},
+		{"ReadyNoChecks", "/readyz", nil, 200, []string{`"status":"ok"`}},
+		{"Ready", "/readyz", []health.Check{check("database", nil), check("schema", nil)}, 200,
+			[]string{`"name":"database"`, `"detail":"detalle de schema"`}},
+		{"NotReady", "/readyz", []health.Check{check("database", nil), check("schema", errors.New("faltan migraciones"))}, 503,
+			[]string{`"status":"fail"`, `"name":"schema","status":"fail"`, `"error":"faltan migraciones"`}},
+		{"Timeout", "/readyz", []health.Check{{Name: "lenta", Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}}}, 503, []string{`"error":"context deadline exceeded"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/livez", health.Livez)
+			r.GET("/readyz", health.NewChecker(tt.checks...).Readyz)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, quiero %d; cuerpo: %s", w.Code, tt.status, w.Body.String())
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Fatalf("el cuerpo no contiene %q: %s", s, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWorkerCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []error
+		wait    time.Duration
+		wantErr string
+		detail  string
+	}{
+		{"NoRuns", nil, 0, "", "sin ejecuciones aún"},
+		{"OK", []error{nil}, 0, "", "última ejecución hace"},
+		{"Recovered", []error{errors.New("base caída"), nil}, 0, "", "última ejecución hace"},
+		{"Failed", []error{nil, errors.New("base caída")}, 0, "falló: base caída", ""},
+		{"Stale", []error{nil}, 250 * time.Millisecond, "sin ejecutarse hace", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := health.NewWorker("prueba", 100*time.Millisecond)
+			for _, err := range tt.reports {
+				w.Report(err)
+			}
+			time.Sleep(tt.wait)
+			detail, err := w.Check().Run(context.Background())
+			if tt.wantErr == "" {
+				if err != nil || !strings.Contains(detail, tt.detail) {
+					t.Fatalf("Check = %q, %v; quiero %q sin error", detail, err, tt.detail)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check = %q, %v; quiero un error con %q", detail, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Worker sigue el estado de un job en segundo plano. El job llama a Report
+// después de cada ejecución.
+type Worker struct {
+	name     string
+	interval time.Duration
+	started  time.Time
+
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+	runs    int64
+}
+
+// NewWorker crea el seguimiento de un job que corre cada interval
+func NewWorker(name string, interval time.Duration) *Worker {
+	return &Worker{name: name, interval: interval, started: time.Now()}
+}
+
+// Report registra el resultado de una ejecución del job
+func (w *Worker) Report(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastRun = time.Now()
+	w.lastErr = err
+	w.runs++
+}
+
+// Check falla si la última ejecución del job terminó con error o si lleva
+// más de dos intervalos sin ejecutarse. Un job que falla reintenta solo en la
+// siguiente vuelta; mientras tanto la instancia no se considera preparada.
+func (w *Worker) Check() Check {
+	return Check{Name: "worker:" + w.name, Run: func(context.Context) (string, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		last := w.lastRun
+		if w.runs == 0 {
+			last = w.started
+		}
+		if since := time.Since(last); since > 2*w.interval {
+			return "", fmt.Errorf("sin ejecutarse hace %s (intervalo %s)", since.Round(time.Second), w.interval)
+		}
+		switch {
+		case w.runs == 0:
+			return "sin ejecuciones aún", nil
+		case w.lastErr != nil:
+			return "", fmt.Errorf("la última ejecución, hace %s, falló: %w", time.Since(w.lastRun).Round(time.Second), w.lastErr)
+		}
+		return fmt.Sprintf("última ejecución hace %s", time.Since(w.lastRun).Round(time.Second)), nil
+	}}
+}
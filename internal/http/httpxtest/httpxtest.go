@@ -0,0 +1,86 @@
+// Package httpxtest prueba handlers con httptest: monta sus rutas bajo
+// /api/v1 con el middleware de errores, igual que main, y corre casos de
+// tabla contra ellas.
+package httpxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	httpx "uzm-server/internal/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router monta las rutas de register bajo /api/v1 con httpx.Problems
+func Router(register func(*gin.RouterGroup)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(httpx.Problems())
+	r.NoRoute(httpx.NoRoute)
+	register(r.Group("/api/v1"))
+	return r
+}
+
+// Case es una solicitud y lo que se espera de su respuesta
+type Case struct {
+	Name   string
+	Method string
+	Path   string            // relativo a /api/v1
+	Body   string            // JSON; vacío = sin cuerpo
+	Header map[string]string // encabezados extra
+	Status int               // status esperado
+	Code   string            // code del problem+json, si la respuesta es un error
+	Want   []string          // fragmentos que el cuerpo debe contener
+}
+
+// Run corre cada caso como una subprueba
+func Run(t *testing.T, router http.Handler, cases []Case) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			w := Do(router, tc.Method, tc.Path, tc.Body, tc.Header)
+			Check(t, w, tc.Status, tc.Code, tc.Want...)
+		})
+	}
+}
+
+// Do hace una solicitud a /api/v1+path y devuelve la respuesta grabada
+func Do(router http.Handler, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// Check compara el status, el code del problem+json y los fragmentos del cuerpo
+func Check(t *testing.T, w *httptest.ResponseRecorder, status int, code string, want ...string) {
+	t.Helper()
+	body := w.Body.String()
+	if w.Code != status {
+		t.Fatalf("status = %d, quiero %d; cuerpo: %s", w.Code, status, body)
+	}
+	if code != "" {
+		var p httpx.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil || p.Code != code {
+			t.Fatalf("code = %q, quiero %q; cuerpo: %s", p.Code, code, body)
+		}
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, httpx.ProblemContentType) {
+			t.Fatalf("Content-Type = %q, quiero %s", ct, httpx.ProblemContentType)
+		}
+	}
+	for _, s := range want {
+		if !strings.Contains(body, s) {
+			t.Fatalf("el cuerpo no contiene %q: %s", s, body)
+		}
+	}
+}
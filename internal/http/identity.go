@@ -0,0 +1,34 @@
+package httpx
+
+import (
+	"strconv"
+
+	"uzm-server/internal/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDHeader identifica al usuario que hace la solicitud. La API aún no tiene
+// sesiones, así que el cliente lo envía en cada operación que lo requiere.
+const UserIDHeader = "X-User-ID"
+
+var ErrMissingUser = apperr.Unauthorized("missing_user", "falta el encabezado "+UserIDHeader+" o no es válido")
+
+// UserID lee el usuario que hace la solicitud desde X-User-ID
+func UserID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.GetHeader(UserIDHeader), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// RequireUserID es UserID para las operaciones que exigen usuario: si falta
+// deja ErrMissingUser para el middleware de errores
+func RequireUserID(c *gin.Context) (int64, bool) {
+	id, ok := UserID(c)
+	if !ok {
+		_ = c.Error(ErrMissingUser)
+	}
+	return id, ok
+}
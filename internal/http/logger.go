@@ -0,0 +1,67 @@
+package httpx
+
+import (
+	"log/slog"
+	"time"
+
+	"uzm-server/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader correlaciona una solicitud entre el cliente, el log de
+// acceso y los errores que registren los servicios y repositorios
+const RequestIDHeader = "X-Request-ID"
+
+// RequestLogger asigna un id a cada solicitud (o reutiliza el X-Request-ID que
+// trae), lo deja en el contexto y en la respuesta, y al terminar escribe una
+// línea de acceso. No registra cuerpos ni parámetros de consulta.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		ctx := logging.WithRequestID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID, ok := UserID(c); ok {
+			attrs = append(attrs, slog.Int64("user_id", userID))
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "solicitud", attrs...)
+	}
+}
+
+// validRequestID acepta ids de clientes cortos y sin caracteres de control
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"uzm-server/internal/apperr"
+	"uzm-server/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType es el tipo de las respuestas de error (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// Problem es el cuerpo de una respuesta de error. Code es estable y es lo
+// que deben comparar los clientes; Detail es el mensaje para personas.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []apperr.FieldError `json:"errors,omitempty"`
+}
+
+var statusByKind = map[apperr.Kind]int{
+	apperr.KindInternal:             http.StatusInternalServerError,
+	apperr.KindNotFound:             http.StatusNotFound,
+	apperr.KindConflict:             http.StatusConflict,
+	apperr.KindValidation:           http.StatusBadRequest,
+	apperr.KindInsufficientFunds:    http.StatusPaymentRequired,
+	apperr.KindForbidden:            http.StatusForbidden,
+	apperr.KindUnauthorized:         http.StatusUnauthorized,
+	apperr.KindPreconditionFailed:   http.StatusPreconditionFailed,
+	apperr.KindPreconditionRequired: http.StatusPreconditionRequired,
+	apperr.KindTooLarge:             http.StatusRequestEntityTooLarge,
+	apperr.KindUnsupportedMedia:     http.StatusUnsupportedMediaType,
+}
+
+var (
+	errInternal     = apperr.New(apperr.KindInternal, "internal_error", "error interno del servidor")
+	errInvalidBody  = apperr.Validation("invalid_body", "el cuerpo de la solicitud no es válido")
+	errRouteMissing = apperr.NotFound("route_not_found", "la ruta no existe")
+)
+
+func init() {
+	// Los errores de validación nombran los campos como en el JSON
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(f.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// Problems responde con problem+json el último error que el handler dejó con
+// c.Error. Los errores marcados como gin.ErrorTypeBind vienen de leer el cuerpo
+// o los parámetros y se informan como validación con detalle por campo.
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		last := c.Errors.Last()
+		if last.IsType(gin.ErrorTypeBind) {
+			WriteProblem(c, bindProblem(last.Err))
+			return
+		}
+		WriteProblem(c, last.Err)
+	}
+}
+
+// WriteProblem escribe err como problem+json. Los errores que no son de
+// dominio se registran en el log y se informan como error interno.
+func WriteProblem(c *gin.Context, err error) {
+	e, ok := apperr.As(err)
+	if !ok {
+		logging.FromContext(c.Request.Context()).Error("error interno",
+			"method", c.Request.Method, "path", c.Request.URL.Path, "err", err)
+		e = errInternal
+		err = errInternal
+	}
+	status, ok := statusByKind[e.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: c.Request.URL.Path,
+		Code:     e.Code,
+		Errors:   e.Fields,
+	})
+}
+
+// BindError deja para Problems un error al leer el cuerpo de la solicitud
+func BindError(c *gin.Context, err error) {
+	_ = c.Error(err).SetType(gin.ErrorTypeBind)
+}
+
+// ParamID lee un id numérico positivo de la ruta. Si no lo es deja el error
+// de validación y devuelve false; el handler solo debe retornar.
+func ParamID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		_ = c.Error(apperr.InvalidParam(name, "debe ser un id numérico positivo"))
+		return 0, false
+	}
+	return id, true
+}
+
+// NoRoute responde problem+json para rutas que no existen
+func NoRoute(c *gin.Context) {
+	WriteProblem(c, errRouteMissing)
+}
+
+// bindProblem traduce los errores de gin/validator a un error de validación
+func bindProblem(err error) error {
+	if _, ok := apperr.As(err); ok {
+		return err
+	}
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]apperr.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, apperr.FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return apperr.Validation("validation_failed", "la solicitud tiene campos inválidos").WithFields(fields...)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return apperr.Validation("validation_failed", "la solicitud tiene campos inválidos").WithFields(apperr.FieldError{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "debe ser de tipo " + typeErr.Type.String(),
+		})
+	}
+	if errors.Is(err, io.EOF) {
+		return apperr.Validation("invalid_body", "falta el cuerpo de la solicitud")
+	}
+	return errInvalidBody
+}
+
+// fieldPath quita el nombre del struct raíz: "createRequest.email" -> "email"
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "es obligatorio"
+	case "email":
+		return "debe ser un email válido"
+	case "min", "gte":
+		return "debe ser al menos " + fe.Param()
+	case "max", "lte":
+		return "debe ser como máximo " + fe.Param()
+	case "oneof":
+		return "debe ser uno de: " + fe.Param()
+	}
+	return "no cumple la regla " + fe.Tag()
+}
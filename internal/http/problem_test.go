@@ -0,0 +1,106 @@
+package httpx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"uzm-server/internal/apperr"
+	httpx "uzm-server/internal/http"
+	"uzm-server/internal/http/httpxtest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failWith es un handler que deja err para el middleware de errores
+func failWith(err error) gin.HandlerFunc {
+	return func(c *gin.Context) { _ = c.Error(err) }
+}
+
+type createRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Age   int    `json:"age" binding:"min=18"`
+}
+
+func routes(r *gin.RouterGroup) {
+	r.GET("/not-found", failWith(apperr.NotFound("book_not_found", "libro no encontrado")))
+	r.GET("/conflict", failWith(apperr.Conflict("book_on_loan", "el libro tiene préstamos pendientes")))
+	r.GET("/validation", failWith(apperr.Validation("invalid_book", "libro inválido")))
+	r.GET("/funds", failWith(apperr.InsufficientFunds("insufficient_funds", "saldo insuficiente")))
+	r.GET("/forbidden", failWith(apperr.Forbidden("not_owner", "no es tuyo")))
+	r.GET("/unauthorized", failWith(apperr.Unauthorized("missing_user", "falta X-User-ID")))
+	r.GET("/precondition", failWith(apperr.New(apperr.KindPreconditionFailed, "version_mismatch", "el libro cambió")))
+	r.GET("/precondition-required", failWith(apperr.New(apperr.KindPreconditionRequired, "if_match_required", "falta If-Match")))
+	r.GET("/too-large", failWith(apperr.New(apperr.KindTooLarge, "image_too_large", "imagen muy grande")))
+	r.GET("/media", failWith(apperr.New(apperr.KindUnsupportedMedia, "unsupported_image", "formato no soportado")))
+	r.GET("/wrapped", failWith(fmt.Errorf("%w: falta el nombre", apperr.Validation("invalid_book", "libro inválido"))))
+	r.GET("/internal", failWith(errors.New("pq: la conexión se cerró")))
+	r.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "listo")
+		_ = c.Error(errors.New("después de responder"))
+	})
+	r.GET("/items/:id", func(c *gin.Context) {
+		if _, ok := httpx.ParamID(c, "id"); ok {
+			c.Status(http.StatusNoContent)
+		}
+	})
+	r.POST("/items", func(c *gin.Context) {
+		var req createRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			httpx.BindError(c, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+}
+
+// Cada clase de error de dominio sale con su status, su código y problem+json
+func TestProblems(t *testing.T) {
+	router := httpxtest.Router(routes)
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "NotFound", Method: "GET", Path: "/not-found", Status: 404, Code: "book_not_found", Want: []string{`"title":"Not Found"`, `"detail":"libro no encontrado"`}},
+		{Name: "Conflict", Method: "GET", Path: "/conflict", Status: 409, Code: "book_on_loan"},
+		{Name: "Validation", Method: "GET", Path: "/validation", Status: 400, Code: "invalid_book"},
+		{Name: "InsufficientFunds", Method: "GET", Path: "/funds", Status: 402, Code: "insufficient_funds"},
+		{Name: "Forbidden", Method: "GET", Path: "/forbidden", Status: 403, Code: "not_owner"},
+		{Name: "Unauthorized", Method: "GET", Path: "/unauthorized", Status: 401, Code: "missing_user"},
+		{Name: "PreconditionFailed", Method: "GET", Path: "/precondition", Status: 412, Code: "version_mismatch"},
+		{Name: "PreconditionRequired", Method: "GET", Path: "/precondition-required", Status: 428, Code: "if_match_required"},
+		{Name: "TooLarge", Method: "GET", Path: "/too-large", Status: 413, Code: "image_too_large"},
+		{Name: "UnsupportedMedia", Method: "GET", Path: "/media", Status: 415, Code: "unsupported_image"},
+		{Name: "Wrapped", Method: "GET", Path: "/wrapped", Status: 400, Code: "invalid_book", Want: []string{`"detail":"libro inválido: falta el nombre"`}},
+		{Name: "Instance", Method: "GET", Path: "/conflict", Status: 409, Code: "book_on_loan", Want: []string{`"instance":"/api/v1/conflict"`, `"type":"about:blank"`}},
+		{Name: "NoRoute", Method: "GET", Path: "/nada", Status: 404, Code: "route_not_found"},
+		{Name: "ParamID", Method: "GET", Path: "/items/x", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"id"`}},
+		{Name: "ParamIDZero", Method: "GET", Path: "/items/0", Status: 400, Code: "invalid_parameter"},
+		{Name: "ParamIDOK", Method: "GET", Path: "/items/3", Status: 204},
+		{Name: "AlreadyWritten", Method: "GET", Path: "/written", Status: 200, Want: []string{"listo"}},
+
+		{Name: "BindRequired", Method: "POST", Path: "/items", Body: `{"age":20}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"email"`, `"code":"required"`, `"message":"es obligatorio"`}},
+		{Name: "BindRules", Method: "POST", Path: "/items", Body: `{"email":"no","age":3}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"code":"email"`, `"field":"age"`, `"message":"debe ser al menos 18"`}},
+		{Name: "BindType", Method: "POST", Path: "/items", Body: `{"email":"a@usm.cl","age":"veinte"}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"age"`, `"code":"type"`}},
+		{Name: "BindMalformed", Method: "POST", Path: "/items", Body: `{"email":`, Status: 400, Code: "invalid_body"},
+		{Name: "BindEmpty", Method: "POST", Path: "/items", Status: 400, Code: "invalid_body", Want: []string{"falta el cuerpo"}},
+	})
+}
+
+// Los errores sin clasificar son 500 y no muestran el mensaje original
+func TestProblemsHideInternalErrors(t *testing.T) {
+	router := httpxtest.Router(routes)
+	w := httpxtest.Do(router, "GET", "/internal", "", nil)
+	httpxtest.Check(t, w, http.StatusInternalServerError, "internal_error")
+
+	var p httpx.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != 500 || p.Title != "Internal Server Error" || p.Detail != "error interno del servidor" {
+		t.Fatalf("problem = %+v, quiero el detalle genérico", p)
+	}
+}
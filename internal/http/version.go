@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marca las respuestas de un grupo de rutas obsoleto: Deprecation
+// con la fecha desde la que lo está (RFC 9745), Sunset con la fecha en que se
+// retira (RFC 8594) y un Link a la misma ruta bajo successor, la versión que
+// lo reemplaza. base es el prefijo del grupo obsoleto.
+func Deprecated(base, successor string, since, sunset time.Time) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	base = strings.TrimRight(base, "/")
+	successor = strings.TrimRight(successor, "/")
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("Deprecation", deprecation)
+		h.Set("Sunset", sunsetDate)
+		if rest, ok := strings.CutPrefix(c.Request.URL.Path, base); ok {
+			h.Add("Link", "<"+successor+rest+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,62 @@
+// Package logging configura slog para el servidor y lleva el id de la
+// solicitud en el context.Context, de modo que cualquier capa (handler,
+// servicio o repositorio) registre sus mensajes asociados a la solicitud.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type requestIDKey struct{}
+
+// Setup deja como logger por defecto uno JSON o de texto con el nivel dado.
+// Los mensajes del paquete log también pasan por él.
+func Setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("nivel de log inválido %q", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("formato de log inválido %q", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// NewRequestID genera un id aleatorio de 16 caracteres hexadecimales
+func NewRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID devuelve el id de la solicitud o "" si ctx no viene de una
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext es el logger por defecto con el request_id de ctx, si hay
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
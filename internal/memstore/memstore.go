@@ -0,0 +1,267 @@
+// Package memstore es una base de datos en memoria para el modo
+// --storage=memory y para las pruebas: las mismas tablas del esquema SQL,
+// guardadas como mapas por id, detrás de un solo candado.
+//
+// Los repositorios en memoria de cada paquete (books.NewMemoryRepository,
+// users.NewMemoryRepository, ...) comparten un Store, así una venta hecha por
+// copies se ve en las recomendaciones de books igual que con SQL. Cada
+// escritura trabaja sobre una copia de las tablas que solo se publica si la
+// operación termina sin error: es una transacción serializable, y un error a
+// medio camino no deja cambios a medias. Copiar las tablas en cada escritura
+// es caro con muchos datos; el Store está pensado para demos y pruebas.
+package memstore
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// User es una fila de Usuario
+type User struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+	USMPesos  int64
+}
+
+// Book es una fila de Libro
+type Book struct {
+	ID               int64
+	Name             string
+	Category         string
+	TransactionType  string
+	Price            int64
+	RentalPrice      *int64
+	RentalPeriodDays int64
+	Status           bool
+	PopularityScore  int64
+	ArchivedAt       *time.Time
+	ReorderThreshold int64
+	CoverContentType *string
+	CoverUpdatedAt   *time.Time
+	Version          int64
+}
+
+// Inventory es la fila de Inventario de un libro
+type Inventory struct {
+	BookID    int64
+	Available int64
+	Rental    int64
+}
+
+// Copy es una fila de Ejemplar
+type Copy struct {
+	ID        int64
+	BookID    int64
+	Barcode   string
+	Condition string
+	Location  string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Estados de un préstamo
+const (
+	LoanPending  = "pendiente"
+	LoanFinished = "finalizado"
+)
+
+// Loan es una fila de Prestamo
+type Loan struct {
+	ID         int64
+	UserID     int64
+	BookID     int64
+	CopyID     int64
+	StartDate  time.Time
+	ReturnDate time.Time
+	Status     string
+}
+
+// Sale es una fila de Venta
+type Sale struct {
+	ID     int64
+	UserID int64
+	BookID int64
+	CopyID int64
+	Date   time.Time
+	Price  int64 // USM Pesos cobrados
+}
+
+// Review es una fila de Resena
+type Review struct {
+	ID        int64
+	UserID    int64
+	BookID    int64
+	Rating    int
+	Comment   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PriceChange es una fila de HistorialPrecio
+type PriceChange struct {
+	ID        int64
+	BookID    int64
+	OldPrice  *int64
+	NewPrice  int64
+	ChangedAt time.Time
+}
+
+// Promotion es una fila de Promocion
+type Promotion struct {
+	ID            int64
+	BookID        *int64
+	BookCategory  *string
+	DiscountType  string
+	DiscountValue int64
+	StartsAt      time.Time
+	EndsAt        time.Time
+}
+
+// Adjustment es una fila de AjusteInventario
+type Adjustment struct {
+	ID            int64
+	BookID        int64
+	UserID        *int64
+	Delta         int64
+	Reason        string
+	Note          string
+	QuantityAfter int64
+	CreatedAt     time.Time
+}
+
+// StockAlert es una fila de AlertaStock
+type StockAlert struct {
+	ID                int64
+	BookID            int64
+	AvailableQuantity int64
+	ReorderThreshold  int64
+	DailyVelocity     float64
+	SuggestedQuantity int64
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	ResolvedAt        *time.Time
+}
+
+// WishlistKey identifica una fila de ListaDeseos
+type WishlistKey struct {
+	UserID int64
+	BookID int64
+}
+
+// Notification es una fila de Notificacion
+type Notification struct {
+	ID        int64
+	UserID    int64
+	BookID    int64
+	Kind      string
+	Message   string
+	CreatedAt time.Time
+	ReadAt    *time.Time
+}
+
+// Tables son las tablas del esquema. Las filas se guardan por valor; quien
+// modifica una fila la vuelve a asignar en el mapa.
+type Tables struct {
+	Users         map[int64]User
+	Books         map[int64]Book
+	Inventory     map[int64]Inventory // por book_id
+	Copies        map[int64]Copy
+	Loans         map[int64]Loan
+	Sales         map[int64]Sale
+	Reviews       map[int64]Review
+	PriceChanges  map[int64]PriceChange
+	Promotions    map[int64]Promotion
+	Adjustments   map[int64]Adjustment
+	StockAlerts   map[int64]StockAlert
+	Wishlist      map[WishlistKey]time.Time // fecha en que se agregó
+	Notifications map[int64]Notification
+
+	seq map[string]int64 // último id entregado por tabla, como AUTOINCREMENT
+}
+
+func newTables() *Tables {
+	return &Tables{
+		Users:         map[int64]User{},
+		Books:         map[int64]Book{},
+		Inventory:     map[int64]Inventory{},
+		Copies:        map[int64]Copy{},
+		Loans:         map[int64]Loan{},
+		Sales:         map[int64]Sale{},
+		Reviews:       map[int64]Review{},
+		PriceChanges:  map[int64]PriceChange{},
+		Promotions:    map[int64]Promotion{},
+		Adjustments:   map[int64]Adjustment{},
+		StockAlerts:   map[int64]StockAlert{},
+		Wishlist:      map[WishlistKey]time.Time{},
+		Notifications: map[int64]Notification{},
+		seq:           map[string]int64{},
+	}
+}
+
+func (t *Tables) clone() *Tables {
+	return &Tables{
+		Users:         maps.Clone(t.Users),
+		Books:         maps.Clone(t.Books),
+		Inventory:     maps.Clone(t.Inventory),
+		Copies:        maps.Clone(t.Copies),
+		Loans:         maps.Clone(t.Loans),
+		Sales:         maps.Clone(t.Sales),
+		Reviews:       maps.Clone(t.Reviews),
+		PriceChanges:  maps.Clone(t.PriceChanges),
+		Promotions:    maps.Clone(t.Promotions),
+		Adjustments:   maps.Clone(t.Adjustments),
+		StockAlerts:   maps.Clone(t.StockAlerts),
+		Wishlist:      maps.Clone(t.Wishlist),
+		Notifications: maps.Clone(t.Notifications),
+		seq:           maps.Clone(t.seq),
+	}
+}
+
+// NextID entrega el siguiente id de la tabla; los ids no se reutilizan
+func (t *Tables) NextID(table string) int64 {
+	t.seq[table]++
+	return t.seq[table]
+}
+
+// Store guarda las tablas y serializa las escrituras
+type Store struct {
+	mu sync.RWMutex
+	t  *Tables
+}
+
+// New entrega un Store vacío
+func New() *Store {
+	return &Store{t: newTables()}
+}
+
+// View da a fn acceso de solo lectura a las tablas. fn no debe modificarlas
+// ni guardar los mapas después de volver; las filas sí se pueden copiar.
+func (s *Store) View(fn func(t *Tables) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(s.t)
+}
+
+// Update corre fn sobre una copia de las tablas y la publica solo si fn no
+// devuelve error. Las escrituras no se intercalan entre sí.
+func (s *Store) Update(fn func(t *Tables) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.t.clone()
+	if err := fn(next); err != nil {
+		return err
+	}
+	s.t = next
+	return nil
+}
+
+// Stamp deja t con la precisión de las fechas guardadas en SQL (RFC3339,
+// segundos), para que ambos motores devuelvan lo mismo
+func Stamp(t time.Time) time.Time {
+	return t.Truncate(time.Second)
+}
@@ -0,0 +1,74 @@
+// Package metrics expone las métricas del servidor en formato Prometheus:
+// solicitudes HTTP por ruta, el pool de database/sql, el runtime de Go y
+// métricas de negocio que se calculan desde la base de datos en cada lectura.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"uzm-server/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// New registra todas las métricas sobre un registro propio, así /metrics no
+// depende del registro global de la librería. Sin conn (almacenamiento en
+// memoria) no hay métricas del pool ni de negocio.
+func New(conn *db.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Solicitudes HTTP atendidas, por método, ruta y status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duración de las solicitudes HTTP, por método y ruta.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	if conn != nil {
+		m.registry.MustRegister(
+			collectors.NewDBStatsCollector(conn.DB, "uzm"),
+			newBusinessCollector(conn),
+		)
+	}
+	return m
+}
+
+// Middleware mide cada solicitud. La ruta es el patrón de Gin (/api/v1/books/:id)
+// y no la URL, para que los ids no multipliquen las series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "sin_ruta"
+		}
+		m.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.latency.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler responde GET /metrics
+func (m *Metrics) Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
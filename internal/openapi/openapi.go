@@ -0,0 +1,88 @@
+// Package openapi publica la especificación OpenAPI de la API y valida las
+// solicitudes contra ella.
+package openapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// La especificación se escribe a mano en YAML y va dentro del binario. Al
+// agregar o cambiar una ruta de la API hay que actualizarla: Validate
+// rechaza las que no están.
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed docs.html
+var docsHTML []byte
+
+// Spec es la especificación cargada, lista para servir y validar
+type Spec struct {
+	doc  *openapi3.T
+	json []byte
+	// routes traduce la ruta de gin sin el prefijo de la versión
+	// ("/books/:id") a la operación
+	routes map[string]route
+}
+
+type route struct {
+	path string // como en la especificación: "/books/{id}"
+	item *openapi3.PathItem
+}
+
+// Load lee y valida la especificación embebida
+func Load() (*Spec, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(specYAML)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("openapi: especificación inválida: %w", err)
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+
+	routes := make(map[string]route, doc.Paths.Len())
+	for path, item := range doc.Paths.Map() {
+		routes[ginPath(path)] = route{path: path, item: item}
+	}
+	return &Spec{doc: doc, json: body, routes: routes}, nil
+}
+
+// ginPath pasa los parámetros de "{id}" a ":id"
+func ginPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			parts[i] = ":" + p[1:len(p)-1]
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// RegisterRoutes publica la especificación y la página de documentación
+func (s *Spec) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/openapi.json", s.serveJSON)
+	r.GET("/docs", serveDocs)
+}
+
+func (s *Spec) serveJSON(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", s.json)
+}
+
+// serveDocs entrega una página que lee openapi.json y la muestra; no depende
+// de recursos externos, así que funciona sin conexión.
+func serveDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsHTML)
+}
@@ -0,0 +1,158 @@
+package openapi_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/covers"
+	"uzm-server/internal/http/httpxtest"
+	"uzm-server/internal/openapi"
+	"uzm-server/internal/reviews"
+	"uzm-server/internal/users"
+	"uzm-server/internal/wishlist"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Router con la validación delante de handlers que siempre responden 200,
+// así cualquier error viene de la especificación
+func newRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	spec := load(t)
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) }
+	return httpxtest.Router(func(api *gin.RouterGroup) {
+		api.Use(spec.Validate(api.BasePath()))
+		spec.RegisterRoutes(api)
+		api.GET("/books", ok)
+		api.GET("/books/:id", ok)
+		api.PATCH("/books/:id", ok)
+		api.POST("/users", ok)
+		api.GET("/inventory/alerts", ok)
+		api.GET("/copies/:barcode", ok)
+		api.POST("/copies/:barcode/checkout", ok)
+		api.POST("/copies/:barcode/checkin", ok)
+		api.POST("/books/:id/reviews", ok)
+		api.GET("/books/:id/reviews", ok)
+		api.PUT("/books/:id/cover", ok)
+		api.POST("/users/:id/wishlist", ok)
+		api.GET("/users/:id/notifications", ok)
+		api.GET("/sin-documentar", ok)
+	})
+}
+
+func load(t *testing.T) *openapi.Spec {
+	t.Helper()
+	spec, err := openapi.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestRoutes(t *testing.T) {
+	router := newRouter(t)
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "Docs", Method: "GET", Path: "/docs", Status: 200, Want: []string{"openapi.json"}},
+		{Name: "JSON", Method: "GET", Path: "/openapi.json", Status: 200, Want: []string{`"openapi":"3.`, `"/books/{id}"`}},
+	})
+
+	w := httpxtest.Do(router, "GET", "/openapi.json", "", nil)
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || len(doc.Paths) == 0 {
+		t.Fatalf("openapi.json no trae rutas (%v): %s", err, w.Body.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	const user = `{"first_name":"Ana","last_name":"Rojas","email":"ana@usm.cl","password":"x"}`
+
+	httpxtest.Run(t, newRouter(t), []httpxtest.Case{
+		{Name: "Valid", Method: "GET", Path: "/books?status=true&mode=venta", Status: 200},
+		{Name: "UnknownQuery", Method: "GET", Path: "/books?color=rojo", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"color"`}},
+		{Name: "BadBool", Method: "GET", Path: "/books?status=quizas", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"status"`}},
+		{Name: "BadEnum", Method: "GET", Path: "/inventory/alerts?status=cerradas", Status: 400, Code: "invalid_parameter"},
+		{Name: "BadPathID", Method: "GET", Path: "/books/0", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"id"`}},
+
+		{Name: "ValidBody", Method: "POST", Path: "/users", Body: user, Status: 200},
+		{Name: "MissingFields", Method: "POST", Path: "/users", Body: `{"first_name":"Ana"}`, Status: 400, Code: "validation_failed",
+			Want: []string{`"field":"last_name"`, `"field":"password"`}},
+		{Name: "UnknownField", Method: "POST", Path: "/users", Body: `{"first_name":"Ana","last_name":"Rojas","email":"a@b.cl","password":"x","admin":true}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"admin"`}},
+		{Name: "WrongType", Method: "POST", Path: "/users", Body: `{"first_name":1,"last_name":"Rojas","email":"a@b.cl","password":"x"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"first_name"`}},
+		{Name: "NoBody", Method: "POST", Path: "/users", Status: 400, Code: "invalid_body"},
+		{Name: "NegativeStock", Method: "PATCH", Path: "/books/1", Body: `{"stock":-3}`, Header: map[string]string{"If-Match": "*"},
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"stock"`}},
+		{Name: "NotJSON", Method: "POST", Path: "/users", Body: user, Header: map[string]string{"Content-Type": "text/plain"},
+			Status: 415, Code: "unsupported_media_type"},
+
+		{Name: "CopyUnknownQuery", Method: "GET", Path: "/copies/LIB-1?lo=que-sea", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"lo"`}},
+		{Name: "CheckOut", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":1,"type":"venta"}`, Status: 200},
+		{Name: "CheckOutWrongType", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":"uno","type":"venta"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"user_id"`}},
+		{Name: "CheckOutUnknownField", Method: "POST", Path: "/copies/LIB-1/checkout", Body: `{"user_id":1,"type":"venta","descuento":100}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"descuento"`}},
+		{Name: "CheckInNoBody", Method: "POST", Path: "/copies/LIB-1/checkin", Status: 200},
+		{Name: "CheckInUnknownField", Method: "POST", Path: "/copies/LIB-1/checkin", Body: `{"estado":"roto"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"estado"`}},
+		{Name: "ReviewUnknownField", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":5,"likes":3}`, Header: map[string]string{"X-User-ID": "1"},
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"likes"`}},
+		{Name: "ReviewPageSize", Method: "GET", Path: "/books/1/reviews?page_size=500", Status: 400, Code: "invalid_parameter", Want: []string{`"field":"page_size"`}},
+		{Name: "WishlistWrongType", Method: "POST", Path: "/users/1/wishlist", Body: `{"book_id":"7"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"book_id"`}},
+		{Name: "NotificationsBadBool", Method: "GET", Path: "/users/1/notifications?unread=tal-vez", Status: 400, Code: "invalid_parameter"},
+		{Name: "CoverNotMultipart", Method: "PUT", Path: "/books/1/cover", Body: `{"cover":"x"}`, Status: 415, Code: "unsupported_media_type",
+			Want: []string{"multipart/form-data"}},
+
+		// Una ruta que la especificación no documenta no llega al handler
+		{Name: "Undocumented", Method: "GET", Path: "/sin-documentar", Status: 500, Code: "internal_error"},
+	})
+}
+
+// La portada va en multipart: se acepta sin que la validación lea la imagen
+func TestValidateMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("cover", "portada.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte("\x89PNG no es una imagen de verdad"))
+	_ = mw.Close()
+
+	w := httpxtest.Do(newRouter(t), "PUT", "/books/1/cover", body.String(), map[string]string{"Content-Type": mw.FormDataContentType()})
+	httpxtest.Check(t, w, http.StatusOK, "")
+}
+
+// Todas las rutas que registran los handlers de la API están en la
+// especificación; si no, Validate las rechaza
+func TestCheck(t *testing.T) {
+	spec := load(t)
+	router := httpxtest.Router(func(api *gin.RouterGroup) {
+		spec.RegisterRoutes(api)
+		users.NewHandler(nil).RegisterRoutes(api)
+		books.NewHandler(nil, 0).RegisterRoutes(api)
+		reviews.NewHandler(nil).RegisterRoutes(api)
+		covers.NewHandler(nil).RegisterRoutes(api)
+		copies.NewHandler(nil).RegisterRoutes(api)
+		wishlist.NewHandler(nil).RegisterRoutes(api)
+	})
+	if err := spec.Check(router.Routes(), "/api/v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	router.GET("/api/v1/sin-documentar", func(*gin.Context) {})
+	router.GET("/livez", func(*gin.Context) {})
+	err := spec.Check(router.Routes(), "/api/v1")
+	if err == nil || !strings.Contains(err.Error(), "GET /api/v1/sin-documentar") || strings.Contains(err.Error(), "livez") {
+		t.Fatalf("Check = %v, quiero que nombre solo GET /api/v1/sin-documentar", err)
+	}
+}
@@ -0,0 +1,322 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"uzm-server/internal/apperr"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errInvalidBody = apperr.Validation("invalid_body", "el cuerpo de la solicitud no es válido")
+	errMissingBody = apperr.Validation("invalid_body", "falta el cuerpo de la solicitud")
+)
+
+var validateOptions = &openapi3filter.Options{
+	MultiError:          true,
+	SkipSettingDefaults: true, // los handlers aplican sus propios valores por defecto
+	AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+}
+
+// Los cuerpos que no son JSON (la portada en multipart) los revisa el
+// handler, que además limita su tamaño; aquí solo se revisa el Content-Type
+var paramsOnlyOptions = &openapi3filter.Options{
+	ExcludeRequestBody: true,
+	AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+}
+
+// Validate revisa cada solicitud contra la operación que la especificación
+// documenta para su ruta: parámetros de ruta, consulta y encabezado, y el
+// cuerpo. Los parámetros de consulta y los campos del cuerpo que no están
+// documentados se rechazan. Las rutas del grupo que la especificación no
+// cubre son un error del servidor: responden 500 sin llegar al handler y
+// quedan en el log. Check las detecta antes, al arrancar.
+// base es el prefijo del grupo de rutas (/api/v1). Debe ir después de
+// httpx.Problems, que responde el error.
+func (s *Spec) Validate(base string) gin.HandlerFunc {
+	base = strings.TrimRight(base, "/")
+	return func(c *gin.Context) {
+		path, ok := strings.CutPrefix(c.FullPath(), base)
+		if !ok {
+			c.Next()
+			return
+		}
+		rt, op := s.operation(c.Request.Method, path)
+		if op == nil {
+			_ = c.Error(fmt.Errorf("openapi: %s %s no está en la especificación", c.Request.Method, c.FullPath()))
+			c.Abort()
+			return
+		}
+		if err := s.validate(c, rt, op); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// operation busca la operación documentada para el método y la ruta de gin
+// sin el prefijo; devuelve nil si no existe
+func (s *Spec) operation(method, path string) (route, *openapi3.Operation) {
+	rt, ok := s.routes[path]
+	if !ok {
+		return route{}, nil
+	}
+	return rt, rt.item.GetOperation(method)
+}
+
+// Check compara las rutas del router bajo base con la especificación y
+// devuelve un error que nombra las que no están documentadas. Se llama al
+// arrancar para no descubrirlas recién cuando Validate las rechaza.
+func (s *Spec) Check(routes gin.RoutesInfo, base string) error {
+	base = strings.TrimRight(base, "/")
+	var missing []string
+	for _, r := range routes {
+		path, ok := strings.CutPrefix(r.Path, base)
+		if !ok || path == "" || path[0] != '/' {
+			continue
+		}
+		if _, op := s.operation(r.Method, path); op == nil {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("openapi: rutas sin documentar: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func (s *Spec) validate(c *gin.Context, rt route, op *openapi3.Operation) error {
+	if err := unknownQuery(c, rt.item, op); err != nil {
+		return err
+	}
+	options := validateOptions
+	if op.RequestBody != nil && c.Request.ContentLength != 0 {
+		content := op.RequestBody.Value.Content
+		mt, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if mt == "" || content.Get(mt) == nil {
+			return unsupportedMedia(content)
+		}
+		if mt != "application/json" {
+			options = paramsOnlyOptions
+		}
+	}
+
+	params := make(map[string]string, len(c.Params))
+	for _, p := range c.Params {
+		params[p.Key] = p.Value
+	}
+	err := openapi3filter.ValidateRequest(c.Request.Context(), &openapi3filter.RequestValidationInput{
+		Request:    c.Request,
+		PathParams: params,
+		Route: &routers.Route{
+			Spec:      s.doc,
+			Path:      rt.path,
+			PathItem:  rt.item,
+			Method:    c.Request.Method,
+			Operation: op,
+		},
+		Options: options,
+	})
+	if err != nil {
+		return requestProblem(err)
+	}
+	return nil
+}
+
+// unsupportedMedia nombra los tipos de contenido que la operación acepta
+func unsupportedMedia(content openapi3.Content) error {
+	types := make([]string, 0, len(content))
+	for mt := range content {
+		types = append(types, mt)
+	}
+	sort.Strings(types)
+	return apperr.New(apperr.KindUnsupportedMedia, "unsupported_media_type",
+		"el cuerpo debe enviarse como "+strings.Join(types, " o "))
+}
+
+// unknownQuery rechaza los parámetros de consulta que la operación no documenta
+func unknownQuery(c *gin.Context, item *openapi3.PathItem, op *openapi3.Operation) error {
+	for name := range c.Request.URL.Query() {
+		if op.Parameters.GetByInAndName(openapi3.ParameterInQuery, name) == nil &&
+			item.Parameters.GetByInAndName(openapi3.ParameterInQuery, name) == nil {
+			return apperr.InvalidParam(name, "no es un parámetro de esta operación")
+		}
+	}
+	return nil
+}
+
+// requestProblem traduce los errores de openapi3filter a los mismos errores
+// de validación que producen los handlers: invalid_parameter para los
+// parámetros y validation_failed, con el detalle por campo, para el cuerpo.
+func requestProblem(err error) error {
+	var paramFields, bodyFields []apperr.FieldError
+	for _, e := range flatten(err) {
+		var reqErr *openapi3filter.RequestError
+		if !errors.As(e, &reqErr) {
+			return errInvalidBody
+		}
+		switch {
+		case reqErr.Parameter != nil:
+			paramFields = append(paramFields, paramField(reqErr.Parameter.Name, reqErr.Err))
+		case errors.Is(reqErr.Err, openapi3filter.ErrInvalidRequired):
+			return errMissingBody
+		default:
+			fields := bodyFieldErrors(reqErr.Err)
+			if len(fields) == 0 {
+				return errInvalidBody
+			}
+			bodyFields = append(bodyFields, fields...)
+		}
+	}
+	if len(bodyFields) > 0 {
+		return apperr.Validation("validation_failed", "la solicitud tiene campos inválidos").
+			WithFields(append(paramFields, bodyFields...)...)
+	}
+	if len(paramFields) == 1 {
+		f := paramFields[0]
+		return apperr.Validation("invalid_parameter", "el parámetro "+f.Field+" no es válido").WithFields(f)
+	}
+	return apperr.Validation("invalid_parameter", "la solicitud tiene parámetros inválidos").WithFields(paramFields...)
+}
+
+// flatten abre los openapi3.MultiError anidados. No usa errors.As porque
+// RequestError también envuelve un MultiError con los errores del esquema.
+func flatten(err error) []error {
+	me, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+	var out []error
+	for _, e := range me {
+		out = append(out, flatten(e)...)
+	}
+	return out
+}
+
+func paramField(name string, err error) apperr.FieldError {
+	var schemaErr *openapi3.SchemaError
+	var parseErr *openapi3filter.ParseError
+	switch {
+	case errors.As(err, &schemaErr):
+		return schemaField(name, schemaErr)
+	case errors.As(err, &parseErr):
+		return apperr.FieldError{Field: name, Code: "type", Message: "no tiene el tipo esperado"}
+	case errors.Is(err, openapi3filter.ErrInvalidRequired):
+		return apperr.FieldError{Field: name, Code: "required", Message: "es obligatorio"}
+	}
+	return apperr.FieldError{Field: name, Code: "invalid", Message: "no es válido"}
+}
+
+func bodyFieldErrors(err error) []apperr.FieldError {
+	var out []apperr.FieldError
+	for _, e := range flatten(err) {
+		var schemaErr *openapi3.SchemaError
+		if !errors.As(e, &schemaErr) {
+			return nil
+		}
+		path := schemaErr.JSONPointer()
+		if name, ok := unknownProperty(schemaErr); ok {
+			path = append(path, name)
+		}
+		field := strings.Join(path, ".")
+		if field == "" {
+			field = "body"
+		}
+		out = append(out, schemaField(field, schemaErr))
+	}
+	return out
+}
+
+// unknownProperty obtiene el nombre de un campo no documentado. kin-openapi
+// lo informa con SchemaField "properties" y el nombre solo en Reason:
+// property "color" is unsupported.
+func unknownProperty(e *openapi3.SchemaError) (string, bool) {
+	if e.SchemaField != "properties" {
+		return "", false
+	}
+	i := strings.IndexByte(e.Reason, '"')
+	if i < 0 {
+		return "", false
+	}
+	quoted, err := strconv.QuotedPrefix(e.Reason[i:])
+	if err != nil {
+		return "", false
+	}
+	name, err := strconv.Unquote(quoted)
+	return name, err == nil
+}
+
+// schemaField describe en castellano la regla del esquema que no se cumplió
+func schemaField(field string, e *openapi3.SchemaError) apperr.FieldError {
+	fe := apperr.FieldError{Field: field, Code: e.SchemaField}
+	s := e.Schema
+	switch e.SchemaField {
+	case "required":
+		fe.Message = "es obligatorio"
+	case "properties":
+		fe.Code = "unknown_field"
+		fe.Message = "no es un campo de esta operación"
+	case "type", "nullable":
+		fe.Code = "type"
+		fe.Message = "debe ser de tipo " + typeName(s)
+	case "enum":
+		values := make([]string, 0, len(s.Enum))
+		for _, v := range s.Enum {
+			values = append(values, fmt.Sprint(v))
+		}
+		fe.Message = "debe ser uno de: " + strings.Join(values, " ")
+	case "minimum":
+		fe.Message = "debe ser al menos " + number(s.Min)
+	case "maximum":
+		fe.Message = "debe ser como máximo " + number(s.Max)
+	case "minLength":
+		fe.Message = "no puede estar vacío"
+		if s.MinLength > 1 {
+			fe.Message = fmt.Sprintf("debe tener al menos %d caracteres", s.MinLength)
+		}
+	case "format":
+		fe.Message = "debe tener formato " + s.Format
+	default:
+		fe.Message = "no cumple la regla " + e.SchemaField
+	}
+	return fe
+}
+
+var typeNames = map[string]string{
+	openapi3.TypeString:  "texto",
+	openapi3.TypeInteger: "entero",
+	openapi3.TypeNumber:  "número",
+	openapi3.TypeBoolean: "booleano",
+	openapi3.TypeObject:  "objeto",
+	openapi3.TypeArray:   "arreglo",
+}
+
+func typeName(s *openapi3.Schema) string {
+	if s == nil || s.Type == nil || len(s.Type.Slice()) == 0 {
+		return "desconocido"
+	}
+	t := s.Type.Slice()[0]
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return t
+}
+
+func number(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,157 @@
+package reviews
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"uzm-server/internal/apperr"
+	httpx "uzm-server/internal/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reviewResponse struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	BookID    int64     `json:"book_id"`
+	Rating    int       `json:"rating"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func toReviewResponse(r *Review) reviewResponse {
+	return reviewResponse{
+		ID:        r.ID,
+		UserID:    r.UserID,
+		BookID:    r.BookID,
+		Rating:    r.Rating,
+		Comment:   r.Comment,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler { return &Handler{service: service} }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/books/:id/reviews", h.listReviews)
+	r.POST("/books/:id/reviews", h.createReview)
+	r.PATCH("/reviews/:id", h.updateReview)
+	r.DELETE("/reviews/:id", h.deleteReview)
+}
+
+// Tamaño de página por defecto y máximo del listado de reseñas
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parsePage lee ?page= y ?page_size=
+func parsePage(c *gin.Context) (page, pageSize int, ok bool) {
+	page, pageSize = 1, defaultPageSize
+	var err error
+	if v := c.Query("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page <= 0 {
+			_ = c.Error(apperr.InvalidParam("page", "debe ser un entero positivo"))
+			return 0, 0, false
+		}
+	}
+	if v := c.Query("page_size"); v != "" {
+		if pageSize, err = strconv.Atoi(v); err != nil || pageSize <= 0 || pageSize > maxPageSize {
+			_ = c.Error(apperr.InvalidParam("page_size", fmt.Sprintf("debe ser un entero entre 1 y %d", maxPageSize)))
+			return 0, 0, false
+		}
+	}
+	return page, pageSize, true
+}
+
+func (h *Handler) listReviews(c *gin.Context) {
+	bookID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	page, pageSize, ok := parsePage(c)
+	if !ok {
+		return
+	}
+
+	list, total, err := h.service.ListByBook(c.Request.Context(), bookID, page, pageSize)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]reviewResponse, 0, len(list))
+	for _, r := range list {
+		out = append(out, toReviewResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"reviews": out, "page": page, "page_size": pageSize, "total": total})
+}
+
+func (h *Handler) createReview(c *gin.Context) {
+	bookID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := httpx.RequireUserID(c)
+	if !ok {
+		return
+	}
+	var input CreateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+
+	r, err := h.service.CreateReview(c.Request.Context(), userID, bookID, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, toReviewResponse(r))
+}
+
+func (h *Handler) updateReview(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := httpx.RequireUserID(c)
+	if !ok {
+		return
+	}
+	var input UpdateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+
+	r, err := h.service.UpdateReview(c.Request.Context(), userID, id, input)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewResponse(r))
+}
+
+func (h *Handler) deleteReview(c *gin.Context) {
+	id, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := httpx.RequireUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteReview(c.Request.Context(), userID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package reviews_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"uzm-server/internal/http/httpxtest"
+	"uzm-server/internal/reviews"
+)
+
+// fakeService: el libro 1 existe, la reseña 3 es del usuario 5 y el
+// usuario 6 no compró ni arrendó nada.
+type fakeService struct{}
+
+func review(id, userID, bookID int64, rating int) *reviews.Review {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &reviews.Review{ID: id, UserID: userID, BookID: bookID, Rating: rating, Comment: "Muy bueno", CreatedAt: now, UpdatedAt: now}
+}
+
+func (fakeService) ListByBook(ctx context.Context, bookID int64, page, pageSize int) ([]*reviews.Review, int64, error) {
+	if bookID != 1 {
+		return nil, 0, reviews.ErrBookNotFound
+	}
+	return []*reviews.Review{review(3, 5, 1, 4)}, 1, nil
+}
+
+func (fakeService) CreateReview(ctx context.Context, userID, bookID int64, input reviews.CreateReviewInput) (*reviews.Review, error) {
+	switch {
+	case bookID != 1:
+		return nil, reviews.ErrBookNotFound
+	case input.Rating < 1 || input.Rating > 5:
+		return nil, reviews.ErrInvalidRating
+	case userID == 6:
+		return nil, reviews.ErrNotEligible
+	case userID == 5:
+		return nil, reviews.ErrAlreadyReviewed
+	}
+	return review(4, userID, bookID, input.Rating), nil
+}
+
+func (fakeService) UpdateReview(ctx context.Context, userID, id int64, input reviews.UpdateReviewInput) (*reviews.Review, error) {
+	if id != 3 {
+		return nil, reviews.ErrReviewNotFound
+	}
+	if userID != 5 {
+		return nil, reviews.ErrNotOwner
+	}
+	r := review(3, 5, 1, 4)
+	if input.Rating != nil {
+		if *input.Rating < 1 || *input.Rating > 5 {
+			return nil, reviews.ErrInvalidRating
+		}
+		r.Rating = *input.Rating
+	}
+	return r, nil
+}
+
+func (fakeService) DeleteReview(ctx context.Context, userID, id int64) error {
+	if id != 3 {
+		return reviews.ErrReviewNotFound
+	}
+	if userID != 5 {
+		return reviews.ErrNotOwner
+	}
+	return nil
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	router := httpxtest.Router(reviews.NewHandler(fakeService{}).RegisterRoutes)
+	owner := map[string]string{"X-User-ID": "5"}
+	other := map[string]string{"X-User-ID": "7"}
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "List", Method: "GET", Path: "/books/1/reviews", Status: 200,
+			Want: []string{`"reviews":[`, `"rating":4`, `"page":1`, `"page_size":20`, `"total":1`}},
+		{Name: "ListPaged", Method: "GET", Path: "/books/1/reviews?page=2&page_size=100", Status: 200, Want: []string{`"page":2`, `"page_size":100`}},
+		{Name: "ListBadPage", Method: "GET", Path: "/books/1/reviews?page=0", Status: 400, Code: "invalid_parameter"},
+		{Name: "ListPageSizeTooBig", Method: "GET", Path: "/books/1/reviews?page_size=101", Status: 400, Code: "invalid_parameter"},
+		{Name: "ListMissingBook", Method: "GET", Path: "/books/9/reviews", Status: 404, Code: "book_not_found"},
+		{Name: "ListBadBook", Method: "GET", Path: "/books/x/reviews", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "Create", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":5,"comment":"Excelente"}`, Header: other,
+			Status: 201, Want: []string{`"id":4`, `"user_id":7`, `"rating":5`}},
+		{Name: "CreateNoUser", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":5}`, Status: 401, Code: "missing_user"},
+		{Name: "CreateNoRating", Method: "POST", Path: "/books/1/reviews", Body: `{"comment":"sin nota"}`, Header: other,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"rating"`}},
+		{Name: "CreateBadRating", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":6}`, Header: other,
+			Status: 400, Code: "invalid_rating", Want: []string{`"field":"rating"`}},
+		{Name: "CreateNotEligible", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":3}`, Header: map[string]string{"X-User-ID": "6"},
+			Status: 403, Code: "review_not_eligible"},
+		{Name: "CreateTwice", Method: "POST", Path: "/books/1/reviews", Body: `{"rating":3}`, Header: owner, Status: 409, Code: "review_exists"},
+		{Name: "CreateMissingBook", Method: "POST", Path: "/books/9/reviews", Body: `{"rating":3}`, Header: other, Status: 404, Code: "book_not_found"},
+
+		{Name: "Update", Method: "PATCH", Path: "/reviews/3", Body: `{"rating":2}`, Header: owner, Status: 200, Want: []string{`"rating":2`}},
+		{Name: "UpdateBadRating", Method: "PATCH", Path: "/reviews/3", Body: `{"rating":0}`, Header: owner, Status: 400, Code: "invalid_rating"},
+		{Name: "UpdateNotOwner", Method: "PATCH", Path: "/reviews/3", Body: `{"rating":2}`, Header: other, Status: 403, Code: "review_not_owner"},
+		{Name: "UpdateMissing", Method: "PATCH", Path: "/reviews/8", Body: `{}`, Header: owner, Status: 404, Code: "review_not_found"},
+		{Name: "UpdateNoUser", Method: "PATCH", Path: "/reviews/3", Body: `{}`, Status: 401, Code: "missing_user"},
+		{Name: "UpdateWrongType", Method: "PATCH", Path: "/reviews/3", Body: `{"comment":5}`, Header: owner, Status: 400, Code: "validation_failed"},
+
+		{Name: "Delete", Method: "DELETE", Path: "/reviews/3", Header: owner, Status: 204},
+		{Name: "DeleteNotOwner", Method: "DELETE", Path: "/reviews/3", Header: other, Status: 403, Code: "review_not_owner"},
+		{Name: "DeleteMissing", Method: "DELETE", Path: "/reviews/8", Header: owner, Status: 404, Code: "review_not_found"},
+		{Name: "DeleteNoUser", Method: "DELETE", Path: "/reviews/3", Status: 401, Code: "missing_user"},
+	})
+}
@@ -0,0 +1,136 @@
+package reviews
+
+import (
+	"context"
+	"sort"
+
+	"uzm-server/internal/memstore"
+)
+
+type memoryRepository struct{ store *memstore.Store }
+
+func NewMemoryRepository(store *memstore.Store) Repository {
+	return &memoryRepository{store: store}
+}
+
+func toReview(rv memstore.Review) Review {
+	return Review{ID: rv.ID, UserID: rv.UserID, BookID: rv.BookID, Rating: rv.Rating,
+		Comment: rv.Comment, CreatedAt: rv.CreatedAt, UpdatedAt: rv.UpdatedAt}
+}
+
+func (r *memoryRepository) BookExists(ctx context.Context, bookID int64) (ok bool, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		_, ok = t.Books[bookID]
+		return nil
+	})
+	return ok, err
+}
+
+// CanReview indica si el usuario compró el libro o terminó un préstamo de él
+func (r *memoryRepository) CanReview(ctx context.Context, userID, bookID int64) (ok bool, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		for _, s := range t.Sales {
+			if s.UserID == userID && s.BookID == bookID {
+				ok = true
+				return nil
+			}
+		}
+		for _, l := range t.Loans {
+			if l.UserID == userID && l.BookID == bookID && l.Status == memstore.LoanFinished {
+				ok = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return ok, err
+}
+
+func (r *memoryRepository) ListByBook(ctx context.Context, bookID int64, limit, offset int) ([]Review, int64, error) {
+	var all []Review
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, rv := range t.Reviews {
+			if rv.BookID == bookID {
+				all = append(all, toReview(rv))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.After(all[j].CreatedAt)
+		}
+		return all[i].ID > all[j].ID
+	})
+
+	out := []Review{}
+	if offset < len(all) {
+		page := all[offset:]
+		if limit >= 0 && limit < len(page) {
+			page = page[:limit]
+		}
+		out = append(out, page...)
+	}
+	return out, int64(len(all)), nil
+}
+
+func (r *memoryRepository) GetReview(ctx context.Context, id int64) (out *Review, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		if rv, ok := t.Reviews[id]; ok {
+			review := toReview(rv)
+			out = &review
+		}
+		return nil
+	})
+	return out, err
+}
+
+// CreateReview inserta la reseña; cada usuario puede reseñar un libro una sola vez
+func (r *memoryRepository) CreateReview(ctx context.Context, rv *Review) (int64, error) {
+	var id int64
+	err := r.store.Update(func(t *memstore.Tables) error {
+		for _, other := range t.Reviews {
+			if other.UserID == rv.UserID && other.BookID == rv.BookID {
+				return ErrAlreadyReviewed
+			}
+		}
+		id = t.NextID("Resena")
+		t.Reviews[id] = memstore.Review{
+			ID:        id,
+			UserID:    rv.UserID,
+			BookID:    rv.BookID,
+			Rating:    rv.Rating,
+			Comment:   rv.Comment,
+			CreatedAt: memstore.Stamp(rv.CreatedAt),
+			UpdatedAt: memstore.Stamp(rv.UpdatedAt),
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *memoryRepository) UpdateReview(ctx context.Context, rv *Review) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if row, ok := t.Reviews[rv.ID]; ok {
+			row.Rating, row.Comment, row.UpdatedAt = rv.Rating, rv.Comment, memstore.Stamp(rv.UpdatedAt)
+			t.Reviews[rv.ID] = row
+		}
+		return nil
+	})
+}
+
+func (r *memoryRepository) DeleteReview(ctx context.Context, id int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if _, ok := t.Reviews[id]; !ok {
+			return ErrReviewNotFound
+		}
+		delete(t.Reviews, id)
+		return nil
+	})
+}
@@ -0,0 +1,151 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"uzm-server/internal/db"
+)
+
+type sqlRepository struct{ dbconn *db.DB }
+
+func NewSQLiteRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.SQLite)}
+}
+
+func NewPostgresRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.Postgres)}
+}
+
+const reviewSelect = `
+SELECT id, user_id, book_id, rating, comment, created_at, updated_at
+FROM   Resena`
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanReview(row rowScanner) (Review, error) {
+	var r Review
+	var createdAt, updatedAt string
+	if err := row.Scan(&r.ID, &r.UserID, &r.BookID, &r.Rating, &r.Comment, &createdAt, &updatedAt); err != nil {
+		return Review{}, err
+	}
+	r.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	r.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return r, nil
+}
+
+func (r *sqlRepository) BookExists(ctx context.Context, bookID int64) (bool, error) {
+	var one int
+	err := r.dbconn.QueryRowContext(ctx, `SELECT 1 FROM Libro WHERE id = ?`, bookID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CanReview indica si el usuario compró el libro o terminó un préstamo de él
+func (r *sqlRepository) CanReview(ctx context.Context, userID, bookID int64) (bool, error) {
+	var ok bool
+	err := r.dbconn.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM Venta    WHERE user_id = ? AND book_id = ?)
+    OR EXISTS (SELECT 1 FROM Prestamo WHERE user_id = ? AND book_id = ? AND status = 'finalizado')`,
+		userID, bookID, userID, bookID).Scan(&ok)
+	return ok, err
+}
+
+func (r *sqlRepository) ListByBook(ctx context.Context, bookID int64, limit, offset int) ([]Review, int64, error) {
+	var total int64
+	if err := r.dbconn.QueryRowContext(ctx, `SELECT COUNT(*) FROM Resena WHERE book_id = ?`, bookID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.dbconn.QueryContext(ctx, reviewSelect+`
+WHERE  book_id = ?
+ORDER BY created_at DESC, id DESC
+LIMIT  ? OFFSET ?`, bookID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, rv)
+	}
+	return out, total, rows.Err()
+}
+
+func (r *sqlRepository) GetReview(ctx context.Context, id int64) (*Review, error) {
+	rv, err := scanReview(r.dbconn.QueryRowContext(ctx, reviewSelect+` WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// CreateReview inserta la reseña; cada usuario puede reseñar un libro una sola vez
+func (r *sqlRepository) CreateReview(ctx context.Context, rv *Review) (id int64, err error) {
+	tx, err := r.dbconn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+SELECT EXISTS (SELECT 1 FROM Resena WHERE user_id = ? AND book_id = ?)`, rv.UserID, rv.BookID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		err = ErrAlreadyReviewed
+		return 0, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO Resena (user_id, book_id, rating, comment, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING id`,
+		rv.UserID, rv.BookID, rv.Rating, rv.Comment,
+		rv.CreatedAt.Format(time.RFC3339), rv.UpdatedAt.Format(time.RFC3339),
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *sqlRepository) UpdateReview(ctx context.Context, rv *Review) error {
+	_, err := r.dbconn.ExecContext(ctx, `
+UPDATE Resena SET rating = ?, comment = ?, updated_at = ? WHERE id = ?`,
+		rv.Rating, rv.Comment, rv.UpdatedAt.Format(time.RFC3339), rv.ID)
+	return err
+}
+
+func (r *sqlRepository) DeleteReview(ctx context.Context, id int64) error {
+	res, err := r.dbconn.ExecContext(ctx, `DELETE FROM Resena WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
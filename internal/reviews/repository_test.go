@@ -0,0 +1,53 @@
+package reviews_test
+
+import (
+	"os"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/reviews"
+	"uzm-server/internal/reviews/reviewstest"
+	"uzm-server/internal/users"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	reviewstest.RunRepositoryContract(t, func(t *testing.T) reviewstest.Repos {
+		conn := dbtest.SQLite(t).DB
+		return reviewstest.Repos{
+			Reviews: reviews.NewSQLiteRepository(conn),
+			Books:   books.NewSQLiteRepository(conn),
+			Users:   users.NewSQLiteRepository(conn),
+			Copies:  copies.NewSQLiteRepository(conn),
+		}
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	reviewstest.RunRepositoryContract(t, func(t *testing.T) reviewstest.Repos {
+		conn := dbtest.Postgres(t).DB
+		return reviewstest.Repos{
+			Reviews: reviews.NewPostgresRepository(conn),
+			Books:   books.NewPostgresRepository(conn),
+			Users:   users.NewPostgresRepository(conn),
+			Copies:  copies.NewPostgresRepository(conn),
+		}
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	reviewstest.RunRepositoryContract(t, func(t *testing.T) reviewstest.Repos {
+		store := memstore.New()
+		return reviewstest.Repos{
+			Reviews: reviews.NewMemoryRepository(store),
+			Books:   books.NewMemoryRepository(store),
+			Users:   users.NewMemoryRepository(store),
+			Copies:  copies.NewMemoryRepository(store),
+		}
+	})
+}
@@ -0,0 +1,251 @@
+// Package reviewstest tiene la prueba de contrato de reviews.Repository: todas
+// las implementaciones deben pasarla igual, sea cual sea el motor que usen.
+package reviewstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/copies"
+	"uzm-server/internal/reviews"
+	"uzm-server/internal/users"
+)
+
+// Repos son los repositorios de una misma base vacía: el que se prueba y los
+// que crean los libros, usuarios, ventas y préstamos de los que depende
+type Repos struct {
+	Reviews reviews.Repository
+	Books   books.Repository
+	Users   users.Repository
+	Copies  copies.Repository
+}
+
+var at = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// RunRepositoryContract prueba una implementación de reviews.Repository.
+// newRepos debe entregar repositorios vacíos en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepos func(t *testing.T) Repos) {
+	ctx := context.Background()
+
+	t.Run("BookExists", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		if ok, err := r.Reviews.BookExists(ctx, bookID); err != nil || !ok {
+			t.Fatalf("BookExists(%d) = %v, %v; quiero true", bookID, ok, err)
+		}
+		if ok, err := r.Reviews.BookExists(ctx, 999); err != nil || ok {
+			t.Fatalf("BookExists(999) = %v, %v; quiero false", ok, err)
+		}
+	})
+
+	t.Run("CanReviewAfterSale", func(t *testing.T) {
+		r := newRepos(t)
+		buyer, other := createUser(t, r, "a"), createUser(t, r, "b")
+		bookID := createBook(t, r, books.ModeSale)
+		createCopy(t, r, bookID, "LIB-1")
+
+		canReview(t, r, buyer, bookID, false)
+		checkOut(t, r, "LIB-1", copies.CheckoutSale, buyer)
+		canReview(t, r, buyer, bookID, true)
+		canReview(t, r, other, bookID, false)
+	})
+
+	t.Run("CanReviewAfterReturn", func(t *testing.T) {
+		r := newRepos(t)
+		reader := createUser(t, r, "a")
+		bookID := createBook(t, r, books.ModeRental)
+		createCopy(t, r, bookID, "LIB-1")
+
+		// Un préstamo pendiente todavía no habilita la reseña
+		checkOut(t, r, "LIB-1", copies.CheckoutLoan, reader)
+		canReview(t, r, reader, bookID, false)
+		if _, err := r.Copies.CheckIn(ctx, "LIB-1", nil, nil, 0, at); err != nil {
+			t.Fatal(err)
+		}
+		canReview(t, r, reader, bookID, true)
+	})
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r, "a")
+		bookID := createBook(t, r, books.ModeSale)
+
+		id := createReview(t, r, userID, bookID, 4, at)
+		if id <= 0 {
+			t.Fatalf("CreateReview devolvió el id %d", id)
+		}
+		got, err := r.Reviews.GetReview(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := reviews.Review{ID: id, UserID: userID, BookID: bookID, Rating: 4, Comment: "Reseña 4", CreatedAt: at, UpdatedAt: at}
+		if got == nil || *got != want {
+			t.Fatalf("GetReview = %+v, quiero %+v", got, want)
+		}
+		if rv, err := r.Reviews.GetReview(ctx, 999); err != nil || rv != nil {
+			t.Fatalf("GetReview(999) = %+v, %v; quiero nil, nil", rv, err)
+		}
+	})
+
+	t.Run("OnePerUserAndBook", func(t *testing.T) {
+		r := newRepos(t)
+		a, b := createUser(t, r, "a"), createUser(t, r, "b")
+		bookID, other := createBook(t, r, books.ModeSale), createBook(t, r, books.ModeSale)
+		createReview(t, r, a, bookID, 5, at)
+
+		_, err := r.Reviews.CreateReview(ctx, &reviews.Review{UserID: a, BookID: bookID, Rating: 1, CreatedAt: at, UpdatedAt: at})
+		if !errors.Is(err, reviews.ErrAlreadyReviewed) {
+			t.Fatalf("segunda reseña del mismo usuario = %v, quiero ErrAlreadyReviewed", err)
+		}
+		createReview(t, r, b, bookID, 3, at)
+		createReview(t, r, a, other, 2, at)
+		if _, total, _ := r.Reviews.ListByBook(ctx, bookID, 10, 0); total != 2 {
+			t.Fatalf("el libro quedó con %d reseñas, quiero 2", total)
+		}
+	})
+
+	t.Run("ListByBookPages", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		list, total, err := r.Reviews.ListByBook(ctx, bookID, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list == nil || len(list) != 0 || total != 0 {
+			t.Fatalf("ListByBook sin reseñas = %#v, %d; quiero una lista vacía", list, total)
+		}
+
+		// Las más recientes primero; con la misma fecha, la de id mayor
+		var ids []int64
+		for i, when := range []time.Time{at, at.Add(time.Hour), at.Add(time.Hour), at.Add(-time.Hour)} {
+			ids = append(ids, createReview(t, r, createUser(t, r, fmt.Sprint(i)), bookID, i+1, when))
+		}
+		want := []int64{ids[2], ids[1], ids[0], ids[3]}
+
+		tests := []struct {
+			limit, offset int
+			want          []int64
+		}{
+			{10, 0, want},
+			{2, 0, want[:2]},
+			{2, 2, want[2:]},
+			{2, 3, want[3:]},
+			{2, 4, nil},
+		}
+		for _, tt := range tests {
+			list, total, err := r.Reviews.ListByBook(ctx, bookID, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]int64, 0, len(list))
+			for _, rv := range list {
+				got = append(got, rv.ID)
+			}
+			if total != 4 || fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("ListByBook(limit %d, offset %d) = %v (total %d), quiero %v (total 4)", tt.limit, tt.offset, got, total, tt.want)
+			}
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		id := createReview(t, r, createUser(t, r, "a"), bookID, 4, at)
+
+		rv, err := r.Reviews.GetReview(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rv.Rating, rv.Comment, rv.UpdatedAt = 2, "Cambié de opinión", at.Add(time.Hour)
+		if err := r.Reviews.UpdateReview(ctx, rv); err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.Reviews.GetReview(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == nil || *got != *rv {
+			t.Fatalf("GetReview tras UpdateReview = %+v, quiero %+v", got, rv)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r := newRepos(t)
+		bookID := createBook(t, r, books.ModeSale)
+		userID := createUser(t, r, "a")
+		id := createReview(t, r, userID, bookID, 4, at)
+
+		if err := r.Reviews.DeleteReview(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		if rv, err := r.Reviews.GetReview(ctx, id); err != nil || rv != nil {
+			t.Fatalf("GetReview de una reseña borrada = %+v, %v", rv, err)
+		}
+		if err := r.Reviews.DeleteReview(ctx, id); !errors.Is(err, reviews.ErrReviewNotFound) {
+			t.Fatalf("borrar dos veces = %v, quiero ErrReviewNotFound", err)
+		}
+		// Borrada la reseña, el usuario puede volver a reseñar el libro
+		createReview(t, r, userID, bookID, 5, at)
+	})
+}
+
+func createReview(t *testing.T, r Repos, userID, bookID int64, rating int, when time.Time) int64 {
+	t.Helper()
+	id, err := r.Reviews.CreateReview(context.Background(), &reviews.Review{
+		UserID: userID, BookID: bookID, Rating: rating, Comment: fmt.Sprintf("Reseña %d", rating), CreatedAt: when, UpdatedAt: when,
+	})
+	if err != nil {
+		t.Fatalf("CreateReview: %v", err)
+	}
+	return id
+}
+
+func canReview(t *testing.T, r Repos, userID, bookID int64, want bool) {
+	t.Helper()
+	ok, err := r.Reviews.CanReview(context.Background(), userID, bookID)
+	if err != nil || ok != want {
+		t.Fatalf("CanReview(%d, %d) = %v, %v; quiero %v", userID, bookID, ok, err, want)
+	}
+}
+
+func createBook(t *testing.T, r Repos, mode string) int64 {
+	t.Helper()
+	b := &books.Book{BookName: "Rayuela", BookCategory: "Novela", TransactionType: mode, Price: 1000, RentalPeriodDays: 7}
+	id, err := r.Books.CreateBook(context.Background(), b, 0, 0)
+	if err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	return id
+}
+
+func createUser(t *testing.T, r Repos, name string) int64 {
+	t.Helper()
+	id, err := r.Users.CreateUser(context.Background(), &users.Usuario{
+		FirstName: "Usuario", LastName: name, Email: name + "@usm.cl", Password: "x",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", name, err)
+	}
+	return id
+}
+
+func createCopy(t *testing.T, r Repos, bookID int64, barcode string) {
+	t.Helper()
+	_, err := r.Copies.CreateCopy(context.Background(), &copies.Copy{
+		BookID: bookID, Barcode: barcode, Condition: copies.ConditionNew, Status: copies.StatusAvailable, CreatedAt: at, UpdatedAt: at,
+	})
+	if err != nil {
+		t.Fatalf("CreateCopy(%s): %v", barcode, err)
+	}
+}
+
+func checkOut(t *testing.T, r Repos, barcode, kind string, userID int64) {
+	t.Helper()
+	if err := r.Copies.CheckOut(context.Background(), barcode, &copies.Checkout{Type: kind}, userID, at); err != nil {
+		t.Fatalf("CheckOut(%s, %s): %v", barcode, kind, err)
+	}
+}
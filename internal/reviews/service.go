@@ -0,0 +1,165 @@
+package reviews
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrReviewNotFound  = apperr.NotFound("review_not_found", "reseña no encontrada")
+	ErrBookNotFound    = apperr.NotFound("book_not_found", "libro no encontrado")
+	ErrNotEligible     = apperr.Forbidden("review_not_eligible", "solo puede reseñar quien compró el libro o terminó un préstamo")
+	ErrAlreadyReviewed = apperr.Conflict("review_exists", "el usuario ya reseñó este libro")
+	ErrNotOwner        = apperr.Forbidden("review_not_owner", "la reseña pertenece a otro usuario")
+	ErrInvalidRating   = apperr.Validation("invalid_rating", "la calificación debe estar entre 1 y 5 estrellas").
+				WithFields(apperr.FieldError{Field: "rating", Code: "range", Message: "debe estar entre 1 y 5"})
+)
+
+type Review struct {
+	ID        int64
+	UserID    int64
+	BookID    int64
+	Rating    int
+	Comment   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CreateReviewInput struct {
+	Rating  int    `json:"rating" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+type UpdateReviewInput struct {
+	Rating  *int    `json:"rating"`
+	Comment *string `json:"comment"`
+}
+
+type Service interface { // Interfaz del servicio de reseñas
+	ListByBook(ctx context.Context, bookID int64, page, pageSize int) ([]*Review, int64, error)       // Reseñas de un libro paginadas, junto al total
+	CreateReview(ctx context.Context, userID, bookID int64, input CreateReviewInput) (*Review, error) // Publica una reseña si el usuario compró o arrendó el libro
+	UpdateReview(ctx context.Context, userID, id int64, input UpdateReviewInput) (*Review, error)     // Edita una reseña propia
+	DeleteReview(ctx context.Context, userID, id int64) error                                         // Borra una reseña propia
+}
+
+type Repository interface {
+	BookExists(ctx context.Context, bookID int64) (bool, error)
+	CanReview(ctx context.Context, userID, bookID int64) (bool, error)
+	ListByBook(ctx context.Context, bookID int64, limit, offset int) ([]Review, int64, error)
+	GetReview(ctx context.Context, id int64) (*Review, error)
+	CreateReview(ctx context.Context, r *Review) (int64, error)
+	UpdateReview(ctx context.Context, r *Review) error
+	DeleteReview(ctx context.Context, id int64) error
+}
+
+type service struct { // Implementación del servicio de reseñas
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func validRating(r int) bool { return r >= 1 && r <= 5 }
+
+func (s *service) ListByBook(ctx context.Context, bookID int64, page, pageSize int) ([]*Review, int64, error) {
+	ok, err := s.repo.BookExists(ctx, bookID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !ok {
+		return nil, 0, ErrBookNotFound
+	}
+	list, total, err := s.repo.ListByBook(ctx, bookID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]*Review, len(list))
+	for i := range list {
+		out[i] = &list[i]
+	}
+	return out, total, nil
+}
+
+func (s *service) CreateReview(ctx context.Context, userID, bookID int64, input CreateReviewInput) (*Review, error) {
+	if !validRating(input.Rating) {
+		return nil, ErrInvalidRating
+	}
+	ok, err := s.repo.BookExists(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrBookNotFound
+	}
+	ok, err = s.repo.CanReview(ctx, userID, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotEligible
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	r := &Review{
+		UserID:    userID,
+		BookID:    bookID,
+		Rating:    input.Rating,
+		Comment:   strings.TrimSpace(input.Comment),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	id, err := s.repo.CreateReview(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	r.ID = id
+	return r, nil
+}
+
+// ownReview carga la reseña y verifica que pertenezca al usuario
+func (s *service) ownReview(ctx context.Context, userID, id int64) (*Review, error) {
+	r, err := s.repo.GetReview(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, ErrReviewNotFound
+	}
+	if r.UserID != userID {
+		return nil, ErrNotOwner
+	}
+	return r, nil
+}
+
+func (s *service) UpdateReview(ctx context.Context, userID, id int64, input UpdateReviewInput) (*Review, error) {
+	r, err := s.ownReview(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if input.Rating != nil {
+		if !validRating(*input.Rating) {
+			return nil, ErrInvalidRating
+		}
+		r.Rating = *input.Rating
+	}
+	if input.Comment != nil {
+		r.Comment = strings.TrimSpace(*input.Comment)
+	}
+	r.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	if err := s.repo.UpdateReview(ctx, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *service) DeleteReview(ctx context.Context, userID, id int64) error {
+	if _, err := s.ownReview(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteReview(ctx, id)
+}
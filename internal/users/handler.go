@@ -2,7 +2,9 @@ package users
 
 import (
 	"net/http"
-	"strconv"
+
+	"uzm-server/internal/apperr"
+	httpx "uzm-server/internal/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,7 +15,7 @@ type Handler struct {
 
 func NewHandler(service Service) *Handler { return &Handler{service: service} }
 
-// Rutas REST consistentes bajo /api/v1
+// Rutas REST del contrato v1; main las registra bajo /api/v1 y el alias /api
 func (h *Handler) RegisterRoutes(rg *gin.RouterGroup) {
 	rg.GET("/users", h.ListUsers)
 	rg.GET("/users/:id", h.getUserByID)
@@ -57,7 +59,7 @@ func toUserResponse(u *Usuario) userResponse {
 func (h *Handler) ListUsers(c *gin.Context) {
 	us, err := h.service.ListUsers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	out := make([]userResponse, 0, len(us))
@@ -66,19 +68,18 @@ func (h *Handler) ListUsers(c *gin.Context) {
 }
 
 func (h *Handler) getUserByID(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}); return }
+	id, ok := httpx.ParamID(c, "id")
+	if !ok { return }
 
 	u, err := h.service.GetUserByID(c.Request.Context(), id)
-	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
-	if u == nil   { c.JSON(http.StatusNotFound,      gin.H{"error": "User not found"}); return }
+	if err != nil { _ = c.Error(err); return }
 	c.JSON(http.StatusOK, toUserResponse(u))
 }
 
 func (h *Handler) createUser(c *gin.Context) {
 	var req createRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		httpx.BindError(c, err)
 		return
 	}
 
@@ -90,7 +91,7 @@ func (h *Handler) createUser(c *gin.Context) {
 		USMPesos:  req.USMPesos,
 	}
 	id, err := h.service.RegisterUser(c.Request.Context(), u)
-	if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()}); return }
+	if err != nil { _ = c.Error(err); return }
 
 	// devuelve el creado (sin password)
 	u.ID = id
@@ -98,25 +99,25 @@ func (h *Handler) createUser(c *gin.Context) {
 }
 
 func (h *Handler) updateUserUSMPesos(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"}); return }
+	id, ok := httpx.ParamID(c, "id")
+	if !ok { return }
 
 	var body struct {
 		Amount int64 `json:"amount"` // usa int64 para calzar con service/repo
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		httpx.BindError(c, err)
 		return
 	}
 
 	if err := h.service.UpdateUserUSMPesos(c.Request.Context(), id, body.Amount); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	// Entrega el valor actualizado de los pesos
 	u, err := h.service.GetUserByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, toUserResponse(u))
@@ -125,17 +126,13 @@ func (h *Handler) updateUserUSMPesos(c *gin.Context) {
 func (h *Handler) getUserByEmail(c *gin.Context) {
 	email := c.Param("email")
 	if email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		_ = c.Error(apperr.InvalidParam("email", "es obligatorio"))
 		return
 	}
 
 	u, err := h.service.GetUserByEmail(c.Request.Context(), email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-	if u == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, toUserResponse(u))
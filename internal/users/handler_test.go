@@ -0,0 +1,96 @@
+package users_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"uzm-server/internal/http/httpxtest"
+	"uzm-server/internal/users"
+)
+
+// fakeService conoce un solo usuario, ana@usm.cl con id 1 y 500 USM Pesos
+type fakeService struct{}
+
+func ana() *users.Usuario {
+	return &users.Usuario{ID: 1, FirstName: "Ana", LastName: "Rojas", Email: "ana@usm.cl", Password: "secreta", USMPesos: 500}
+}
+
+func (fakeService) RegisterUser(ctx context.Context, u *users.Usuario) (int64, error) {
+	if u.Email == ana().Email {
+		return 0, users.ErrEmailTaken
+	}
+	return 2, nil
+}
+
+func (fakeService) LoginUser(ctx context.Context, email, password string) (*users.Usuario, error) {
+	return nil, users.ErrInvalidCredentials
+}
+
+func (fakeService) GetUserByID(ctx context.Context, id int64) (*users.Usuario, error) {
+	if id != 1 {
+		return nil, users.ErrUserNotFound
+	}
+	return ana(), nil
+}
+
+func (fakeService) UpdateUserUSMPesos(ctx context.Context, userID, amount int64) error {
+	if userID != 1 {
+		return users.ErrUserNotFound
+	}
+	return nil
+}
+
+func (fakeService) ListUsers(ctx context.Context) ([]*users.Usuario, error) {
+	return []*users.Usuario{ana()}, nil
+}
+
+func (fakeService) GetUserByEmail(ctx context.Context, email string) (*users.Usuario, error) {
+	if email != ana().Email {
+		return nil, users.ErrUserNotFound
+	}
+	return ana(), nil
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	router := httpxtest.Router(users.NewHandler(fakeService{}).RegisterRoutes)
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "List", Method: "GET", Path: "/users", Status: 200, Want: []string{`"users":[`, `"email":"ana@usm.cl"`}},
+
+		{Name: "Get", Method: "GET", Path: "/users/1", Status: 200, Want: []string{`"first_name":"Ana"`, `"usm_pesos":500`}},
+		{Name: "GetMissing", Method: "GET", Path: "/users/9", Status: 404, Code: "user_not_found"},
+		{Name: "GetNotNumeric", Method: "GET", Path: "/users/ana", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "Create", Method: "POST", Path: "/users", Body: `{"first_name":"Luis","last_name":"Soto","email":"luis@usm.cl","password":"x"}`,
+			Status: 201, Want: []string{`"id":2`, `"email":"luis@usm.cl"`}},
+		{Name: "CreateEmailTaken", Method: "POST", Path: "/users", Body: `{"first_name":"Ana","last_name":"Rojas","email":"ana@usm.cl","password":"x"}`,
+			Status: 409, Code: "email_taken"},
+		{Name: "CreateBadEmail", Method: "POST", Path: "/users", Body: `{"first_name":"Luis","last_name":"Soto","email":"luis","password":"x"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"email"`}},
+		{Name: "CreateMissingFields", Method: "POST", Path: "/users", Body: `{"email":"luis@usm.cl"}`,
+			Status: 400, Code: "validation_failed", Want: []string{`"field":"first_name"`, `"field":"password"`}},
+		{Name: "CreateNoBody", Method: "POST", Path: "/users", Status: 400, Code: "invalid_body"},
+
+		{Name: "AddPesos", Method: "PATCH", Path: "/users/1/usm_pesos", Body: `{"amount":100}`, Status: 200, Want: []string{`"id":1`}},
+		{Name: "PesosMissingUser", Method: "PATCH", Path: "/users/9/usm_pesos", Body: `{"amount":1}`, Status: 404, Code: "user_not_found"},
+		{Name: "PesosWrongType", Method: "PATCH", Path: "/users/1/usm_pesos", Body: `{"amount":"cien"}`, Status: 400, Code: "validation_failed"},
+
+		{Name: "ByEmail", Method: "GET", Path: "/users/email/ana@usm.cl", Status: 200, Want: []string{`"id":1`}},
+		{Name: "ByEmailMissing", Method: "GET", Path: "/users/email/nadie@usm.cl", Status: 404, Code: "user_not_found"},
+	})
+}
+
+// La respuesta nunca expone la contraseña
+func TestHandlerHidesPassword(t *testing.T) {
+	router := httpxtest.Router(users.NewHandler(fakeService{}).RegisterRoutes)
+	for _, path := range []string{"/users", "/users/1", "/users/email/ana@usm.cl"} {
+		w := httpxtest.Do(router, "GET", path, "", nil)
+		httpxtest.Check(t, w, 200, "")
+		for _, leak := range []string{"password", "secreta"} {
+			if body := w.Body.String(); strings.Contains(body, leak) {
+				t.Fatalf("%s expone %q: %s", path, leak, body)
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"uzm-server/internal/memstore"
+)
+
+type memoryRepository struct { // Implementación del repositorio sobre memstore
+	store *memstore.Store // Tablas en memoria compartidas con los demás repositorios
+}
+
+func NewMemoryRepository(store *memstore.Store) Repository { // Constructor para crear un repositorio en memoria
+	return &memoryRepository{store: store}
+}
+
+func toUsuario(u memstore.User) *Usuario {
+	return &Usuario{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, Email: u.Email, Password: u.Password, USMPesos: u.USMPesos}
+}
+
+// findUser devuelve el primer usuario que cumple match, o nil
+func findUser(t *memstore.Tables, match func(memstore.User) bool) *Usuario {
+	for _, u := range t.Users {
+		if match(u) {
+			return toUsuario(u)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, user *Usuario) (int64, error) {
+	var id int64
+	err := r.store.Update(func(t *memstore.Tables) error {
+		// Mismas restricciones UNIQUE que la tabla Usuario
+		for _, u := range t.Users {
+			if u.Email == user.Email {
+				return ErrEmailTaken
+			}
+			if u.LastName == user.LastName {
+				return fmt.Errorf("users: ya existe un usuario con last_name %q", user.LastName)
+			}
+		}
+		id = t.NextID("Usuario")
+		t.Users[id] = memstore.User{ID: id, FirstName: user.FirstName, LastName: user.LastName, Email: user.Email, Password: user.Password, USMPesos: user.USMPesos}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *memoryRepository) LoginUser(ctx context.Context, email, password string) (user *Usuario, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		user = findUser(t, func(u memstore.User) bool { return u.Email == email && u.Password == password })
+		return nil
+	})
+	return user, err
+}
+
+func (r *memoryRepository) GetUserByEmail(ctx context.Context, email string) (user *Usuario, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		user = findUser(t, func(u memstore.User) bool { return u.Email == email })
+		return nil
+	})
+	return user, err
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id int64) (user *Usuario, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		if u, ok := t.Users[id]; ok {
+			user = toUsuario(u)
+		}
+		return nil
+	})
+	return user, err
+}
+
+func (r *memoryRepository) UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		u, ok := t.Users[userID]
+		if !ok {
+			return ErrUserNotFound
+		}
+		u.USMPesos += amount
+		t.Users[userID] = u
+		return nil
+	})
+}
+
+func (r *memoryRepository) ListUsers(ctx context.Context) (users []*Usuario, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		for _, u := range t.Users {
+			users = append(users, toUsuario(u))
+		}
+		return nil
+	})
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, err
+}
@@ -3,6 +3,8 @@ package users
 import ( 
 	"context"
 	"database/sql"
+
+	"uzm-server/internal/db"
 )
 
 type Repository interface {
@@ -14,24 +16,51 @@ type Repository interface {
 	GetUserByEmail(ctx context.Context, email string) (*Usuario, error) // Obtiene un usuario por su email
 }
 
-type sqliteRepository struct { // Implementación del repositorio utilizando SQLite
-	db *sql.DB // Conexión a la base de datos
+type sqlRepository struct { // Implementación del repositorio sobre SQLite o PostgreSQL
+	db *db.DB // Conexión a la base de datos; traduce los marcadores ? al motor
+}
+
+func NewSQLiteRepository(conn *sql.DB) Repository { // Constructor para crear un nuevo repositorio SQLite
+	return &sqlRepository{db: db.Wrap(conn, db.SQLite)} // Retorna una instancia del repositorio SQLite
 }
 
-func NewSQLiteRepository(db *sql.DB) Repository { // Constructor para crear un nuevo repositorio SQLite
-	return &sqliteRepository{db: db} // Retorna una instancia del repositorio SQLite
+func NewPostgresRepository(conn *sql.DB) Repository { // Constructor para crear un nuevo repositorio PostgreSQL
+	return &sqlRepository{db: db.Wrap(conn, db.Postgres)}
 }
 
-func (r *sqliteRepository) CreateUser(ctx context.Context, user *Usuario) (int64, error) {
-	result, err := r.db.ExecContext(ctx, "INSERT INTO Usuario (first_name, last_name, email, password, usm_pesos) VALUES (?, ?, ?, ?, ?)",
-		user.FirstName, user.LastName, user.Email, user.Password, user.USMPesos)
+func (r *sqlRepository) CreateUser(ctx context.Context, user *Usuario) (id int64, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// El email se busca en la misma transacción; si otra la gana entre la
+	// búsqueda y el INSERT, lo detecta el código de error del motor
+	var taken bool
+	if err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM Usuario WHERE email = ?)", user.Email).Scan(&taken); err != nil {
+		return 0, err
+	}
+	if taken {
+		err = ErrEmailTaken
+		return 0, err
+	}
+	err = tx.QueryRowContext(ctx, "INSERT INTO Usuario (first_name, last_name, email, password, usm_pesos) VALUES (?, ?, ?, ?, ?) RETURNING id",
+		user.FirstName, user.LastName, user.Email, user.Password, user.USMPesos).Scan(&id)
+	if db.IsUniqueViolation(err, "Usuario", "email") {
+		err = ErrEmailTaken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-func (r *sqliteRepository) LoginUser(ctx context.Context, email, password string) (*Usuario, error) {
+func (r *sqlRepository) LoginUser(ctx context.Context, email, password string) (*Usuario, error) {
 	row := r.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, password, usm_pesos FROM Usuario WHERE email = ? AND password = ?", email, password)
 	user := &Usuario{}
 	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.USMPesos)
@@ -44,7 +73,7 @@ func (r *sqliteRepository) LoginUser(ctx context.Context, email, password string
 	return user, nil
 }
 
-func (r *sqliteRepository) GetUserByEmail(ctx context.Context, email string) (*Usuario, error) {
+func (r *sqlRepository) GetUserByEmail(ctx context.Context, email string) (*Usuario, error) {
 	row := r.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, password, usm_pesos FROM Usuario WHERE email = ?", email)
 	user := &Usuario{}
 	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.USMPesos)
@@ -57,7 +86,7 @@ func (r *sqliteRepository) GetUserByEmail(ctx context.Context, email string) (*U
 	return user, nil
 }
 
-func (r *sqliteRepository) GetUserByID(ctx context.Context, id int64) (*Usuario, error) {
+func (r *sqlRepository) GetUserByID(ctx context.Context, id int64) (*Usuario, error) {
 	row := r.db.QueryRowContext(ctx, "SELECT id, first_name, last_name, email, password, usm_pesos FROM Usuario WHERE id = ?", id)
 	user := &Usuario{}
 	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password, &user.USMPesos)
@@ -70,12 +99,22 @@ func (r *sqliteRepository) GetUserByID(ctx context.Context, id int64) (*Usuario,
 	return user, nil
 }
 
-func (r *sqliteRepository) UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE Usuario SET usm_pesos = usm_pesos + ? WHERE id = ?", amount, userID)
-	return err
+func (r *sqlRepository) UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE Usuario SET usm_pesos = usm_pesos + ? WHERE id = ?", amount, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
-func (r *sqliteRepository) ListUsers(ctx context.Context) ([]*Usuario, error) {
+func (r *sqlRepository) ListUsers(ctx context.Context) ([]*Usuario, error) {
 	rows, err := r.db.QueryContext(ctx, "SELECT id, first_name, last_name, email, password, usm_pesos FROM Usuario")
 	if err != nil {
 		return nil, err
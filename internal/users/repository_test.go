@@ -0,0 +1,32 @@
+package users_test
+
+import (
+	"os"
+	"testing"
+
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/users"
+	"uzm-server/internal/users/userstest"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	userstest.RunRepositoryContract(t, func(t *testing.T) users.Repository {
+		return users.NewSQLiteRepository(dbtest.SQLite(t).DB)
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	userstest.RunRepositoryContract(t, func(t *testing.T) users.Repository {
+		return users.NewPostgresRepository(dbtest.Postgres(t).DB)
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	userstest.RunRepositoryContract(t, func(t *testing.T) users.Repository {
+		return users.NewMemoryRepository(memstore.New())
+	})
+}
@@ -1,6 +1,17 @@
 package users
 
-import "context"
+import (
+	"context"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrUserNotFound       = apperr.NotFound("user_not_found", "usuario no encontrado")
+	ErrEmailTaken         = apperr.Conflict("email_taken", "ya existe un usuario con ese email")
+	ErrInsufficientFunds  = apperr.InsufficientFunds("insufficient_funds", "el usuario no tiene USM Pesos suficientes")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "email o contraseña incorrectos")
+)
 
 type Usuario struct {
 	ID    int64
@@ -15,7 +26,7 @@ type Service interface {
 	RegisterUser(ctx context.Context, user *Usuario) (int64, error) // Registra un nuevo usuario y devuelve su ID
 	LoginUser(ctx context.Context, email, password string) (*Usuario, error) // Autentica a un usuario y devuelve su información
 	GetUserByID(ctx context.Context, id int64) (*Usuario, error) // Obtiene un usuario por su ID
-	UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error // Actualiza la cantidad de USM Pesos de un usuario
+	UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error // Suma (o resta, si amount < 0) USM Pesos
 	ListUsers(ctx context.Context) ([]*Usuario, error) // Lista todos los usuarios
 	GetUserByEmail(ctx context.Context, email string) (*Usuario, error) // Obtiene un usuario por su email
 }
@@ -33,11 +44,25 @@ func (s *service) RegisterUser(ctx context.Context, user *Usuario) (int64, error
 }
 
 func (s *service) LoginUser(ctx context.Context, email, password string) (*Usuario, error) {
-	return s.repo.LoginUser(ctx, email, password)
+	u, err := s.repo.LoginUser(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
 }
 
 func (s *service) GetUserByID(ctx context.Context, id int64) (*Usuario, error) {
-	return s.repo.GetUserByID(ctx, id)
+	u, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
 }
 
 func (s *service) UpdateUserUSMPesos(ctx context.Context, userID int64, amount int64) error {
@@ -48,7 +73,14 @@ func (s *service) ListUsers(ctx context.Context) ([]*Usuario, error) {
 	return s.repo.ListUsers(ctx)
 }
 func (s *service) GetUserByEmail(ctx context.Context, email string) (*Usuario, error) {
-	return s.repo.GetUserByEmail(ctx, email)
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
 }
 
 
@@ -0,0 +1,144 @@
+// Package userstest tiene la prueba de contrato de users.Repository: todas las
+// implementaciones deben pasarla igual, sea cual sea el motor que usen.
+package userstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"uzm-server/internal/users"
+)
+
+// RunRepositoryContract prueba una implementación de users.Repository.
+// newRepo debe entregar un repositorio vacío en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepo func(t *testing.T) users.Repository) {
+	ctx := context.Background()
+
+	create := func(t *testing.T, repo users.Repository, email string, pesos int64) int64 {
+		t.Helper()
+		id, err := repo.CreateUser(ctx, &users.Usuario{
+			FirstName: "Ana",
+			LastName:  "Pérez " + email, // last_name también es único
+			Email:     email,
+			Password:  "secreto",
+			USMPesos:  pesos,
+		})
+		if err != nil {
+			t.Fatalf("CreateUser(%s): %v", email, err)
+		}
+		return id
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+		id := create(t, repo, "ana@usm.cl", 100)
+		if id <= 0 {
+			t.Fatalf("CreateUser devolvió el id %d", id)
+		}
+
+		byID, err := repo.GetUserByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := users.Usuario{ID: id, FirstName: "Ana", LastName: "Pérez ana@usm.cl", Email: "ana@usm.cl", Password: "secreto", USMPesos: 100}
+		if byID == nil || *byID != want {
+			t.Fatalf("GetUserByID = %+v, quiero %+v", byID, want)
+		}
+		byEmail, err := repo.GetUserByEmail(ctx, "ana@usm.cl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if byEmail == nil || *byEmail != want {
+			t.Fatalf("GetUserByEmail = %+v, quiero %+v", byEmail, want)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		if u, err := repo.GetUserByID(ctx, 999); err != nil || u != nil {
+			t.Fatalf("GetUserByID(999) = %+v, %v; quiero nil, nil", u, err)
+		}
+		if u, err := repo.GetUserByEmail(ctx, "nadie@usm.cl"); err != nil || u != nil {
+			t.Fatalf("GetUserByEmail = %+v, %v; quiero nil, nil", u, err)
+		}
+	})
+
+	t.Run("EmailTaken", func(t *testing.T) {
+		repo := newRepo(t)
+		create(t, repo, "ana@usm.cl", 0)
+		_, err := repo.CreateUser(ctx, &users.Usuario{FirstName: "Otra", LastName: "Persona", Email: "ana@usm.cl", Password: "x"})
+		if !errors.Is(err, users.ErrEmailTaken) {
+			t.Fatalf("CreateUser con email repetido = %v, quiero ErrEmailTaken", err)
+		}
+		list, err := repo.ListUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 1 {
+			t.Fatalf("quedaron %d usuarios, quiero 1", len(list))
+		}
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		repo := newRepo(t)
+		id := create(t, repo, "ana@usm.cl", 0)
+		u, err := repo.LoginUser(ctx, "ana@usm.cl", "secreto")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u == nil || u.ID != id {
+			t.Fatalf("LoginUser = %+v, quiero el usuario %d", u, id)
+		}
+		u, err = repo.LoginUser(ctx, "ana@usm.cl", "otra")
+		if err != nil || u != nil {
+			t.Fatalf("LoginUser con contraseña incorrecta = %+v, %v; quiero nil, nil", u, err)
+		}
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		repo := newRepo(t)
+		list, err := repo.ListUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 0 {
+			t.Fatalf("ListUsers en un repositorio vacío devolvió %d usuarios", len(list))
+		}
+		a := create(t, repo, "a@usm.cl", 0)
+		b := create(t, repo, "b@usm.cl", 0)
+		list, err = repo.ListUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[int64]string{}
+		for _, u := range list {
+			got[u.ID] = u.Email
+		}
+		if len(got) != 2 || got[a] != "a@usm.cl" || got[b] != "b@usm.cl" {
+			t.Fatalf("ListUsers = %v", got)
+		}
+	})
+
+	t.Run("UpdateUSMPesos", func(t *testing.T) {
+		repo := newRepo(t)
+		id := create(t, repo, "ana@usm.cl", 100)
+
+		if err := repo.UpdateUserUSMPesos(ctx, id, 50); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdateUserUSMPesos(ctx, id, -150); err != nil {
+			t.Fatalf("descontar el saldo completo: %v", err)
+		}
+		if err := repo.UpdateUserUSMPesos(ctx, 999, 10); !errors.Is(err, users.ErrUserNotFound) {
+			t.Fatalf("usuario inexistente = %v, quiero ErrUserNotFound", err)
+		}
+		u, err := repo.GetUserByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.USMPesos != 0 {
+			t.Fatalf("saldo = %d, quiero 0", u.USMPesos)
+		}
+	})
+}
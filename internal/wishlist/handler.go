@@ -0,0 +1,174 @@
+package wishlist
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"uzm-server/internal/apperr"
+	httpx "uzm-server/internal/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type itemResponse struct {
+	BookID            int64     `json:"book_id"`
+	BookName          string    `json:"book_name"`
+	AvailableQuantity int64     `json:"available_quantity"`
+	AddedAt           time.Time `json:"added_at"`
+}
+
+func toItemResponse(it *Item) itemResponse {
+	return itemResponse{
+		BookID:            it.BookID,
+		BookName:          it.BookName,
+		AvailableQuantity: it.AvailableQuantity,
+		AddedAt:           it.AddedAt,
+	}
+}
+
+type notificationResponse struct {
+	ID        int64      `json:"id"`
+	BookID    int64      `json:"book_id"`
+	Kind      string     `json:"kind"`
+	Message   string     `json:"message"`
+	Read      bool       `json:"read"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+func toNotificationResponse(n *Notification) notificationResponse {
+	return notificationResponse{
+		ID:        n.ID,
+		BookID:    n.BookID,
+		Kind:      n.Kind,
+		Message:   n.Message,
+		Read:      n.ReadAt != nil,
+		CreatedAt: n.CreatedAt,
+		ReadAt:    n.ReadAt,
+	}
+}
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler { return &Handler{service: service} }
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/users/:id/wishlist", h.listWishlist)
+	r.POST("/users/:id/wishlist", h.addToWishlist)
+	r.DELETE("/users/:id/wishlist/:bookId", h.removeFromWishlist)
+	r.GET("/users/:id/notifications", h.listNotifications)
+	r.POST("/users/:id/notifications/read", h.markAllRead)
+	r.POST("/users/:id/notifications/:notificationId/read", h.markRead)
+}
+
+func (h *Handler) listWishlist(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	items, err := h.service.ListWishlist(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]itemResponse, 0, len(items))
+	for _, it := range items {
+		out = append(out, toItemResponse(it))
+	}
+	c.JSON(http.StatusOK, gin.H{"wishlist": out})
+}
+
+func (h *Handler) addToWishlist(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	var body struct {
+		BookID int64 `json:"book_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		httpx.BindError(c, err)
+		return
+	}
+	it, err := h.service.AddToWishlist(c.Request.Context(), userID, body.BookID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, toItemResponse(it))
+}
+
+func (h *Handler) removeFromWishlist(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	bookID, ok := httpx.ParamID(c, "bookId")
+	if !ok {
+		return
+	}
+	if err := h.service.RemoveFromWishlist(c.Request.Context(), userID, bookID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listNotifications entrega el buzón del usuario; ?unread=true filtra las no leídas
+func (h *Handler) listNotifications(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	onlyUnread := false
+	if v := c.Query("unread"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			_ = c.Error(apperr.InvalidParam("unread", "debe ser true o false"))
+			return
+		}
+		onlyUnread = parsed
+	}
+	list, unread, err := h.service.ListNotifications(c.Request.Context(), userID, onlyUnread)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	out := make([]notificationResponse, 0, len(list))
+	for _, n := range list {
+		out = append(out, toNotificationResponse(n))
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": out, "unread_count": unread})
+}
+
+func (h *Handler) markRead(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := httpx.ParamID(c, "notificationId")
+	if !ok {
+		return
+	}
+	n, err := h.service.MarkRead(c.Request.Context(), userID, id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, toNotificationResponse(n))
+}
+
+func (h *Handler) markAllRead(c *gin.Context) {
+	userID, ok := httpx.ParamID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.MarkAllRead(c.Request.Context(), userID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
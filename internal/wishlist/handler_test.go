@@ -0,0 +1,130 @@
+package wishlist_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"uzm-server/internal/http/httpxtest"
+	"uzm-server/internal/wishlist"
+)
+
+// fakeService: el usuario 5 existe y desea el libro 1; el libro 2 existe pero
+// no está en su lista. Su buzón tiene la notificación 7 sin leer.
+type fakeService struct {
+	onlyUnread bool
+}
+
+var addedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func item(bookID int64) *wishlist.Item {
+	return &wishlist.Item{UserID: 5, BookID: bookID, BookName: "Rayuela", AvailableQuantity: 2, AddedAt: addedAt}
+}
+
+func notification(read bool) *wishlist.Notification {
+	n := &wishlist.Notification{ID: 7, UserID: 5, BookID: 1, Kind: wishlist.KindBackInStock, Message: "Rayuela volvió a estar disponible",
+		CreatedAt: addedAt}
+	if read {
+		at := addedAt.Add(time.Hour)
+		n.ReadAt = &at
+	}
+	return n
+}
+
+func checkUser(userID int64) error {
+	if userID != 5 {
+		return wishlist.ErrUserNotFound
+	}
+	return nil
+}
+
+func (f *fakeService) ListWishlist(ctx context.Context, userID int64) ([]*wishlist.Item, error) {
+	if err := checkUser(userID); err != nil {
+		return nil, err
+	}
+	return []*wishlist.Item{item(1)}, nil
+}
+
+func (f *fakeService) AddToWishlist(ctx context.Context, userID, bookID int64) (*wishlist.Item, error) {
+	if err := checkUser(userID); err != nil {
+		return nil, err
+	}
+	if bookID != 1 && bookID != 2 {
+		return nil, wishlist.ErrBookNotFound
+	}
+	return item(bookID), nil
+}
+
+func (f *fakeService) RemoveFromWishlist(ctx context.Context, userID, bookID int64) error {
+	if err := checkUser(userID); err != nil {
+		return err
+	}
+	if bookID != 1 {
+		return wishlist.ErrNotInWishlist
+	}
+	return nil
+}
+
+func (f *fakeService) ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]*wishlist.Notification, int64, error) {
+	f.onlyUnread = onlyUnread
+	if err := checkUser(userID); err != nil {
+		return nil, 0, err
+	}
+	return []*wishlist.Notification{notification(false)}, 1, nil
+}
+
+func (f *fakeService) MarkRead(ctx context.Context, userID, id int64) (*wishlist.Notification, error) {
+	if err := checkUser(userID); err != nil {
+		return nil, err
+	}
+	if id != 7 {
+		return nil, wishlist.ErrNotificationNotFound
+	}
+	return notification(true), nil
+}
+
+func (f *fakeService) MarkAllRead(ctx context.Context, userID int64) error {
+	return checkUser(userID)
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	router := httpxtest.Router(wishlist.NewHandler(&fakeService{}).RegisterRoutes)
+
+	httpxtest.Run(t, router, []httpxtest.Case{
+		{Name: "List", Method: "GET", Path: "/users/5/wishlist", Status: 200, Want: []string{`"wishlist":[`, `"book_id":1`, `"available_quantity":2`}},
+		{Name: "ListMissingUser", Method: "GET", Path: "/users/9/wishlist", Status: 404, Code: "user_not_found"},
+		{Name: "ListBadUser", Method: "GET", Path: "/users/x/wishlist", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "Add", Method: "POST", Path: "/users/5/wishlist", Body: `{"book_id":2}`, Status: 201, Want: []string{`"book_id":2`, `"book_name":"Rayuela"`}},
+		{Name: "AddNoBook", Method: "POST", Path: "/users/5/wishlist", Body: `{}`, Status: 400, Code: "validation_failed", Want: []string{`"field":"book_id"`}},
+		{Name: "AddMissingBook", Method: "POST", Path: "/users/5/wishlist", Body: `{"book_id":9}`, Status: 404, Code: "book_not_found"},
+		{Name: "AddMissingUser", Method: "POST", Path: "/users/9/wishlist", Body: `{"book_id":1}`, Status: 404, Code: "user_not_found"},
+
+		{Name: "Remove", Method: "DELETE", Path: "/users/5/wishlist/1", Status: 204},
+		{Name: "RemoveNotThere", Method: "DELETE", Path: "/users/5/wishlist/2", Status: 404, Code: "not_in_wishlist"},
+		{Name: "RemoveBadBook", Method: "DELETE", Path: "/users/5/wishlist/x", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "Notifications", Method: "GET", Path: "/users/5/notifications", Status: 200,
+			Want: []string{`"notifications":[`, `"kind":"disponible"`, `"read":false`, `"unread_count":1`}},
+		{Name: "NotificationsBadUnread", Method: "GET", Path: "/users/5/notifications?unread=quizas", Status: 400, Code: "invalid_parameter"},
+		{Name: "NotificationsMissingUser", Method: "GET", Path: "/users/9/notifications", Status: 404, Code: "user_not_found"},
+
+		{Name: "MarkRead", Method: "POST", Path: "/users/5/notifications/7/read", Status: 200, Want: []string{`"read":true`, `"read_at":"2026-03-01T13:00:00Z"`}},
+		{Name: "MarkReadMissing", Method: "POST", Path: "/users/5/notifications/8/read", Status: 404, Code: "notification_not_found"},
+		{Name: "MarkReadBadID", Method: "POST", Path: "/users/5/notifications/0/read", Status: 400, Code: "invalid_parameter"},
+
+		{Name: "MarkAllRead", Method: "POST", Path: "/users/5/notifications/read", Status: 204},
+		{Name: "MarkAllReadMissingUser", Method: "POST", Path: "/users/9/notifications/read", Status: 404, Code: "user_not_found"},
+	})
+}
+
+func TestHandlerUnreadFilter(t *testing.T) {
+	for query, want := range map[string]bool{"": false, "?unread=true": true, "?unread=false": false, "?unread=1": true} {
+		svc := &fakeService{}
+		router := httpxtest.Router(wishlist.NewHandler(svc).RegisterRoutes)
+		httpxtest.Check(t, httpxtest.Do(router, "GET", "/users/5/notifications"+query, "", nil), 200, "")
+		if svc.onlyUnread != want {
+			t.Fatalf("%q: el servicio recibió onlyUnread=%v, quiero %v", query, svc.onlyUnread, want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package wishlist
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"uzm-server/internal/memstore"
+)
+
+type memoryRepository struct{ store *memstore.Store }
+
+func NewMemoryRepository(store *memstore.Store) Repository {
+	return &memoryRepository{store: store}
+}
+
+// NotifyBackInStockInMemory es NotifyBackInStock para los repositorios en
+// memoria; se llama dentro de su memstore.Store.Update
+func NotifyBackInStockInMemory(t *memstore.Tables, bookID int64) {
+	book, ok := t.Books[bookID]
+	if !ok {
+		return
+	}
+	var userIDs []int64
+	for k := range t.Wishlist {
+		if k.BookID == bookID {
+			userIDs = append(userIDs, k.UserID)
+		}
+	}
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, userID := range userIDs {
+		id := t.NextID("Notificacion")
+		t.Notifications[id] = memstore.Notification{
+			ID:        id,
+			UserID:    userID,
+			BookID:    bookID,
+			Kind:      KindBackInStock,
+			Message:   "«" + book.Name + "» volvió a estar disponible",
+			CreatedAt: now,
+		}
+	}
+}
+
+func (r *memoryRepository) UserExists(ctx context.Context, userID int64) (ok bool, err error) {
+	err = r.store.View(func(t *memstore.Tables) error {
+		_, ok = t.Users[userID]
+		return nil
+	})
+	return ok, err
+}
+
+func (r *memoryRepository) ListWishlist(ctx context.Context, userID int64) ([]Item, error) {
+	out := []Item{}
+	err := r.store.View(func(t *memstore.Tables) error {
+		for k, addedAt := range t.Wishlist {
+			book, ok := t.Books[k.BookID]
+			if k.UserID != userID || !ok {
+				continue
+			}
+			out = append(out, Item{
+				UserID:            k.UserID,
+				BookID:            k.BookID,
+				BookName:          book.Name,
+				AvailableQuantity: t.Inventory[k.BookID].Available,
+				AddedAt:           addedAt,
+			})
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].AddedAt.Equal(out[j].AddedAt) {
+			return out[i].AddedAt.After(out[j].AddedAt)
+		}
+		return out[i].BookID < out[j].BookID
+	})
+	return out, err
+}
+
+func (r *memoryRepository) AddToWishlist(ctx context.Context, userID, bookID int64, at time.Time) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		if _, ok := t.Books[bookID]; !ok {
+			return ErrBookNotFound
+		}
+		k := memstore.WishlistKey{UserID: userID, BookID: bookID}
+		if _, ok := t.Wishlist[k]; !ok {
+			t.Wishlist[k] = memstore.Stamp(at)
+		}
+		return nil
+	})
+}
+
+func (r *memoryRepository) RemoveFromWishlist(ctx context.Context, userID, bookID int64) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		k := memstore.WishlistKey{UserID: userID, BookID: bookID}
+		if _, ok := t.Wishlist[k]; !ok {
+			return ErrNotInWishlist
+		}
+		delete(t.Wishlist, k)
+		return nil
+	})
+}
+
+func toNotification(n memstore.Notification) Notification {
+	out := Notification{ID: n.ID, UserID: n.UserID, BookID: n.BookID, Kind: n.Kind, Message: n.Message, CreatedAt: n.CreatedAt}
+	if n.ReadAt != nil {
+		readAt := *n.ReadAt
+		out.ReadAt = &readAt
+	}
+	return out
+}
+
+func (r *memoryRepository) ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]Notification, int64, error) {
+	out := []Notification{}
+	var unread int64
+	err := r.store.View(func(t *memstore.Tables) error {
+		for _, n := range t.Notifications {
+			if n.UserID != userID {
+				continue
+			}
+			if n.ReadAt == nil {
+				unread++
+			} else if onlyUnread {
+				continue
+			}
+			out = append(out, toNotification(n))
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out, unread, err
+}
+
+func (r *memoryRepository) MarkRead(ctx context.Context, userID, id int64, at time.Time) (*Notification, error) {
+	var out Notification
+	err := r.store.Update(func(t *memstore.Tables) error {
+		n, ok := t.Notifications[id]
+		if !ok || n.UserID != userID {
+			return ErrNotificationNotFound
+		}
+		// Si ya estaba leída se conserva la fecha original
+		if n.ReadAt == nil {
+			readAt := memstore.Stamp(at)
+			n.ReadAt = &readAt
+			t.Notifications[id] = n
+		}
+		out = toNotification(n)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *memoryRepository) MarkAllRead(ctx context.Context, userID int64, at time.Time) error {
+	return r.store.Update(func(t *memstore.Tables) error {
+		readAt := memstore.Stamp(at)
+		for id, n := range t.Notifications {
+			if n.UserID == userID && n.ReadAt == nil {
+				n.ReadAt = &readAt
+				t.Notifications[id] = n
+			}
+		}
+		return nil
+	})
+}
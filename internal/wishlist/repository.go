@@ -0,0 +1,180 @@
+package wishlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"uzm-server/internal/db"
+)
+
+type sqlRepository struct{ dbconn *db.DB }
+
+func NewSQLiteRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.SQLite)}
+}
+
+func NewPostgresRepository(conn *sql.DB) Repository {
+	return &sqlRepository{dbconn: db.Wrap(conn, db.Postgres)}
+}
+
+// NotifyBackInStock avisa a quienes desean el libro que volvió a tener stock.
+// Los repositorios que cambian Inventario la llaman dentro de su transacción
+// cuando available_quantity pasa de 0 a positivo. Los CAST le dan tipo a los
+// parámetros del SELECT, que PostgreSQL no deduce solo.
+func NotifyBackInStock(ctx context.Context, tx *db.Tx, bookID int64) error {
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO Notificacion (user_id, book_id, kind, message, created_at)
+SELECT d.user_id, d.book_id, CAST(? AS TEXT), '«' || l.book_name || '» volvió a estar disponible', CAST(? AS TEXT)
+FROM   ListaDeseos d
+JOIN   Libro l ON l.id = d.book_id
+WHERE  d.book_id = ?`,
+		KindBackInStock, time.Now().UTC().Format(time.RFC3339), bookID)
+	return err
+}
+
+func (r *sqlRepository) UserExists(ctx context.Context, userID int64) (bool, error) {
+	var one int
+	err := r.dbconn.QueryRowContext(ctx, `SELECT 1 FROM Usuario WHERE id = ?`, userID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *sqlRepository) ListWishlist(ctx context.Context, userID int64) ([]Item, error) {
+	rows, err := r.dbconn.QueryContext(ctx, `
+SELECT   d.user_id, d.book_id, l.book_name, COALESCE(i.available_quantity, 0), d.created_at
+FROM     ListaDeseos d
+JOIN     Libro l ON l.id = d.book_id
+LEFT JOIN Inventario i ON i.book_id = d.book_id
+WHERE    d.user_id = ?
+ORDER BY d.created_at DESC, d.book_id ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Item{}
+	for rows.Next() {
+		var it Item
+		var addedAt string
+		if err := rows.Scan(&it.UserID, &it.BookID, &it.BookName, &it.AvailableQuantity, &addedAt); err != nil {
+			return nil, err
+		}
+		it.AddedAt, _ = time.Parse(time.RFC3339, addedAt)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepository) AddToWishlist(ctx context.Context, userID, bookID int64, at time.Time) error {
+	var one int
+	err := r.dbconn.QueryRowContext(ctx, `SELECT 1 FROM Libro WHERE id = ?`, bookID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrBookNotFound
+	}
+	if err != nil {
+		return err
+	}
+	_, err = r.dbconn.ExecContext(ctx, `
+INSERT INTO ListaDeseos (user_id, book_id, created_at) VALUES (?, ?, ?)
+ON CONFLICT (user_id, book_id) DO NOTHING`, userID, bookID, at.Format(time.RFC3339))
+	return err
+}
+
+func (r *sqlRepository) RemoveFromWishlist(ctx context.Context, userID, bookID int64) error {
+	res, err := r.dbconn.ExecContext(ctx, `DELETE FROM ListaDeseos WHERE user_id = ? AND book_id = ?`, userID, bookID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotInWishlist
+	}
+	return nil
+}
+
+const notificationSelect = `
+SELECT id, user_id, book_id, kind, message, created_at, read_at
+FROM   Notificacion`
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanNotification(row rowScanner) (Notification, error) {
+	var n Notification
+	var createdAt string
+	var readAt sql.NullString
+	if err := row.Scan(&n.ID, &n.UserID, &n.BookID, &n.Kind, &n.Message, &createdAt, &readAt); err != nil {
+		return Notification{}, err
+	}
+	n.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	if readAt.Valid {
+		if t, err := time.Parse(time.RFC3339, readAt.String); err == nil {
+			n.ReadAt = &t
+		}
+	}
+	return n, nil
+}
+
+func (r *sqlRepository) ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]Notification, int64, error) {
+	var unread int64
+	err := r.dbconn.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM Notificacion WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&unread)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	q := notificationSelect + ` WHERE user_id = ?`
+	if onlyUnread {
+		q += " AND read_at IS NULL"
+	}
+	q += " ORDER BY id DESC"
+	rows, err := r.dbconn.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := []Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, n)
+	}
+	return out, unread, rows.Err()
+}
+
+func (r *sqlRepository) MarkRead(ctx context.Context, userID, id int64, at time.Time) (*Notification, error) {
+	// Si ya estaba leída se conserva la fecha original
+	res, err := r.dbconn.ExecContext(ctx, `
+UPDATE Notificacion SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`,
+		at.Format(time.RFC3339), id, userID)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, ErrNotificationNotFound
+	}
+	notif, err := scanNotification(r.dbconn.QueryRowContext(ctx, notificationSelect+` WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	return &notif, nil
+}
+
+func (r *sqlRepository) MarkAllRead(ctx context.Context, userID int64, at time.Time) error {
+	_, err := r.dbconn.ExecContext(ctx, `
+UPDATE Notificacion SET read_at = ? WHERE user_id = ? AND read_at IS NULL`, at.Format(time.RFC3339), userID)
+	return err
+}
@@ -0,0 +1,49 @@
+package wishlist_test
+
+import (
+	"os"
+	"testing"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/db/dbtest"
+	"uzm-server/internal/memstore"
+	"uzm-server/internal/users"
+	"uzm-server/internal/wishlist"
+	"uzm-server/internal/wishlist/wishlisttest"
+)
+
+func TestMain(m *testing.M) { os.Exit(dbtest.Main(m)) }
+
+func TestSQLiteRepository(t *testing.T) {
+	wishlisttest.RunRepositoryContract(t, func(t *testing.T) wishlisttest.Repos {
+		conn := dbtest.SQLite(t).DB
+		return wishlisttest.Repos{
+			Wishlist: wishlist.NewSQLiteRepository(conn),
+			Books:    books.NewSQLiteRepository(conn),
+			Users:    users.NewSQLiteRepository(conn),
+		}
+	})
+}
+
+func TestPostgresRepository(t *testing.T) {
+	wishlisttest.RunRepositoryContract(t, func(t *testing.T) wishlisttest.Repos {
+		conn := dbtest.Postgres(t).DB
+		return wishlisttest.Repos{
+			Wishlist: wishlist.NewPostgresRepository(conn),
+			Books:    books.NewPostgresRepository(conn),
+			Users:    users.NewPostgresRepository(conn),
+		}
+	})
+}
+
+// El repositorio en memoria debe comportarse igual que los de SQL
+func TestMemoryRepository(t *testing.T) {
+	wishlisttest.RunRepositoryContract(t, func(t *testing.T) wishlisttest.Repos {
+		store := memstore.New()
+		return wishlisttest.Repos{
+			Wishlist: wishlist.NewMemoryRepository(store),
+			Books:    books.NewMemoryRepository(store),
+			Users:    users.NewMemoryRepository(store),
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package wishlist
+
+import (
+	"context"
+	"time"
+
+	"uzm-server/internal/apperr"
+)
+
+var (
+	ErrUserNotFound         = apperr.NotFound("user_not_found", "usuario no encontrado")
+	ErrBookNotFound         = apperr.NotFound("book_not_found", "libro no encontrado")
+	ErrNotInWishlist        = apperr.NotFound("not_in_wishlist", "el libro no está en la lista de deseos")
+	ErrNotificationNotFound = apperr.NotFound("notification_not_found", "notificación no encontrada")
+)
+
+// Tipos de notificación del buzón
+const KindBackInStock = "disponible" // un libro deseado volvió a tener stock
+
+// Item es un libro en la lista de deseos de un usuario
+type Item struct {
+	UserID            int64
+	BookID            int64
+	BookName          string
+	AvailableQuantity int64
+	AddedAt           time.Time
+}
+
+// Notification es un aviso del buzón del usuario; ReadAt es nil mientras no se lea
+type Notification struct {
+	ID        int64
+	UserID    int64
+	BookID    int64
+	Kind      string
+	Message   string
+	CreatedAt time.Time
+	ReadAt    *time.Time
+}
+
+type Service interface { // Interfaz del servicio de listas de deseos y notificaciones
+	ListWishlist(ctx context.Context, userID int64) ([]*Item, error)                                      // Libros deseados por el usuario
+	AddToWishlist(ctx context.Context, userID, bookID int64) (*Item, error)                               // Agrega un libro (idempotente)
+	RemoveFromWishlist(ctx context.Context, userID, bookID int64) error                                   // Quita un libro
+	ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]*Notification, int64, error) // Buzón del usuario y cantidad sin leer
+	MarkRead(ctx context.Context, userID, id int64) (*Notification, error)                                // Marca una notificación como leída
+	MarkAllRead(ctx context.Context, userID int64) error                                                  // Marca todo el buzón como leído
+}
+
+type Repository interface {
+	UserExists(ctx context.Context, userID int64) (bool, error)
+	ListWishlist(ctx context.Context, userID int64) ([]Item, error)
+	AddToWishlist(ctx context.Context, userID, bookID int64, at time.Time) error
+	RemoveFromWishlist(ctx context.Context, userID, bookID int64) error
+	ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]Notification, int64, error)
+	MarkRead(ctx context.Context, userID, id int64, at time.Time) (*Notification, error)
+	MarkAllRead(ctx context.Context, userID int64, at time.Time) error
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) requireUser(ctx context.Context, userID int64) error {
+	ok, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *service) ListWishlist(ctx context.Context, userID int64) ([]*Item, error) {
+	if err := s.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListWishlist(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Item, len(items))
+	for i := range items {
+		out[i] = &items[i]
+	}
+	return out, nil
+}
+
+func (s *service) AddToWishlist(ctx context.Context, userID, bookID int64) (*Item, error) {
+	if err := s.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddToWishlist(ctx, userID, bookID, time.Now().UTC().Truncate(time.Second)); err != nil {
+		return nil, err
+	}
+	items, err := s.repo.ListWishlist(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if items[i].BookID == bookID {
+			return &items[i], nil
+		}
+	}
+	return nil, ErrNotInWishlist
+}
+
+func (s *service) RemoveFromWishlist(ctx context.Context, userID, bookID int64) error {
+	return s.repo.RemoveFromWishlist(ctx, userID, bookID)
+}
+
+func (s *service) ListNotifications(ctx context.Context, userID int64, onlyUnread bool) ([]*Notification, int64, error) {
+	if err := s.requireUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	list, unread, err := s.repo.ListNotifications(ctx, userID, onlyUnread)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]*Notification, len(list))
+	for i := range list {
+		out[i] = &list[i]
+	}
+	return out, unread, nil
+}
+
+func (s *service) MarkRead(ctx context.Context, userID, id int64) (*Notification, error) {
+	return s.repo.MarkRead(ctx, userID, id, time.Now().UTC().Truncate(time.Second))
+}
+
+func (s *service) MarkAllRead(ctx context.Context, userID int64) error {
+	if err := s.requireUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.repo.MarkAllRead(ctx, userID, time.Now().UTC().Truncate(time.Second))
+}
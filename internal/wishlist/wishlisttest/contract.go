@@ -0,0 +1,237 @@
+// Package wishlisttest tiene la prueba de contrato de wishlist.Repository:
+// todas las implementaciones deben pasarla igual, sea cual sea el motor que
+// usen.
+package wishlisttest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"uzm-server/internal/books"
+	"uzm-server/internal/users"
+	"uzm-server/internal/wishlist"
+)
+
+// Repos son los repositorios de una misma base vacía: el que se prueba y los
+// que crean los usuarios y libros, y mueven el stock que dispara los avisos
+type Repos struct {
+	Wishlist wishlist.Repository
+	Books    books.Repository
+	Users    users.Repository
+}
+
+var at = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// RunRepositoryContract prueba una implementación de wishlist.Repository.
+// newRepos debe entregar repositorios vacíos en cada llamada.
+func RunRepositoryContract(t *testing.T, newRepos func(t *testing.T) Repos) {
+	ctx := context.Background()
+
+	t.Run("UserExists", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r, "ana")
+		if ok, err := r.Wishlist.UserExists(ctx, userID); err != nil || !ok {
+			t.Fatalf("UserExists(%d) = %v, %v; quiero true", userID, ok, err)
+		}
+		if ok, err := r.Wishlist.UserExists(ctx, 999); err != nil || ok {
+			t.Fatalf("UserExists(999) = %v, %v; quiero false", ok, err)
+		}
+	})
+
+	t.Run("AddAndList", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r, "ana")
+		rayuela := createBook(t, r, "Rayuela", 3)
+		ficciones := createBook(t, r, "Ficciones", 0)
+
+		list := listWishlist(t, r, userID)
+		if list == nil || len(list) != 0 {
+			t.Fatalf("ListWishlist vacía = %#v, quiero una lista vacía", list)
+		}
+
+		add(t, r, userID, rayuela, at)
+		add(t, r, userID, ficciones, at.Add(time.Hour))
+		// Agregar de nuevo no duplica ni cambia la fecha
+		add(t, r, userID, rayuela, at.Add(2*time.Hour))
+
+		want := []wishlist.Item{
+			{UserID: userID, BookID: ficciones, BookName: "Ficciones", AvailableQuantity: 0, AddedAt: at.Add(time.Hour)},
+			{UserID: userID, BookID: rayuela, BookName: "Rayuela", AvailableQuantity: 3, AddedAt: at},
+		}
+		list = listWishlist(t, r, userID)
+		if len(list) != len(want) || list[0] != want[0] || list[1] != want[1] {
+			t.Fatalf("ListWishlist = %+v, quiero %+v", list, want)
+		}
+
+		if err := r.Wishlist.AddToWishlist(ctx, userID, 999, at); !errors.Is(err, wishlist.ErrBookNotFound) {
+			t.Fatalf("AddToWishlist de un libro inexistente = %v, quiero ErrBookNotFound", err)
+		}
+		if other := listWishlist(t, r, createUser(t, r, "luis")); len(other) != 0 {
+			t.Fatalf("la lista de otro usuario trae %+v", other)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		r := newRepos(t)
+		userID := createUser(t, r, "ana")
+		bookID := createBook(t, r, "Rayuela", 3)
+		add(t, r, userID, bookID, at)
+
+		if err := r.Wishlist.RemoveFromWishlist(ctx, userID, bookID); err != nil {
+			t.Fatal(err)
+		}
+		if list := listWishlist(t, r, userID); len(list) != 0 {
+			t.Fatalf("ListWishlist tras quitar el libro = %+v", list)
+		}
+		if err := r.Wishlist.RemoveFromWishlist(ctx, userID, bookID); !errors.Is(err, wishlist.ErrNotInWishlist) {
+			t.Fatalf("quitar dos veces = %v, quiero ErrNotInWishlist", err)
+		}
+	})
+
+	t.Run("BackInStock", func(t *testing.T) {
+		r := newRepos(t)
+		ana, luis := createUser(t, r, "ana"), createUser(t, r, "luis")
+		bookID := createBook(t, r, "Rayuela", 0)
+		other := createBook(t, r, "Ficciones", 0)
+		add(t, r, ana, bookID, at)
+		add(t, r, luis, other, at)
+
+		adjust(t, r, bookID, 2)
+		list, unread := notifications(t, r, ana, false)
+		if len(list) != 1 || unread != 1 {
+			t.Fatalf("ListNotifications = %+v (%d sin leer), quiero un aviso", list, unread)
+		}
+		n := list[0]
+		if n.ID <= 0 || n.UserID != ana || n.BookID != bookID || n.Kind != wishlist.KindBackInStock ||
+			!strings.Contains(n.Message, "Rayuela") || n.ReadAt != nil || n.CreatedAt.IsZero() {
+			t.Fatalf("aviso = %+v", n)
+		}
+		if list, unread := notifications(t, r, luis, false); len(list) != 0 || unread != 0 {
+			t.Fatalf("avisaron a quien no desea el libro: %+v", list)
+		}
+
+		// Mientras siga habiendo stock no se vuelve a avisar
+		adjust(t, r, bookID, 1)
+		if list, _ := notifications(t, r, ana, false); len(list) != 1 {
+			t.Fatalf("hay %d avisos tras reponer un libro con stock, quiero 1", len(list))
+		}
+		adjust(t, r, bookID, -3)
+		adjust(t, r, bookID, 1)
+		if list, unread := notifications(t, r, ana, false); len(list) != 2 || unread != 2 || list[0].ID <= list[1].ID {
+			t.Fatalf("ListNotifications = %+v (%d sin leer), quiero dos avisos, el último primero", list, unread)
+		}
+	})
+
+	t.Run("MarkRead", func(t *testing.T) {
+		r := newRepos(t)
+		ana, luis := createUser(t, r, "ana"), createUser(t, r, "luis")
+		first, second := createBook(t, r, "Rayuela", 0), createBook(t, r, "Ficciones", 0)
+		add(t, r, ana, first, at)
+		add(t, r, ana, second, at)
+		adjust(t, r, first, 1)
+		adjust(t, r, second, 1)
+		list, _ := notifications(t, r, ana, false)
+		if len(list) != 2 {
+			t.Fatalf("hay %d avisos, quiero 2", len(list))
+		}
+		id := list[1].ID
+
+		read := at.Add(time.Hour)
+		n, err := r.Wishlist.MarkRead(ctx, ana, id, read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n.ID != id || n.ReadAt == nil || !n.ReadAt.Equal(read) {
+			t.Fatalf("MarkRead = %+v, quiero leída el %v", n, read)
+		}
+		// Marcarla otra vez conserva la primera lectura
+		if n, err = r.Wishlist.MarkRead(ctx, ana, id, read.Add(time.Hour)); err != nil || !n.ReadAt.Equal(read) {
+			t.Fatalf("MarkRead repetido = %+v, %v; quiero leída el %v", n, err, read)
+		}
+		if _, err := r.Wishlist.MarkRead(ctx, luis, id, read); !errors.Is(err, wishlist.ErrNotificationNotFound) {
+			t.Fatalf("MarkRead de un aviso ajeno = %v, quiero ErrNotificationNotFound", err)
+		}
+		if _, err := r.Wishlist.MarkRead(ctx, ana, 999, read); !errors.Is(err, wishlist.ErrNotificationNotFound) {
+			t.Fatalf("MarkRead(999) = %v, quiero ErrNotificationNotFound", err)
+		}
+
+		unreadList, unread := notifications(t, r, ana, true)
+		if len(unreadList) != 1 || unread != 1 || unreadList[0].ID == id {
+			t.Fatalf("ListNotifications(onlyUnread) = %+v (%d sin leer)", unreadList, unread)
+		}
+
+		if err := r.Wishlist.MarkAllRead(ctx, ana, read.Add(2*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		all, unread := notifications(t, r, ana, false)
+		if len(all) != 2 || unread != 0 {
+			t.Fatalf("tras MarkAllRead quedan %d sin leer de %d", unread, len(all))
+		}
+		for _, n := range all {
+			want := read.Add(2 * time.Hour)
+			if n.ID == id {
+				want = read
+			}
+			if n.ReadAt == nil || !n.ReadAt.Equal(want) {
+				t.Fatalf("aviso %d leído el %v, quiero %v", n.ID, n.ReadAt, want)
+			}
+		}
+	})
+}
+
+func createUser(t *testing.T, r Repos, name string) int64 {
+	t.Helper()
+	id, err := r.Users.CreateUser(context.Background(), &users.Usuario{
+		FirstName: "Usuario", LastName: name, Email: name + "@usm.cl", Password: "x",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", name, err)
+	}
+	return id
+}
+
+func createBook(t *testing.T, r Repos, name string, stock int64) int64 {
+	t.Helper()
+	b := &books.Book{BookName: name, BookCategory: "Novela", TransactionType: books.ModeSale, Price: 1000, RentalPeriodDays: 7}
+	id, err := r.Books.CreateBook(context.Background(), b, stock, 0)
+	if err != nil {
+		t.Fatalf("CreateBook(%s): %v", name, err)
+	}
+	return id
+}
+
+func adjust(t *testing.T, r Repos, bookID, delta int64) {
+	t.Helper()
+	adj := &books.InventoryAdjustment{BookID: bookID, Delta: delta, Reason: books.ReasonCorrection, CreatedAt: at}
+	if err := r.Books.AdjustInventory(context.Background(), adj); err != nil {
+		t.Fatalf("AdjustInventory(%d, %+d): %v", bookID, delta, err)
+	}
+}
+
+func add(t *testing.T, r Repos, userID, bookID int64, when time.Time) {
+	t.Helper()
+	if err := r.Wishlist.AddToWishlist(context.Background(), userID, bookID, when); err != nil {
+		t.Fatalf("AddToWishlist(%d, %d): %v", userID, bookID, err)
+	}
+}
+
+func listWishlist(t *testing.T, r Repos, userID int64) []wishlist.Item {
+	t.Helper()
+	list, err := r.Wishlist.ListWishlist(context.Background(), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func notifications(t *testing.T, r Repos, userID int64, onlyUnread bool) ([]wishlist.Notification, int64) {
+	t.Helper()
+	list, unread, err := r.Wishlist.ListNotifications(context.Background(), userID, onlyUnread)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list, unread
+}